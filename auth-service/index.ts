@@ -7,6 +7,8 @@
  *   - Verify:          /verify-token → exchange code → redirect to UI
  *   - Exchange:        /exchange {code} → JWT issued, session stored
  *   - Login (email):   email → JWT (dev fallback — remove before feature-complete)
+ *   - Login (password): POST /login {email, password} → session JWT
+ *                       (fallback for browsers without WebAuthn; verified accounts only)
  *   - Passkey register: POST /passkey/register/begin → /passkey/register/complete
  *   - Passkey login:    POST /passkey/login/begin   → /passkey/login/complete
  *
@@ -126,6 +128,45 @@ async function markPasskeyEnrolled(id: string): Promise<void> {
   await db.query('UPDATE players SET passkey_enrolled = true WHERE id = $1', [id]);
 }
 
+// ── Passwords ─────────────────────────────────────────────────────────────────
+// Optional, set at registration. Stored as scrypt$<salt>$<hash> (base64).
+
+const MIN_PASSWORD_LENGTH = 10;
+const MAX_PASSWORD_LENGTH = 128;
+
+function scrypt(password: string, salt: Buffer): Promise<Buffer> {
+  return new Promise((resolve, reject) =>
+    crypto.scrypt(password, salt, 64, (err, key) => (err ? reject(err) : resolve(key))));
+}
+
+async function hashPassword(password: string): Promise<string> {
+  const salt = crypto.randomBytes(16);
+  const hash = await scrypt(password, salt);
+  return `scrypt$${salt.toString('base64')}$${hash.toString('base64')}`;
+}
+
+// A fixed hash to check against when the account has no password, so a
+// missing account takes as long to reject as a wrong password
+const DUMMY_PASSWORD_HASH = `scrypt$${Buffer.alloc(16).toString('base64')}$${Buffer.alloc(64).toString('base64')}`;
+
+async function checkPassword(password: string, stored: string | null): Promise<boolean> {
+  const [scheme, salt, hash] = (stored ?? DUMMY_PASSWORD_HASH).split('$');
+  if (scheme !== 'scrypt' || !salt || !hash) return false;
+  const expected = Buffer.from(hash, 'base64');
+  if (expected.length !== 64) return false;
+  const actual   = await scrypt(password, Buffer.from(salt, 'base64'));
+  return crypto.timingSafeEqual(actual, expected) && stored !== null;
+}
+
+async function setPasswordHash(id: string, hash: string): Promise<void> {
+  await db.query('UPDATE players SET password_hash = $1 WHERE id = $2', [hash, id]);
+}
+
+async function getPasswordHash(id: string): Promise<string | null> {
+  const { rows } = await db.query('SELECT password_hash FROM players WHERE id = $1', [id]);
+  return rows[0]?.password_hash ?? null;
+}
+
 // ── Passkey credential operations ─────────────────────────────────────────────
 
 interface StoredCredential {
@@ -390,6 +431,18 @@ const server = http.createServer(async (req, res) => {
     return;
   }
 
+  // ── GET /exists?email= ──────────────────────────────────────────────────────
+  // Lightweight check for auth-ui's registration availability hint. Reveals
+  // no more than /register's 409; auth-ui rate-limits callers.
+
+  if (method === 'GET' && url.pathname === '/exists') {
+    const email = (url.searchParams.get('email') ?? '').trim().toLowerCase();
+    if (!/^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(email)) { jsonResponse(res, 400, { error: 'invalid email address' }); return; }
+    const { rows } = await db.query('SELECT 1 FROM players WHERE email = $1', [email]);
+    jsonResponse(res, 200, { exists: rows.length > 0 });
+    return;
+  }
+
   // ── POST /register ──────────────────────────────────────────────────────────
 
   if (method === 'POST' && url.pathname === '/register') {
@@ -397,14 +450,20 @@ const server = http.createServer(async (req, res) => {
     let data: any;
     try { data = JSON.parse(body || '{}'); } catch { jsonResponse(res, 400, { error: 'invalid JSON' }); return; }
 
-    const email = (data.email ?? '').trim().toLowerCase();
-    const name  = (data.name  ?? '').trim();
+    const email    = (data.email ?? '').trim().toLowerCase();
+    const name     = (data.name  ?? '').trim();
+    const password = typeof data.password === 'string' ? data.password : '';
 
     if (!email || !name)                              { jsonResponse(res, 400, { error: 'email and name required' }); return; }
     if (!/^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(email))   { jsonResponse(res, 400, { error: 'invalid email address' }); return; }
+    if (password && (password.length < MIN_PASSWORD_LENGTH || password.length > MAX_PASSWORD_LENGTH)) {
+      jsonResponse(res, 400, { error: `password must be ${MIN_PASSWORD_LENGTH}-${MAX_PASSWORD_LENGTH} characters` });
+      return;
+    }
     if (await findPlayerByEmail(email))               { jsonResponse(res, 409, { error: 'email already registered' }); return; }
 
     const player = await createPlayer(crypto.randomUUID(), email, name);
+    if (password) await setPasswordHash(player.id, await hashPassword(password));
     await ensureBankAccount(player);
     sendVerificationEmail(player); // fire and forget
 
@@ -417,6 +476,64 @@ const server = http.createServer(async (req, res) => {
     return;
   }
 
+  // ── POST /resend-verification ───────────────────────────────────────────────
+  // Sends a fresh verification link to an unverified account. Always 200 with
+  // the same body, so the response never reveals whether the email exists.
+
+  if (method === 'POST' && url.pathname === '/resend-verification') {
+    const body = await readBody(req);
+    let data: any;
+    try { data = JSON.parse(body || '{}'); } catch { jsonResponse(res, 400, { error: 'invalid JSON' }); return; }
+
+    const email  = (data.email ?? '').trim().toLowerCase();
+    const player = email ? await findPlayerByEmail(email) : null;
+    if (player && !player.verified) {
+      sendVerificationEmail(player); // fire and forget
+      console.log(`[${SERVICE}] Verification email re-sent: id=${player.id}`);
+    }
+    jsonResponse(res, 200, { sent: true });
+    return;
+  }
+
+  // ── POST /login ─────────────────────────────────────────────────────────────
+  // Password fallback for browsers without WebAuthn. Unknown email, no
+  // password set and wrong password all return the same 401.
+
+  if (method === 'POST' && url.pathname === '/login') {
+    const body = await readBody(req);
+    let data: any;
+    try { data = JSON.parse(body || '{}'); } catch { jsonResponse(res, 400, { error: 'invalid JSON' }); return; }
+
+    const email    = (data.email ?? '').trim().toLowerCase();
+    const password = typeof data.password === 'string' ? data.password : '';
+    if (!email || !password || password.length > MAX_PASSWORD_LENGTH) {
+      jsonResponse(res, 400, { error: 'email and password required' });
+      return;
+    }
+
+    const player = await findPlayerByEmail(email);
+    const stored = player ? await getPasswordHash(player.id) : null;
+    if (!player || !(await checkPassword(password, stored))) {
+      console.log(`[${SERVICE}] Password login failed: email=${email}`);
+      jsonResponse(res, 401, { error: 'invalid email or password' });
+      return;
+    }
+    if (!player.verified) {
+      jsonResponse(res, 403, { error: 'email not verified — check your inbox for the activation link' });
+      return;
+    }
+
+    const sessionId = await createSession(player.id);
+    const token     = issueSessionJWT(player, sessionId);
+
+    console.log(`[${SERVICE}] Password login success: player=${player.id}`);
+    jsonResponse(res, 200, {
+      accessToken: token, expiresIn: JWT_EXPIRES_IN,
+      playerId: player.id, playerName: player.name, email: player.email,
+    });
+    return;
+  }
+
   // ── GET /verify-token ───────────────────────────────────────────────────────
 
   if (method === 'GET' && url.pathname === '/verify-token') {
@@ -805,6 +922,7 @@ async function main() {
 
   // Idempotent migration — safe on every restart
   await db.query(`ALTER TABLE players ADD COLUMN IF NOT EXISTS passkey_enrolled BOOLEAN NOT NULL DEFAULT false`);
+  await db.query(`ALTER TABLE players ADD COLUMN IF NOT EXISTS password_hash TEXT`);
   console.log(`[${SERVICE}] Migrations complete`);
 
   server.listen(PORT, () => {
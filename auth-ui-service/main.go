@@ -10,11 +10,17 @@
 // Endpoints:
 //   GET  /fields?action=register|login  — field definitions for modal
 //   POST /submit                        — validate + forward to auth-service
+//                                         (register, or password login)
+//   POST /resend-verification           — re-send the verification email
+//   GET  /available?email=              — is this email free to register?
 //   POST /passkey/register/begin        — proxy to auth-service (requires JWT)
 //   POST /passkey/register/complete     — proxy to auth-service (requires JWT)
 //   POST /passkey/login/begin           — proxy to auth-service
 //   POST /passkey/login/complete        — proxy to auth-service
 //   GET  /health
+//
+// Register submissions and passkey login starts are rate limited per client IP;
+// verification resends are also limited per email.
 
 package main
 
@@ -24,11 +30,17 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 var (
@@ -63,6 +75,8 @@ var registerFields = FieldsResponse{
 			Placeholder: "How should we call you?", MaxLength: 50},
 		{Name: "email", Label: "Email Address", Type: "email", Required: true,
 			Placeholder: "you@example.com", MaxLength: 255},
+		{Name: "password", Label: "Password (optional)", Type: "password", Required: false,
+			Placeholder: "For browsers without passkeys", MaxLength: maxPasswordLength},
 	},
 }
 
@@ -73,6 +87,8 @@ var loginFields = FieldsResponse{
 	Fields: []Field{
 		{Name: "email", Label: "Email Address", Type: "email", Required: true,
 			Placeholder: "you@example.com", MaxLength: 255},
+		{Name: "password", Label: "Password", Type: "password", Required: false,
+			Placeholder: "Leave blank to use your passkey", MaxLength: maxPasswordLength},
 	},
 }
 
@@ -80,6 +96,13 @@ var loginFields = FieldsResponse{
 
 var emailRegex = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
 
+// Password login is a fallback for browsers without WebAuthn. Passwords are
+// optional at registration; when set they must meet these rules.
+const (
+	minPasswordLength = 10
+	maxPasswordLength = 128
+)
+
 type SubmitRequest struct {
 	Action string            `json:"action"`
 	Fields map[string]string `json:"fields"`
@@ -104,9 +127,14 @@ func validateRegister(fields map[string]string) []ValidationError {
 	} else if !emailRegex.MatchString(email) {
 		errs = append(errs, ValidationError{"email", "Please enter a valid email address"})
 	}
+	if fields["password"] != "" {
+		errs = append(errs, validatePassword(fields["password"])...)
+	}
 	return errs
 }
 
+// validateLogin checks a password login. Complexity isn't re-checked here —
+// auth-service decides whether the password is right.
 func validateLogin(fields map[string]string) []ValidationError {
 	var errs []ValidationError
 	email := strings.TrimSpace(fields["email"])
@@ -115,9 +143,40 @@ func validateLogin(fields map[string]string) []ValidationError {
 	} else if !emailRegex.MatchString(email) {
 		errs = append(errs, ValidationError{"email", "Please enter a valid email address"})
 	}
+	if fields["password"] == "" {
+		errs = append(errs, ValidationError{"password", "Password is required"})
+	} else if len(fields["password"]) > maxPasswordLength {
+		errs = append(errs, ValidationError{"password", fmt.Sprintf("Password must be %d characters or less", maxPasswordLength)})
+	}
 	return errs
 }
 
+// validatePassword enforces the registration password rules: length bounds
+// plus at least one lowercase letter, one uppercase letter and one digit.
+func validatePassword(password string) []ValidationError {
+	switch {
+	case len(password) < minPasswordLength:
+		return []ValidationError{{"password", fmt.Sprintf("Password must be at least %d characters", minPasswordLength)}}
+	case len(password) > maxPasswordLength:
+		return []ValidationError{{"password", fmt.Sprintf("Password must be %d characters or less", maxPasswordLength)}}
+	}
+	var lower, upper, digit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+	}
+	if !lower || !upper || !digit {
+		return []ValidationError{{"password", "Password must include upper and lower case letters and a number"}}
+	}
+	return nil
+}
+
 // ── Auth service forwarding ───────────────────────────────────────────────────
 
 type AuthResult struct {
@@ -139,6 +198,15 @@ func forwardToAuth(action string, fields map[string]string) (*AuthResult, int, s
 			"email": strings.TrimSpace(strings.ToLower(fields["email"])),
 			"name":  strings.TrimSpace(fields["name"]),
 		}
+		if fields["password"] != "" {
+			payload["password"] = fields["password"]
+		}
+	case "login":
+		endpoint = "/login"
+		payload = map[string]string{
+			"email":    strings.TrimSpace(strings.ToLower(fields["email"])),
+			"password": fields["password"],
+		}
 	default:
 		return nil, 400, "unknown action"
 	}
@@ -158,6 +226,10 @@ func forwardToAuth(action string, fields map[string]string) (*AuthResult, int, s
 	if resp.StatusCode == 409 {
 		return nil, 409, "that email address is already registered"
 	}
+	if resp.StatusCode == 401 && action == "login" {
+		// Never say which half was wrong
+		return nil, 401, "invalid email or password"
+	}
 	if resp.StatusCode == 401 {
 		return nil, 401, "email not found — have you registered?"
 	}
@@ -262,6 +334,10 @@ func submitHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if throttle(w, r) {
+		return
+	}
+
 	var req SubmitRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, 400, map[string]string{"error": "invalid JSON"})
@@ -276,10 +352,13 @@ func submitHandler(w http.ResponseWriter, r *http.Request) {
 	case "register":
 		validationErrs = validateRegister(req.Fields)
 	case "login":
-		// Login is passkey-only — submit should not be called for login
-		// (UI uses passkey ceremony directly). Return 400 to surface misconfiguration.
-		writeJSON(w, 400, map[string]string{"error": "login requires passkey ceremony — use /passkey/login/begin"})
-		return
+		// Passkey login goes through the ceremony endpoints; /submit only
+		// handles the password fallback. Return 400 to surface misconfiguration.
+		if req.Fields["password"] == "" {
+			writeJSON(w, 400, map[string]string{"error": "login without a password requires passkey ceremony — use /passkey/login/begin"})
+			return
+		}
+		validationErrs = validateLogin(req.Fields)
 	default:
 		writeJSON(w, 400, map[string]string{"error": "action must be 'register' or 'login'"})
 		return
@@ -305,6 +384,113 @@ func submitHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// resendMessage is the only success body /resend-verification ever returns —
+// identical whether or not the email is registered.
+const resendMessage = "If that address has an account awaiting verification, a new link is on its way."
+
+// resendVerificationHandler asks auth-service to re-send a lost verification
+// email. The response is the same generic 200 for any well-formed address so
+// it can't be used to discover accounts.
+func resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		corsHeaders(w)
+		w.WriteHeader(204)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if throttle(w, r) {
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, 400, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+	if !emailRegex.MatchString(email) {
+		writeJSON(w, 422, map[string]any{"error": "validation failed", "fields": []ValidationError{
+			{"email", "Please enter a valid email address"},
+		}})
+		return
+	}
+
+	// Per-email limit stops one inbox being flooded from many IPs
+	if ok, wait := resendLimiter.Allow(email); !ok {
+		log.Printf("[auth-ui-service] resend rate limited for %s", clientIP(r))
+		w.Header().Set("Retry-After", retryAfterSeconds(wait))
+		writeJSON(w, 429, map[string]string{"error": "a verification email was sent recently — check your inbox or try again later"})
+		return
+	}
+
+	body, _ := json.Marshal(map[string]string{"email": email})
+	start := time.Now()
+	resp, err := http.Post(authServiceURL+"/resend-verification", "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[auth-ui] auth-service unreachable (%dms): %v", time.Since(start).Milliseconds(), err)
+		writeJSON(w, 503, map[string]string{"error": "authentication service unavailable"})
+		return
+	}
+	resp.Body.Close()
+	log.Printf("[auth-ui] auth-service /resend-verification → %d (%dms)", resp.StatusCode, time.Since(start).Milliseconds())
+	if resp.StatusCode >= 500 {
+		writeJSON(w, 503, map[string]string{"error": "authentication service unavailable"})
+		return
+	}
+
+	writeJSON(w, 200, map[string]string{"message": resendMessage})
+}
+
+// availableHandler reports whether an email can still be registered, so the
+// form can warn before submit: GET /available?email= → {"available": bool}.
+// It reveals nothing /submit's 409 doesn't, and shares the register rate
+// limit so it is no faster a way to probe for accounts.
+func availableHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		corsHeaders(w)
+		w.WriteHeader(204)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, 405, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if throttle(w, r) {
+		return
+	}
+
+	email := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("email")))
+	if !emailRegex.MatchString(email) {
+		writeJSON(w, 422, map[string]any{"error": "validation failed", "fields": []ValidationError{
+			{"email", "Please enter a valid email address"},
+		}})
+		return
+	}
+
+	start := time.Now()
+	resp, err := http.Get(authServiceURL + "/exists?email=" + url.QueryEscape(email))
+	if err != nil {
+		log.Printf("[auth-ui] auth-service unreachable (%dms): %v", time.Since(start).Milliseconds(), err)
+		writeJSON(w, 503, map[string]string{"error": "authentication service unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Exists bool `json:"exists"`
+	}
+	if resp.StatusCode != 200 || json.NewDecoder(resp.Body).Decode(&result) != nil {
+		log.Printf("[auth-ui] auth-service /exists → %d", resp.StatusCode)
+		writeJSON(w, 502, map[string]string{"error": "unexpected response from auth service"})
+		return
+	}
+	writeJSON(w, 200, map[string]bool{"available": !result.Exists})
+}
+
 // passkeyHandler routes /passkey/* to the appropriate auth-service endpoint
 func passkeyHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
@@ -317,6 +503,11 @@ func passkeyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Each login attempt starts with begin — throttle there
+	if r.URL.Path == "/passkey/login/begin" && throttle(w, r) {
+		return
+	}
+
 	// Strip /passkey prefix — auth-service uses the same path structure
 	authPath := "/passkey" + strings.TrimPrefix(r.URL.Path, "/passkey")
 	proxyToAuth(w, r, authPath)
@@ -337,10 +528,134 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		"language":     "Go",
 		"container":    "scratch",
 		"auth_service": authStatus,
-		"actions":      []string{"register", "login", "passkey/register", "passkey/login"},
+		"actions":      []string{"register", "login", "password/login", "resend-verification", "passkey/register", "passkey/login"},
 	})
 }
 
+// ── Rate limiting ─────────────────────────────────────────────────────────────
+// Auth submissions are throttled per client IP with a token bucket: each IP
+// gets AUTH_RATE_LIMIT_PER_MIN requests a minute, with bursts up to
+// AUTH_RATE_LIMIT_BURST. Buckets that have refilled are dropped by a periodic
+// cleanup, and the table is capped so a flood of addresses can't grow it
+// without bound.
+
+// maxRateLimitKeys caps how many client buckets are tracked at once.
+const maxRateLimitKeys = 10000
+
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	l := &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			l.cleanup()
+		}
+	}()
+	return l
+}
+
+// Allow takes a token for key. When the bucket is empty it reports how long
+// until the next token.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitKeys {
+			l.evictOldestLocked()
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup drops buckets that have refilled — they carry no state.
+func (l *RateLimiter) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// evictOldestLocked drops the least recently used bucket. Caller holds l.mu.
+func (l *RateLimiter) evictOldestLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, b := range l.buckets {
+		if oldestKey == "" || b.last.Before(oldest) {
+			oldestKey, oldest = key, b.last
+		}
+	}
+	delete(l.buckets, oldestKey)
+}
+
+// clientIP identifies the caller. Behind a proxy the address that proxy saw
+// is the last X-Forwarded-For entry — earlier entries are client-supplied.
+func clientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds for Retry-After.
+func retryAfterSeconds(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
+
+// authLimiter throttles register and login attempts. Set in main.
+var authLimiter *RateLimiter
+
+// resendLimiter throttles verification resends per email. Set in main.
+var resendLimiter *RateLimiter
+
+// throttle applies the auth rate limit, writing the 429 itself when the
+// caller is over it.
+func throttle(w http.ResponseWriter, r *http.Request) bool {
+	ok, wait := authLimiter.Allow(clientIP(r))
+	if ok {
+		return false
+	}
+	log.Printf("[auth-ui-service] rate limited %s on %s", clientIP(r), r.URL.Path)
+	w.Header().Set("Retry-After", retryAfterSeconds(wait))
+	writeJSON(w, 429, map[string]string{"error": "too many attempts — try again later"})
+	return true
+}
+
 // ── Main ──────────────────────────────────────────────────────────────────────
 
 func getEnv(key, fallback string) string {
@@ -350,10 +665,23 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
+
 func main() {
+	perMin := getEnvInt("AUTH_RATE_LIMIT_PER_MIN", 10)
+	authLimiter = NewRateLimiter(perMin, getEnvInt("AUTH_RATE_LIMIT_BURST", perMin))
+	resendLimiter = NewRateLimiter(getEnvInt("RESEND_RATE_LIMIT_PER_MIN", 1), getEnvInt("RESEND_RATE_LIMIT_BURST", 3))
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/fields", fieldsHandler)
 	mux.HandleFunc("/submit", submitHandler)
+	mux.HandleFunc("/resend-verification", resendVerificationHandler)
+	mux.HandleFunc("/available", availableHandler)
 	mux.HandleFunc("/passkey/", passkeyHandler)
 	mux.HandleFunc("/health", healthHandler)
 
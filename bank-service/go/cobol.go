@@ -1,23 +1,64 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // cobolDir is set at startup from the COBOL_BIN_DIR environment variable.
 // Defaults to /usr/local/bin/cobol — where Dockerfile places compiled binaries.
 var cobolDir = "/usr/local/bin/cobol"
 
+// ── Execution limits ─────────────────────────────────────────────────────────
+// Every COBOL call is a fork/exec. A hung binary must not hold a bank request
+// forever, and a burst of bets must not fork hundreds of processes at once.
+// Calls queue for one of cobolSlots and the whole call — queueing included —
+// is bounded by cobolTimeout. Both are set at startup from COBOL_TIMEOUT and
+// COBOL_MAX_CONCURRENT.
+
+var (
+	cobolTimeout = 2 * time.Second
+	cobolSlots   = make(chan struct{}, 8)
+
+	cobolTimeouts atomic.Int64 // calls that hit cobolTimeout, reported on /health
+)
+
+// errCOBOLTimeout is returned when a COBOL call did not finish in time.
+var errCOBOLTimeout = errors.New("cobol_timeout")
+
+// SetCOBOLConcurrency resizes the slot pool. Call before serving requests.
+func SetCOBOLConcurrency(n int) {
+	cobolSlots = make(chan struct{}, n)
+}
+
 // RunCOBOL executes a compiled COBOL program with the given environment variables.
 // The program communicates via environment variables (input) and stdout key=value lines (output).
 // Returns a map of output key=value pairs, or an error if the program fails.
-func RunCOBOL(program string, env map[string]string) (map[string]string, error) {
+func RunCOBOL(program string, env map[string]string) (result map[string]string, err error) {
+	start := time.Now()
+	defer func() { reportCOBOL(program, time.Since(start), err) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cobolTimeout)
+	defer cancel()
+
+	select {
+	case cobolSlots <- struct{}{}:
+		defer func() { <-cobolSlots }()
+	case <-ctx.Done():
+		cobolTimeouts.Add(1)
+		return nil, fmt.Errorf("COBOL %s: no free slot within %s: %w", program, cobolTimeout, errCOBOLTimeout)
+	}
+
 	path := cobolDir + "/" + program
-	cmd := exec.Command(path)
+	cmd := exec.CommandContext(ctx, path)
 
 	// Pass input as environment variables
 	for k, v := range env {
@@ -25,6 +66,10 @@ func RunCOBOL(program string, env map[string]string) (map[string]string, error)
 	}
 
 	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		cobolTimeouts.Add(1)
+		return nil, fmt.Errorf("COBOL %s: killed after %s: %w", program, cobolTimeout, errCOBOLTimeout)
+	}
 	if err != nil {
 		// Include stderr in error message if available
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -34,7 +79,7 @@ func RunCOBOL(program string, env map[string]string) (map[string]string, error)
 		return nil, fmt.Errorf("COBOL %s exec error: %w", program, err)
 	}
 
-	result := make(map[string]string)
+	result = make(map[string]string)
 	for _, line := range strings.Split(string(out), "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -56,38 +101,78 @@ func RunCOBOL(program string, env map[string]string) (map[string]string, error)
 // All amounts stored in DB as NUMERIC(15,2) strings e.g. "1000.00"
 // COBOL programs work in integer cents to avoid floating-point arithmetic.
 
+// maxCOBOLCents is the largest magnitude a COBOL PIC S9(15) field can hold.
+const maxCOBOLCents int64 = 999_999_999_999_999
+
 // DollarsToCents converts a decimal string ("1000.00") to integer cents (100000).
-// Parses without floating point to avoid precision loss.
+// Parses without floating point to avoid precision loss. Accepts an optional
+// sign, a missing whole (".5") or fractional ("5.") part, and truncates
+// beyond two decimal places.
 func DollarsToCents(s string) (int64, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return 0, fmt.Errorf("empty amount string")
 	}
-	parts := strings.SplitN(s, ".", 2)
-	whole, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid amount %q: %w", s, err)
+	neg := false
+	digits := s
+	switch digits[0] {
+	case '-':
+		neg = true
+		digits = digits[1:]
+	case '+':
+		digits = digits[1:]
 	}
-	cents := whole * 100
+	parts := strings.SplitN(digits, ".", 2)
+	wholeStr := parts[0]
+	fracStr := ""
 	if len(parts) == 2 {
-		frac := parts[1]
-		switch len(frac) {
-		case 0:
-			// "1000." — no fractional part
-		case 1:
-			frac += "0" // "1000.5" → 50 cents
-		default:
-			frac = frac[:2] // truncate beyond 2 decimal places
-		}
-		f, err := strconv.ParseInt(frac, 10, 64)
-		if err != nil {
-			return 0, fmt.Errorf("invalid fractional amount %q: %w", s, err)
+		fracStr = parts[1]
+	}
+	if wholeStr == "" && fracStr == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if !isDigits(wholeStr) || !isDigits(fracStr) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	var whole int64
+	if wholeStr != "" {
+		var err error
+		whole, err = strconv.ParseInt(wholeStr, 10, 64)
+		if err != nil || whole > maxCOBOLCents/100 {
+			return 0, fmt.Errorf("amount %q out of range", s)
 		}
-		cents += f
+	}
+	switch len(fracStr) {
+	case 0:
+		fracStr = "00" // "1000." — no fractional part
+	case 1:
+		fracStr += "0" // "1000.5" → 50 cents
+	default:
+		fracStr = fracStr[:2] // truncate beyond 2 decimal places
+	}
+	frac, _ := strconv.ParseInt(fracStr, 10, 64)
+
+	cents := whole*100 + frac
+	if cents > maxCOBOLCents {
+		return 0, fmt.Errorf("amount %q out of range", s)
+	}
+	if neg {
+		cents = -cents
 	}
 	return cents, nil
 }
 
+// isDigits reports whether s contains only ASCII digits (empty is allowed).
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // CentsToDollars converts integer cents (100000) to a decimal string ("1000.00").
 func CentsToDollars(cents int64) string {
 	abs := cents
@@ -111,12 +196,16 @@ func ParseCentsResult(result map[string]string, key string) (int64, error) {
 	if !ok {
 		return 0, fmt.Errorf("COBOL output missing key %q", key)
 	}
+	// TrimSpace also drops a trailing CR from CRLF output; ParseInt accepts
+	// COBOL's zero-padded "+000000000100000" form as-is.
 	val = strings.TrimSpace(val)
-	// Strip leading zeros but handle "0" correctly
 	n, err := strconv.ParseInt(val, 10, 64)
 	if err != nil {
 		return 0, fmt.Errorf("COBOL output %q=%q is not an integer: %w", key, val, err)
 	}
+	if n > maxCOBOLCents || n < -maxCOBOLCents {
+		return 0, fmt.Errorf("COBOL output %q=%d exceeds PIC S9(15)", key, n)
+	}
 	return n, nil
 }
 
@@ -150,14 +239,34 @@ func ValidateDebit(balanceCents, debitCents int64) (DebitResult, error) {
 
 type PayoutResult struct {
 	ReturnedCents int64
-	PayoutType    string // "payout_win", "payout_loss", "payout_push"
+	PayoutType    string // "payout_win", "payout_blackjack", "payout_insurance", "payout_loss", "payout_push", "payout_surrender"
 }
 
 // CalcPayout calls CALC-PAYOUT: computes amount to return given bet and result.
+// The ratio for the result comes from the startup payout schedule. Returned
+// cents include the stake — a 100-cent BLACKJACK at 3:2 returns 250.
+// Results are memoized — see payoutCache.
 func CalcPayout(betCents int64, result string) (PayoutResult, error) {
+	result = strings.ToUpper(strings.TrimSpace(result))
+	ratio := payoutSchedule.RatioFor(result)
+	key := payoutKey{betCents: betCents, result: result, ratio: ratio}
+	if p, ok := payoutCache.get(key); ok {
+		return p, nil
+	}
+	p, err := calcPayoutCOBOL(betCents, result, ratio)
+	if err != nil {
+		return PayoutResult{}, err
+	}
+	payoutCache.put(key, p)
+	return p, nil
+}
+
+func calcPayoutCOBOL(betCents int64, result string, ratio Ratio) (PayoutResult, error) {
 	out, err := RunCOBOL("CALC-PAYOUT", map[string]string{
 		"BET_CENTS": CentsToString(betCents),
-		"RESULT":    strings.ToUpper(strings.TrimSpace(result)),
+		"RESULT":    result,
+		"MULT_NUM":  strconv.FormatInt(ratio.Num, 10),
+		"MULT_DEN":  strconv.FormatInt(ratio.Den, 10),
 	})
 	if err != nil {
 		return PayoutResult{}, err
@@ -176,6 +285,64 @@ func CalcPayout(betCents int64, result string) (PayoutResult, error) {
 	}, nil
 }
 
+// ── Payout cache ─────────────────────────────────────────────────────────────
+// CALC-PAYOUT is pure: the same bet, result and ratio always return the same
+// cents. Bets cluster on a handful of chip amounts and there are six results,
+// so a settled hand almost always repeats an earlier (bet, result) pair and
+// the cache turns one of its two COBOL forks into a map lookup. Hit and miss
+// counts are on /health so the win can be read off a running bank.
+//
+// VALIDATE-DEBIT and CALC-CREDIT stay uncached — their inputs include the
+// balance, which is different on nearly every call.
+
+// payoutCacheMax bounds memory; the map is simply reset when it fills. Real
+// traffic sees a few hundred distinct keys, so a reset is rare.
+const payoutCacheMax = 4096
+
+// payoutKey includes the ratio so the cache can never serve a stale schedule.
+type payoutKey struct {
+	betCents int64
+	result   string
+	ratio    Ratio
+}
+
+type payoutMemo struct {
+	mu      sync.Mutex
+	entries map[payoutKey]PayoutResult
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+var payoutCache = &payoutMemo{entries: make(map[payoutKey]PayoutResult)}
+
+func (c *payoutMemo) get(k payoutKey) (PayoutResult, bool) {
+	c.mu.Lock()
+	p, ok := c.entries[k]
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return p, ok
+}
+
+func (c *payoutMemo) put(k payoutKey, p PayoutResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= payoutCacheMax {
+		c.entries = make(map[payoutKey]PayoutResult)
+	}
+	c.entries[k] = p
+}
+
+func (c *payoutMemo) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
 // CalcCredit calls CALC-CREDIT: adds a credit to balance.
 func CalcCredit(balanceCents, creditCents int64) (int64, error) {
 	// Optimization: zero credit is a no-op (payout_loss case)
@@ -192,6 +359,29 @@ func CalcCredit(balanceCents, creditCents int64) (int64, error) {
 	return ParseCentsResult(out, "NEW_BALANCE_CENTS")
 }
 
+// maxBalanceCents caps what credits that bring in new money — deposits,
+// incoming transfers and payouts — may take a balance to. Refunds of a
+// player's own stake or hold are exempt. Zero disables the cap. Set at
+// startup from MAX_BALANCE.
+var maxBalanceCents int64 = 100_000_000
+
+// errBalanceCap is returned by CalcCappedCredit when the credit would take
+// the balance over maxBalanceCents.
+var errBalanceCap = errors.New("balance_cap_exceeded")
+
+// CalcCappedCredit is CalcCredit for new money. On errBalanceCap it still
+// returns the uncapped balance so the caller can decide to clamp instead.
+func CalcCappedCredit(balanceCents, creditCents int64) (int64, error) {
+	newBalCents, err := CalcCredit(balanceCents, creditCents)
+	if err != nil {
+		return 0, err
+	}
+	if maxBalanceCents > 0 && creditCents > 0 && newBalCents > maxBalanceCents {
+		return newBalCents, errBalanceCap
+	}
+	return newBalCents, nil
+}
+
 // roundToTwoDecimals is a safety helper — should never be needed given
 // integer cents arithmetic, but guards against any conversion edge cases.
 func roundToTwoDecimals(f float64) float64 {
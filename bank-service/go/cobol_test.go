@@ -0,0 +1,239 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// realCOBOL points cobolDir at the compiled programs in COBOL_BIN_DIR and
+// skips the test when they aren't there:
+//
+//	mkdir -p /tmp/cobol && cd ../cobol && for p in *.cob; do cobc -x -o /tmp/cobol/${p%.cob} $p; done
+//	COBOL_BIN_DIR=/tmp/cobol go test ./...
+func realCOBOL(t *testing.T) {
+	t.Helper()
+	dir := os.Getenv("COBOL_BIN_DIR")
+	if dir == "" {
+		t.Skip("COBOL_BIN_DIR not set")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "CALC-PAYOUT")); err != nil {
+		t.Skipf("no compiled COBOL in %s: %v", dir, err)
+	}
+	saved := cobolDir
+	cobolDir = dir
+	t.Cleanup(func() { cobolDir = saved })
+	resetPayoutCache(t)
+}
+
+// resetPayoutCache gives the test an empty payout cache, so a result
+// memoized by another test (or another set of programs) can't answer.
+func resetPayoutCache(t *testing.T) {
+	t.Helper()
+	saved := payoutCache
+	payoutCache = &payoutMemo{entries: make(map[payoutKey]PayoutResult)}
+	t.Cleanup(func() { payoutCache = saved })
+}
+
+// ── Cents conversion ─────────────────────────────────────────────────────────
+
+func TestDollarsToCents(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"0.00", 0},
+		{"0", 0},
+		{"1000.00", 100000},
+		{"1000", 100000},
+		{".5", 50},
+		{".05", 5},
+		{"5.", 500},
+		{"1000.5", 100050},
+		{"1000.999", 100099}, // truncates, never rounds up
+		{"0.001", 0},
+		{"  25.00\n", 2500},
+		{"+7.25", 725},
+		{"-7.25", -725},
+		{"-0.50", -50}, // the sign applies to the fraction too
+		{"-.5", -50},
+		{"9999999999999.99", maxCOBOLCents},
+		{"-9999999999999.99", -maxCOBOLCents},
+	}
+	for _, c := range cases {
+		got, err := DollarsToCents(c.in)
+		if err != nil {
+			t.Errorf("DollarsToCents(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("DollarsToCents(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestDollarsToCentsRejects(t *testing.T) {
+	for _, in := range []string{
+		"", "   ", ".", "-", "+", "-.", "abc", "1.2.3", "1,000.00", "1e3",
+		"--5", "5.-1", "5. 5", "0x10",
+		"10000000000000.00",  // one past PIC S9(15)
+		"-10000000000000.00", // and its negative
+		"99999999999999999999",
+	} {
+		if got, err := DollarsToCents(in); err == nil {
+			t.Errorf("DollarsToCents(%q) = %d, want error", in, got)
+		}
+	}
+}
+
+func TestCentsToDollars(t *testing.T) {
+	cases := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{50, "0.50"},
+		{100000, "1000.00"},
+		{-5, "-0.05"},
+		{-725, "-7.25"},
+		{maxCOBOLCents, "9999999999999.99"},
+		{-maxCOBOLCents, "-9999999999999.99"},
+	}
+	for _, c := range cases {
+		if got := CentsToDollars(c.in); got != c.want {
+			t.Errorf("CentsToDollars(%d) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestCentsRoundTrip(t *testing.T) {
+	// CentsToDollars(DollarsToCents(s)) is s in normal form: an optional
+	// minus, the whole part without leading zeros, exactly two decimals.
+	cases := []struct {
+		in         string
+		normalized string
+	}{
+		{"0.00", "0.00"},
+		{"0", "0.00"},
+		{".5", "0.50"},
+		{"5.", "5.00"},
+		{"1000.999", "1000.99"},
+		{"007.10", "7.10"},
+		{"+12.3", "12.30"},
+		{"-12.3", "-12.30"},
+		{"-0.01", "-0.01"},
+		{"9999999999999.99", "9999999999999.99"},
+		{"-9999999999999.999", "-9999999999999.99"},
+	}
+	for _, c := range cases {
+		cents, err := DollarsToCents(c.in)
+		if err != nil {
+			t.Errorf("DollarsToCents(%q): %v", c.in, err)
+			continue
+		}
+		if got := CentsToDollars(cents); got != c.normalized {
+			t.Errorf("round trip %q = %q, want %q", c.in, got, c.normalized)
+		}
+		// A normalized string is a fixed point
+		again, err := DollarsToCents(c.normalized)
+		if err != nil || again != cents {
+			t.Errorf("DollarsToCents(%q) = %d, %v; want %d", c.normalized, again, err, cents)
+		}
+	}
+
+	for _, cents := range []int64{0, 1, 99, 100, 101, 123456789, -1, -99, -100, maxCOBOLCents, -maxCOBOLCents} {
+		got, err := DollarsToCents(CentsToDollars(cents))
+		if err != nil || got != cents {
+			t.Errorf("DollarsToCents(CentsToDollars(%d)) = %d, %v", cents, got, err)
+		}
+	}
+}
+
+func TestCentsToString(t *testing.T) {
+	for cents, want := range map[int64]string{0: "0", 2500: "2500", -50: "-50", maxCOBOLCents: "999999999999999"} {
+		if got := CentsToString(cents); got != want {
+			t.Errorf("CentsToString(%d) = %q, want %q", cents, got, want)
+		}
+	}
+}
+
+// ── COBOL output parsing ─────────────────────────────────────────────────────
+
+func TestParseCentsResult(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want int64
+	}{
+		{"100000", 100000},
+		{"000000000100000", 100000},   // DISPLAY of PIC 9(15)
+		{"+000000000100000", 100000},  // DISPLAY of PIC S9(15) SIGN LEADING SEPARATE
+		{"-000000000000050", -50},     // and negative
+		{"000000000000000", 0},        // zero-padded zero
+		{"  000000000002500  ", 2500}, // space-padded
+		{"000000000002500\r", 2500},   // CRLF line ending
+		{"\t2500\r\n", 2500},
+		{"999999999999999", maxCOBOLCents},
+		{"-999999999999999", -maxCOBOLCents},
+	}
+	for _, c := range cases {
+		got, err := ParseCentsResult(map[string]string{"NEW_BALANCE_CENTS": c.raw}, "NEW_BALANCE_CENTS")
+		if err != nil {
+			t.Errorf("ParseCentsResult(%q): %v", c.raw, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseCentsResult(%q) = %d, want %d", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestParseCentsResultRejects(t *testing.T) {
+	if _, err := ParseCentsResult(map[string]string{}, "RETURNED_CENTS"); err == nil ||
+		!strings.Contains(err.Error(), "missing key") {
+		t.Errorf("missing key: err = %v", err)
+	}
+	for _, raw := range []string{"", "   ", "12.50", "1,000", "ERR", "1000000000000000", "-1000000000000000"} {
+		if got, err := ParseCentsResult(map[string]string{"RETURNED_CENTS": raw}, "RETURNED_CENTS"); err == nil {
+			t.Errorf("ParseCentsResult(%q) = %d, want error", raw, got)
+		}
+	}
+}
+
+// ── Payouts ──────────────────────────────────────────────────────────────────
+
+// checkBlackjackPayout asserts a 100-cent natural returns 250 cents — the
+// stake plus 3:2 — under the default schedule, and that it isn't paid as a
+// plain win.
+func checkBlackjackPayout(t *testing.T) {
+	t.Helper()
+	for _, result := range []string{"blackjack", "BLACKJACK", " Blackjack "} {
+		p, err := CalcPayout(100, result)
+		if err != nil {
+			t.Fatalf("CalcPayout(100, %q): %v", result, err)
+		}
+		if p.ReturnedCents != 250 || p.PayoutType != "payout_blackjack" {
+			t.Errorf("CalcPayout(100, %q) = %+v, want 250 payout_blackjack", result, p)
+		}
+	}
+	win, err := CalcPayout(100, "win")
+	if err != nil {
+		t.Fatalf("CalcPayout(100, win): %v", err)
+	}
+	if win.ReturnedCents != 200 || win.PayoutType != "payout_win" {
+		t.Errorf("CalcPayout(100, win) = %+v, want 200 payout_win", win)
+	}
+}
+
+func TestCalcPayoutBlackjack(t *testing.T) {
+	realCOBOL(t)
+	checkBlackjackPayout(t)
+}
+
+func TestCalcPayoutBlackjackWiring(t *testing.T) {
+	// The stub does the COBOL arithmetic; this covers the Go side — the
+	// result and ratio reaching CALC-PAYOUT and its output being read back.
+	stubCOBOL(t)
+	checkBlackjackPayout(t)
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // DB wraps the PostgreSQL connection pool.
@@ -14,9 +17,14 @@ type DB struct {
 	pool *sql.DB
 }
 
-const (
-	DemoPlayerID    = "player-00000000-0000-0000-0000-000000000001"
-	StartingBalance = "1000.00"
+const DemoPlayerID = "player-00000000-0000-0000-0000-000000000001"
+
+// StartingBalance is the default balance for new accounts (STARTING_BALANCE).
+// MaxStartingBalance caps both the default and any balance requested on
+// POST /account. Both are dollar strings, set once at startup.
+var (
+	StartingBalance    = "1000.00"
+	MaxStartingBalance = "100000.00"
 )
 
 // NewDB opens a PostgreSQL connection pool and waits for the DB to be ready.
@@ -52,6 +60,11 @@ func (d *DB) waitReady() error {
 	return fmt.Errorf("bank-db unavailable after 60s")
 }
 
+// Ping checks the pool can still reach the database.
+func (d *DB) Ping(ctx context.Context) error {
+	return d.pool.PingContext(ctx)
+}
+
 // Migrate creates tables if they don't exist. Idempotent.
 func (d *DB) Migrate() error {
 	_, err := d.pool.Exec(`
@@ -64,6 +77,11 @@ func (d *DB) Migrate() error {
 	if err != nil {
 		return fmt.Errorf("migrate accounts: %w", err)
 	}
+	// Account freeze — set by self-exclusion, lifts itself once the time passes
+	_, err = d.pool.Exec(`ALTER TABLE accounts ADD COLUMN IF NOT EXISTS frozen_until TIMESTAMPTZ`)
+	if err != nil {
+		return fmt.Errorf("migrate accounts frozen_until: %w", err)
+	}
 	_, err = d.pool.Exec(`
 		CREATE TABLE IF NOT EXISTS transactions (
 			id             UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -91,6 +109,45 @@ func (d *DB) Migrate() error {
 	if err != nil {
 		return fmt.Errorf("migrate open_bets: %w", err)
 	}
+	// Double-down bets point at the base bet they extend
+	_, err = d.pool.Exec(`ALTER TABLE open_bets ADD COLUMN IF NOT EXISTS parent_transaction_id VARCHAR(100)`)
+	if err != nil {
+		return fmt.Errorf("migrate open_bets parent_transaction_id: %w", err)
+	}
+	_, err = d.pool.Exec(`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS parent_transaction_id VARCHAR(100)`)
+	if err != nil {
+		return fmt.Errorf("migrate transactions parent_transaction_id: %w", err)
+	}
+	_, err = d.pool.Exec(`
+		CREATE TABLE IF NOT EXISTS holds (
+			hold_id     VARCHAR(100)  PRIMARY KEY,
+			player_id   VARCHAR(100)  NOT NULL REFERENCES accounts(player_id),
+			amount      NUMERIC(15,2) NOT NULL,
+			expires_at  TIMESTAMPTZ   NOT NULL,
+			created_at  TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("migrate holds: %w", err)
+	}
+	_, err = d.pool.Exec(`
+		CREATE TABLE IF NOT EXISTS payout_audit (
+			id              BIGSERIAL     PRIMARY KEY,
+			transaction_id  VARCHAR(100)  NOT NULL,
+			player_id       VARCHAR(100)  NOT NULL,
+			claimed_result  VARCHAR(20)   NOT NULL,
+			expected_result VARCHAR(20)   NOT NULL,
+			player_value    INT           NOT NULL,
+			player_cards    INT           NOT NULL,
+			dealer_value    INT           NOT NULL,
+			dealer_cards    INT           NOT NULL,
+			rejected        BOOLEAN       NOT NULL,
+			created_at      TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("migrate payout_audit: %w", err)
+	}
 	_, err = d.pool.Exec(`
 		CREATE INDEX IF NOT EXISTS idx_transactions_player
 			ON transactions(player_id, created_at DESC)
@@ -98,6 +155,16 @@ func (d *DB) Migrate() error {
 	if err != nil {
 		return fmt.Errorf("migrate index: %w", err)
 	}
+	// One transaction per (player, type, ref_id) — makes refId-carrying
+	// deposits and withdrawals idempotent at the database level
+	_, err = d.pool.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_ref
+			ON transactions(player_id, type, ref_id)
+			WHERE ref_id IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("migrate ref index: %w", err)
+	}
 	log.Printf("[bank-db] schema ready")
 	return nil
 }
@@ -147,6 +214,65 @@ func (d *DB) GetBalance(playerID string) (string, bool, error) {
 	return balance, true, err
 }
 
+// FrozenUntil returns when the player's account freeze ends, or nil if the
+// account is not frozen. An expired freeze counts as not frozen.
+func (d *DB) FrozenUntil(playerID string) (*time.Time, error) {
+	var until sql.NullTime
+	err := d.pool.QueryRow(
+		`SELECT frozen_until FROM accounts WHERE player_id=$1 AND frozen_until > NOW()`, playerID,
+	).Scan(&until)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil || !until.Valid {
+		return nil, err
+	}
+	t := until.Time.UTC()
+	return &t, nil
+}
+
+// FreezeUntil freezes the account until the given time. A freeze can only be
+// extended, never shortened — a player cannot cut their own exclusion short.
+func (d *DB) FreezeUntil(playerID string, until time.Time) (time.Time, bool, error) {
+	var effective time.Time
+	err := d.pool.QueryRow(
+		`UPDATE accounts
+		 SET frozen_until = GREATEST(COALESCE(frozen_until, NOW()), $2)
+		 WHERE player_id=$1
+		 RETURNING frozen_until`,
+		playerID, until,
+	).Scan(&effective)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	return effective.UTC(), true, err
+}
+
+// ── Balance updates ───────────────────────────────────────────────────────────
+// Every balance change is a read-modify-write: the handler reads the balance,
+// COBOL computes the new one, and the write happens here. The write only lands
+// if the balance still equals what was read, so two concurrent bets or payouts
+// for one player can't clobber each other. The loser gets errBalanceChanged
+// with nothing written and can re-read and retry.
+
+// errBalanceChanged means the balance moved between read and write.
+var errBalanceChanged = fmt.Errorf("balance changed concurrently")
+
+// updateBalance sets the balance only if it still equals balanceBefore.
+func updateBalance(tx *sql.Tx, playerID, balanceBefore, newBalance string) error {
+	res, err := tx.Exec(
+		`UPDATE accounts SET balance=$1 WHERE player_id=$2 AND balance=$3`,
+		newBalance, playerID, balanceBefore,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBalanceChanged
+	}
+	return nil
+}
+
 // ── Bet operations ────────────────────────────────────────────────────────────
 
 type BetRecord struct {
@@ -159,8 +285,11 @@ type BetRecord struct {
 
 // PlaceBet debits a bet from the player's balance in a transaction.
 // Returns the new balance string and a transaction ID.
+// parentTxID links a double-down or insurance bet to its base bet ("" for a
+// base bet); the bet row's ref_id is its own transaction ID, as on the payout
+// that settles it.
 // The caller has already validated funds via COBOL.
-func (d *DB) PlaceBet(playerID, balanceBefore, newBalance, amount string) (string, error) {
+func (d *DB) PlaceBet(playerID, balanceBefore, newBalance, amount, parentTxID string) (string, error) {
 	tx, err := d.pool.Begin()
 	if err != nil {
 		return "", err
@@ -168,33 +297,35 @@ func (d *DB) PlaceBet(playerID, balanceBefore, newBalance, amount string) (strin
 	defer tx.Rollback()
 
 	// Update balance
-	_, err = tx.Exec(
-		`UPDATE accounts SET balance=$1 WHERE player_id=$2`,
-		newBalance, playerID,
-	)
-	if err != nil {
+	if err := updateBalance(tx, playerID, balanceBefore, newBalance); err != nil {
 		return "", fmt.Errorf("place bet update balance: %w", err)
 	}
 
+	// Open bet UUID doubles as the transaction ID
+	var txID string
+	err = tx.QueryRow(`SELECT gen_random_uuid()::text`).Scan(&txID)
+	if err != nil {
+		return "", fmt.Errorf("place bet generate uuid: %w", err)
+	}
+	var parent any
+	if parentTxID != "" {
+		parent = parentTxID
+	}
+
 	// Record transaction
 	_, err = tx.Exec(
-		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after)
-		 VALUES($1, 'bet', $2, $3, $4)`,
-		playerID, amount, balanceBefore, newBalance,
+		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, ref_id, parent_transaction_id)
+		 VALUES($1, 'bet', $2, $3, $4, $5, $6)`,
+		playerID, amount, balanceBefore, newBalance, txID, parent,
 	)
 	if err != nil {
 		return "", fmt.Errorf("place bet record transaction: %w", err)
 	}
 
-	// Record open bet with UUID as transaction ID
-	var txID string
-	err = tx.QueryRow(`SELECT gen_random_uuid()::text`).Scan(&txID)
-	if err != nil {
-		return "", fmt.Errorf("place bet generate uuid: %w", err)
-	}
+	// Record open bet
 	_, err = tx.Exec(
-		`INSERT INTO open_bets(transaction_id, player_id, amount) VALUES($1, $2, $3)`,
-		txID, playerID, amount,
+		`INSERT INTO open_bets(transaction_id, player_id, amount, parent_transaction_id) VALUES($1, $2, $3, $4)`,
+		txID, playerID, amount, parent,
 	)
 	if err != nil {
 		return "", fmt.Errorf("place bet open bet: %w", err)
@@ -236,6 +367,41 @@ func (d *DB) GetOpenBet(txID string) (*OpenBet, error) {
 	return &bet, err
 }
 
+// OpenBetInfo is an unsettled bet as listed by GET /open-bets.
+type OpenBetInfo struct {
+	TransactionID string `json:"transactionId"`
+	Amount        string `json:"amount"`
+	CreatedAt     string `json:"createdAt"`
+	AgeSeconds    int64  `json:"ageSeconds"`
+}
+
+// GetOpenBets lists a player's unsettled bets, oldest first.
+func (d *DB) GetOpenBets(playerID string) ([]OpenBetInfo, error) {
+	rows, err := d.pool.Query(
+		`SELECT transaction_id, amount::text, created_at
+		 FROM open_bets WHERE player_id=$1 ORDER BY created_at`, playerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	bets := []OpenBetInfo{}
+	for rows.Next() {
+		var b OpenBetInfo
+		var createdAt time.Time
+		if err := rows.Scan(&b.TransactionID, &b.Amount, &createdAt); err != nil {
+			return nil, err
+		}
+		b.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		b.AgeSeconds = int64(time.Since(createdAt).Seconds())
+		bets = append(bets, b)
+	}
+	return bets, rows.Err()
+}
+
+// errBetNotFound is returned when a bet was already settled.
+var errBetNotFound = fmt.Errorf("open bet not found")
+
 type PayoutRecord struct {
 	PlayerID      string
 	BalanceBefore string
@@ -254,69 +420,391 @@ func (d *DB) SettlePayout(txID, playerID, balanceBefore, newBalance, returned, p
 	}
 	defer tx.Rollback()
 
+	// Delete first — a loss leaves the balance unchanged, so the balance guard
+	// alone would not stop a concurrent second payout of the same bet
+	var parent sql.NullString
+	err = tx.QueryRow(
+		`DELETE FROM open_bets WHERE transaction_id=$1 RETURNING parent_transaction_id`, txID,
+	).Scan(&parent)
+	if err == sql.ErrNoRows {
+		return errBetNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("settle payout delete open bet: %w", err)
+	}
+
 	// Update balance
+	if err := updateBalance(tx, playerID, balanceBefore, newBalance); err != nil {
+		return fmt.Errorf("settle payout update balance: %w", err)
+	}
+
+	// Record transaction — a double-down payout keeps its link to the base bet
 	_, err = tx.Exec(
-		`UPDATE accounts SET balance=$1 WHERE player_id=$2`,
-		newBalance, playerID,
+		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, ref_id, parent_transaction_id)
+		 VALUES($1, $2, $3, $4, $5, $6, $7)`,
+		playerID, payoutType, returned, balanceBefore, newBalance, txID, parent,
 	)
 	if err != nil {
-		return fmt.Errorf("settle payout update balance: %w", err)
+		return fmt.Errorf("settle payout record transaction: %w", err)
 	}
 
-	// Record transaction
+	return tx.Commit()
+}
+
+// CancelBet reverses an unplayed bet: the stake is credited back, a
+// bet_cancelled transaction is recorded, and the open bet is deleted — all in
+// one transaction. Returns errBetNotFound if the bet was already settled.
+func (d *DB) CancelBet(txID, playerID, balanceBefore, newBalance, amount string) error {
+	tx, err := d.pool.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Delete first — guards against a concurrent payout or cancel
+	res, err := tx.Exec(`DELETE FROM open_bets WHERE transaction_id=$1`, txID)
+	if err != nil {
+		return fmt.Errorf("cancel bet delete open bet: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errBetNotFound
+	}
+
+	if err := updateBalance(tx, playerID, balanceBefore, newBalance); err != nil {
+		return fmt.Errorf("cancel bet update balance: %w", err)
+	}
 	_, err = tx.Exec(
 		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, ref_id)
-		 VALUES($1, $2, $3, $4, $5, $6)`,
-		playerID, payoutType, returned, balanceBefore, newBalance, txID,
+		 VALUES($1, 'bet_cancelled', $2, $3, $4, $5)`,
+		playerID, amount, balanceBefore, newBalance, txID,
 	)
 	if err != nil {
-		return fmt.Errorf("settle payout record transaction: %w", err)
+		return fmt.Errorf("cancel bet record transaction: %w", err)
 	}
 
-	// Delete open bet
-	_, err = tx.Exec(`DELETE FROM open_bets WHERE transaction_id=$1`, txID)
+	return tx.Commit()
+}
+
+// RecordPayoutMismatch writes a settlement-guard finding to payout_audit.
+func (d *DB) RecordPayoutMismatch(txID, playerID, claimed, expected string, hand HandEvidence, rejected bool) error {
+	_, err := d.pool.Exec(
+		`INSERT INTO payout_audit(transaction_id, player_id, claimed_result, expected_result,
+		   player_value, player_cards, dealer_value, dealer_cards, rejected)
+		 VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		txID, playerID, strings.ToUpper(claimed), expected,
+		hand.PlayerValue, hand.PlayerCards, hand.DealerValue, hand.DealerCards, rejected,
+	)
 	if err != nil {
-		return fmt.Errorf("settle payout delete open bet: %w", err)
+		return fmt.Errorf("record payout mismatch: %w", err)
+	}
+	return nil
+}
+
+// ── Hold operations ───────────────────────────────────────────────────────────
+// A hold reserves funds (balance already reduced) without opening a bet.
+// It is either committed into an open bet, released, or swept at expiry.
+
+type Hold struct {
+	HoldID    string
+	PlayerID  string
+	Amount    string
+	ExpiresAt time.Time
+}
+
+// errHoldNotFound is returned when a hold was already committed, released or swept.
+var errHoldNotFound = fmt.Errorf("hold not found")
+
+// errHoldExpired is returned when a hold is past its TTL but not yet swept.
+var errHoldExpired = fmt.Errorf("hold expired")
+
+// PlaceHold debits the held amount and records the hold in one transaction.
+// The caller has already validated funds via COBOL.
+func (d *DB) PlaceHold(playerID, balanceBefore, newBalance, amount string, ttl time.Duration) (Hold, error) {
+	tx, err := d.pool.Begin()
+	if err != nil {
+		return Hold{}, err
+	}
+	defer tx.Rollback()
+
+	if err := updateBalance(tx, playerID, balanceBefore, newBalance); err != nil {
+		return Hold{}, fmt.Errorf("place hold update balance: %w", err)
+	}
+
+	hold := Hold{PlayerID: playerID, Amount: amount, ExpiresAt: time.Now().Add(ttl).UTC()}
+	err = tx.QueryRow(`SELECT gen_random_uuid()::text`).Scan(&hold.HoldID)
+	if err != nil {
+		return Hold{}, fmt.Errorf("place hold generate uuid: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO holds(hold_id, player_id, amount, expires_at) VALUES($1, $2, $3, $4)`,
+		hold.HoldID, playerID, amount, hold.ExpiresAt,
+	)
+	if err != nil {
+		return Hold{}, fmt.Errorf("place hold record hold: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, ref_id)
+		 VALUES($1, 'hold', $2, $3, $4, $5)`,
+		playerID, amount, balanceBefore, newBalance, hold.HoldID,
+	)
+	if err != nil {
+		return Hold{}, fmt.Errorf("place hold record transaction: %w", err)
+	}
+
+	return hold, tx.Commit()
+}
+
+// GetHold retrieves a hold by ID. Returns nil if not found.
+func (d *DB) GetHold(holdID string) (*Hold, error) {
+	var h Hold
+	err := d.pool.QueryRow(
+		`SELECT hold_id, player_id, amount::text, expires_at FROM holds WHERE hold_id=$1`, holdID,
+	).Scan(&h.HoldID, &h.PlayerID, &h.Amount, &h.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &h, err
+}
+
+// CommitHold converts a hold into an open bet. The funds were debited when
+// the hold was placed, so the balance does not change. Returns the bet's
+// transaction ID for the normal /payout flow; like PlaceBet's, the bet row's
+// ref_id is that ID, and the hold it came from is in the note. A hold past
+// its TTL can't be committed (errHoldExpired) — the sweeper returns its funds.
+func (d *DB) CommitHold(holdID string) (string, error) {
+	tx, err := d.pool.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// Delete first — guards against a concurrent release or sweep
+	var playerID, amount string
+	err = tx.QueryRow(
+		`DELETE FROM holds WHERE hold_id=$1 AND expires_at > NOW() RETURNING player_id, amount::text`, holdID,
+	).Scan(&playerID, &amount)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if tx.QueryRow(`SELECT true FROM holds WHERE hold_id=$1`, holdID).Scan(&exists) == nil {
+			return "", errHoldExpired
+		}
+		return "", errHoldNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("commit hold delete: %w", err)
+	}
+
+	var balance string
+	err = tx.QueryRow(`SELECT balance::text FROM accounts WHERE player_id=$1`, playerID).Scan(&balance)
+	if err != nil {
+		return "", fmt.Errorf("commit hold get balance: %w", err)
+	}
+
+	var txID string
+	err = tx.QueryRow(`SELECT gen_random_uuid()::text`).Scan(&txID)
+	if err != nil {
+		return "", fmt.Errorf("commit hold generate uuid: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO open_bets(transaction_id, player_id, amount) VALUES($1, $2, $3)`,
+		txID, playerID, amount,
+	)
+	if err != nil {
+		return "", fmt.Errorf("commit hold open bet: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, ref_id, note)
+		 VALUES($1, 'bet', $2, $3, $3, $4, $5)`,
+		playerID, amount, balance, txID, "hold "+holdID,
+	)
+	if err != nil {
+		return "", fmt.Errorf("commit hold record transaction: %w", err)
+	}
+
+	return txID, tx.Commit()
+}
+
+// ReleaseHold returns held funds to the player and deletes the hold.
+// txType is "hold_release" or "hold_expired".
+func (d *DB) ReleaseHold(holdID, playerID, balanceBefore, newBalance, amount, txType string) error {
+	tx, err := d.pool.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Delete first — guards against a concurrent commit or sweep
+	res, err := tx.Exec(`DELETE FROM holds WHERE hold_id=$1`, holdID)
+	if err != nil {
+		return fmt.Errorf("release hold delete: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errHoldNotFound
+	}
+
+	if err := updateBalance(tx, playerID, balanceBefore, newBalance); err != nil {
+		return fmt.Errorf("release hold update balance: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, ref_id)
+		 VALUES($1, $2, $3, $4, $5, $6)`,
+		playerID, txType, amount, balanceBefore, newBalance, holdID,
+	)
+	if err != nil {
+		return fmt.Errorf("release hold record transaction: %w", err)
 	}
 
 	return tx.Commit()
 }
 
+// ExpiredHolds returns holds past their expiry, oldest first.
+func (d *DB) ExpiredHolds() ([]Hold, error) {
+	rows, err := d.pool.Query(
+		`SELECT hold_id, player_id, amount::text, expires_at
+		 FROM holds WHERE expires_at < NOW() ORDER BY expires_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var holds []Hold
+	for rows.Next() {
+		var h Hold
+		if err := rows.Scan(&h.HoldID, &h.PlayerID, &h.Amount, &h.ExpiresAt); err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, rows.Err()
+}
+
 // ── Deposit / Withdraw ────────────────────────────────────────────────────────
 
+// errDuplicateRef is returned when a transaction with the same player, type
+// and ref_id was already recorded — the change was applied once already.
+var errDuplicateRef = fmt.Errorf("duplicate ref_id")
+
 // ApplyBalanceChange updates the balance and records a transaction.
 // Used for deposits, withdrawals, and any direct balance adjustments.
-func (d *DB) ApplyBalanceChange(playerID, balanceBefore, newBalance, amount, txType, note string) error {
+// A non-empty refID makes the change idempotent: a second change with the
+// same player, type and refID rolls back with errDuplicateRef.
+func (d *DB) ApplyBalanceChange(playerID, balanceBefore, newBalance, amount, txType, note, refID string) error {
 	tx, err := d.pool.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(
-		`UPDATE accounts SET balance=$1 WHERE player_id=$2`,
-		newBalance, playerID,
-	)
-	if err != nil {
+	if err := updateBalance(tx, playerID, balanceBefore, newBalance); err != nil {
 		return fmt.Errorf("apply balance change: %w", err)
 	}
 
-	var noteVal interface{}
+	var noteVal, refVal interface{}
 	if note != "" {
 		noteVal = note
 	}
-	_, err = tx.Exec(
-		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, note)
-		 VALUES($1, $2, $3, $4, $5, $6)`,
-		playerID, txType, amount, balanceBefore, newBalance, noteVal,
+	if refID != "" {
+		refVal = refID
+	}
+	res, err := tx.Exec(
+		`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, note, ref_id)
+		 VALUES($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (player_id, type, ref_id) WHERE ref_id IS NOT NULL DO NOTHING`,
+		playerID, txType, amount, balanceBefore, newBalance, noteVal, refVal,
 	)
 	if err != nil {
 		return fmt.Errorf("apply balance change record: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errDuplicateRef
+	}
 
 	return tx.Commit()
 }
 
+// GetTransactionByRef returns the player's transaction of txType carrying
+// refID. Returns nil if there is none.
+func (d *DB) GetTransactionByRef(playerID, txType, refID string) (*Transaction, error) {
+	var t Transaction
+	var createdAt time.Time
+	err := d.pool.QueryRow(
+		`SELECT id, type, amount::text, balance_before::text, balance_after::text,
+		        ref_id, parent_transaction_id, note, created_at
+		 FROM transactions
+		 WHERE player_id=$1 AND type=$2 AND ref_id=$3`,
+		playerID, txType, refID,
+	).Scan(
+		&t.ID, &t.Type, &t.Amount,
+		&t.BalanceBefore, &t.BalanceAfter,
+		&t.RefID, &t.ParentTxID, &t.Note, &createdAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	return &t, nil
+}
+
+// ── Transfers ─────────────────────────────────────────────────────────────────
+
+// TransferSide is one account's half of a transfer.
+type TransferSide struct {
+	PlayerID      string
+	BalanceBefore string
+	BalanceAfter  string
+}
+
+// Transfer moves amount between two players in one transaction: both balances
+// change and a transfer_out / transfer_in pair is recorded under a shared
+// ref_id, which is returned. The caller has already validated funds via COBOL.
+func (d *DB) Transfer(from, to TransferSide, amount string) (string, error) {
+	tx, err := d.pool.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// Lock rows in a fixed order so opposing transfers can't deadlock
+	sides := []TransferSide{from, to}
+	if to.PlayerID < from.PlayerID {
+		sides[0], sides[1] = to, from
+	}
+	for _, side := range sides {
+		if err := updateBalance(tx, side.PlayerID, side.BalanceBefore, side.BalanceAfter); err != nil {
+			return "", fmt.Errorf("transfer update balance: %w", err)
+		}
+	}
+
+	var refID string
+	err = tx.QueryRow(`SELECT gen_random_uuid()::text`).Scan(&refID)
+	if err != nil {
+		return "", fmt.Errorf("transfer generate uuid: %w", err)
+	}
+	legs := []struct {
+		side   TransferSide
+		txType string
+		note   string
+	}{
+		{from, "transfer_out", "to " + to.PlayerID},
+		{to, "transfer_in", "from " + from.PlayerID},
+	}
+	for _, leg := range legs {
+		_, err = tx.Exec(
+			`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, ref_id, note)
+			 VALUES($1, $2, $3, $4, $5, $6, $7)`,
+			leg.side.PlayerID, leg.txType, amount, leg.side.BalanceBefore, leg.side.BalanceAfter, refID, leg.note,
+		)
+		if err != nil {
+			return "", fmt.Errorf("transfer record transaction: %w", err)
+		}
+	}
+
+	return refID, tx.Commit()
+}
+
 // ── Transaction history ───────────────────────────────────────────────────────
 
 type Transaction struct {
@@ -326,34 +814,183 @@ type Transaction struct {
 	BalanceBefore string  `json:"balanceBefore"`
 	BalanceAfter  string  `json:"balanceAfter"`
 	RefID         *string `json:"refId"`
+	ParentTxID    *string `json:"parentTransactionId"` // base bet of a double-down or insurance bet
 	Note          *string `json:"note"`
 	CreatedAt     string  `json:"createdAt"`
 }
 
-// GetTransactions returns the transaction history for a player.
-func (d *DB) GetTransactions(playerID string, limit int) ([]Transaction, error) {
-	rows, err := d.pool.Query(
-		`SELECT id, type, amount::text, balance_before::text, balance_after::text,
-		        ref_id, note, created_at
-		 FROM transactions
-		 WHERE player_id=$1
-		 ORDER BY created_at DESC
-		 LIMIT $2`,
-		playerID, limit,
-	)
+// TxCursor is a position in a player's history, newest first. A page holds
+// the rows strictly older than the cursor. ID breaks ties between rows with
+// the same timestamp; a cursor without one is a plain "before this time".
+type TxCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode returns the opaque cursor string handed to clients.
+func (c TxCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseTxCursor accepts either an RFC3339 timestamp or an opaque cursor
+// from a previous page's nextCursor.
+func ParseTxCursor(s string) (TxCursor, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return TxCursor{CreatedAt: t}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return TxCursor{}, fmt.Errorf("invalid cursor")
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return TxCursor{}, fmt.Errorf("invalid cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return TxCursor{}, fmt.Errorf("invalid cursor")
+	}
+	return TxCursor{CreatedAt: t, ID: id}, nil
+}
+
+// TxRange limits history to created_at between From and To, inclusive.
+// A zero bound is open.
+type TxRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// IsZero reports whether the range is unbounded on both ends.
+func (r TxRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// String describes the range for statement headings, e.g.
+// "2026-01-01T00:00:00Z to 2026-03-31T23:59:59Z". Empty when unbounded.
+func (r TxRange) String() string {
+	switch {
+	case r.IsZero():
+		return ""
+	case r.To.IsZero():
+		return "from " + r.From.UTC().Format(time.RFC3339)
+	case r.From.IsZero():
+		return "up to " + r.To.UTC().Format(time.RFC3339)
+	default:
+		return r.From.UTC().Format(time.RFC3339) + " to " + r.To.UTC().Format(time.RFC3339)
+	}
+}
+
+// where appends the range's conditions to a query whose args are args.
+func (r TxRange) where(query string, args []any) (string, []any) {
+	if !r.From.IsZero() {
+		args = append(args, r.From)
+		query += fmt.Sprintf(` AND created_at >= $%d`, len(args))
+	}
+	if !r.To.IsZero() {
+		args = append(args, r.To)
+		query += fmt.Sprintf(` AND created_at <= $%d`, len(args))
+	}
+	return query, args
+}
+
+// TxTypes lists every transaction type the bank records.
+var TxTypes = map[string]bool{
+	"bet": true, "bet_cancelled": true,
+	"payout_win": true, "payout_blackjack": true, "payout_insurance": true,
+	"payout_loss": true, "payout_push": true, "payout_surrender": true,
+	"hold": true, "hold_release": true, "hold_expired": true,
+	"deposit": true, "withdrawal": true, "cashout": true,
+	"transfer_in": true, "transfer_out": true,
+}
+
+// GetTransactions returns one page of a player's history within rng, newest
+// first, starting after before (nil for the newest page). A non-empty types
+// keeps only those transaction types. The returned cursor is non-nil when
+// older rows remain.
+func (d *DB) GetTransactions(playerID string, rng TxRange, types []string, before *TxCursor, limit int) ([]Transaction, *TxCursor, error) {
+	query := `SELECT id, type, amount::text, balance_before::text, balance_after::text,
+	                 ref_id, parent_transaction_id, note, created_at
+	          FROM transactions
+	          WHERE player_id=$1`
+	args := []any{playerID}
+	query, args = rng.where(query, args)
+	if len(types) > 0 {
+		args = append(args, pq.Array(types))
+		query += fmt.Sprintf(` AND type = ANY($%d)`, len(args))
+	}
+	switch {
+	case before == nil:
+	case before.ID == "":
+		args = append(args, before.CreatedAt)
+		query += fmt.Sprintf(` AND created_at < $%d`, len(args))
+	default:
+		args = append(args, before.CreatedAt, before.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d::uuid)`, len(args)-1, len(args))
+	}
+	// Fetch one extra row to learn whether another page exists
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args)+1)
+	args = append(args, limit+1)
+
+	rows, err := d.pool.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	txns := []Transaction{} // never return null — always return an array
+	var next *TxCursor
+	var lastCreated time.Time // full precision — CreatedAt is rounded to seconds
+	for rows.Next() {
+		var t Transaction
+		var createdAt time.Time
+		err := rows.Scan(
+			&t.ID, &t.Type, &t.Amount,
+			&t.BalanceBefore, &t.BalanceAfter,
+			&t.RefID, &t.ParentTxID, &t.Note, &createdAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(txns) == limit {
+			last := txns[len(txns)-1]
+			next = &TxCursor{CreatedAt: lastCreated, ID: last.ID}
+			break
+		}
+		lastCreated = createdAt
+		t.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		txns = append(txns, t)
+	}
+	return txns, next, rows.Err()
+}
+
+// GetTransactionsAsOf returns the history within rng as it stood at asOf —
+// the exact row set a statement generated at that instant covered. Rows that
+// share a created_at are ordered by id, so signing and verification always
+// see the same rows in the same order, whatever falls under the limit.
+func (d *DB) GetTransactionsAsOf(playerID string, asOf time.Time, rng TxRange, limit int) ([]Transaction, error) {
+	query := `SELECT id, type, amount::text, balance_before::text, balance_after::text,
+	                 ref_id, parent_transaction_id, note, created_at
+	          FROM transactions
+	          WHERE player_id=$1 AND created_at <= $2`
+	query, args := rng.where(query, []any{playerID, asOf})
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := d.pool.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var txns []Transaction
+	txns := []Transaction{}
 	for rows.Next() {
 		var t Transaction
 		var createdAt time.Time
 		err := rows.Scan(
 			&t.ID, &t.Type, &t.Amount,
 			&t.BalanceBefore, &t.BalanceAfter,
-			&t.RefID, &t.Note, &createdAt,
+			&t.RefID, &t.ParentTxID, &t.Note, &createdAt,
 		)
 		if err != nil {
 			return nil, err
@@ -361,12 +998,49 @@ func (d *DB) GetTransactions(playerID string, limit int) ([]Transaction, error)
 		t.CreatedAt = createdAt.UTC().Format(time.RFC3339)
 		txns = append(txns, t)
 	}
-	if txns == nil {
-		txns = []Transaction{} // never return null — always return an array
-	}
 	return txns, rows.Err()
 }
 
+// PlayerSummary aggregates a player's settled play. Amounts are dollar
+// strings like the rest of the API.
+type PlayerSummary struct {
+	Wagered  string // stakes of settled bets: bets, less cancelled and open ones
+	Returned string // everything paid back by payouts, stakes included
+	Net      string // Returned - Wagered
+	OpenBets string // stakes still riding, not counted above
+	Hands    int    // settled base bets; double-down and insurance legs ride on a hand
+	Wins     int
+	Pushes   int
+}
+
+// GetSummary computes PlayerSummary with SQL aggregates over the player's
+// transactions. Side bets are linked to their hand, so only base-bet payouts
+// count as hands; a paid insurance bet from before the link is left out too.
+func (d *DB) GetSummary(playerID string) (PlayerSummary, error) {
+	var s PlayerSummary
+	err := d.pool.QueryRow(`
+		WITH totals AS (
+			SELECT
+				COALESCE(SUM(amount) FILTER (WHERE type = 'bet'), 0)
+					- COALESCE(SUM(amount) FILTER (WHERE type = 'bet_cancelled'), 0)
+					- (SELECT COALESCE(SUM(amount), 0) FROM open_bets WHERE player_id = $1) AS wagered,
+				COALESCE(SUM(amount) FILTER (WHERE type LIKE 'payout\_%'), 0) AS returned,
+				COUNT(*) FILTER (WHERE type LIKE 'payout\_%' AND type <> 'payout_insurance' AND parent_transaction_id IS NULL) AS hands,
+				COUNT(*) FILTER (WHERE type IN ('payout_win', 'payout_blackjack') AND parent_transaction_id IS NULL) AS wins,
+				COUNT(*) FILTER (WHERE type = 'payout_push' AND parent_transaction_id IS NULL) AS pushes
+			FROM transactions
+			WHERE player_id = $1
+		)
+		SELECT wagered::numeric(15,2)::text, returned::numeric(15,2)::text,
+		       (returned - wagered)::numeric(15,2)::text,
+		       (SELECT COALESCE(SUM(amount), 0) FROM open_bets WHERE player_id = $1)::numeric(15,2)::text,
+		       hands, wins, pushes
+		FROM totals`,
+		playerID,
+	).Scan(&s.Wagered, &s.Returned, &s.Net, &s.OpenBets, &s.Hands, &s.Wins, &s.Pushes)
+	return s, err
+}
+
 // ── Dev reset ─────────────────────────────────────────────────────────────────
 
 // DevReset wipes all financial data and re-seeds the demo player.
@@ -379,6 +1053,9 @@ func (d *DB) DevReset() error {
 	if _, err := tx.Exec(`DELETE FROM open_bets`); err != nil {
 		return err
 	}
+	if _, err := tx.Exec(`DELETE FROM holds`); err != nil {
+		return err
+	}
 	if _, err := tx.Exec(`DELETE FROM transactions`); err != nil {
 		return err
 	}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testDB connects to the Postgres in TEST_DATABASE_URL and migrates it. The
+// tests create their own accounts, so any scratch database will do:
+//
+//	TEST_DATABASE_URL="host=localhost dbname=bank_test user=bank password=bank sslmode=disable" go test ./...
+//
+// Without it the DB tests are skipped.
+func testDB(t *testing.T) *DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	db := &DB{pool: pool}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate test db: %v", err)
+	}
+	return db
+}
+
+// testAccount creates an account with a fresh player ID and returns the ID.
+func testAccount(t *testing.T, db *DB, balance string) string {
+	t.Helper()
+	name := strings.NewReplacer("/", "-", " ", "-").Replace(t.Name())
+	playerID := fmt.Sprintf("test-%s-%d", name, time.Now().UnixNano())
+	if err := db.CreateAccount(playerID, balance); err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	return playerID
+}
+
+func assertBalance(t *testing.T, db *DB, playerID, want string) {
+	t.Helper()
+	got, found, err := db.GetBalance(playerID)
+	if err != nil || !found {
+		t.Fatalf("get balance: %v (found=%v)", err, found)
+	}
+	if got != want {
+		t.Errorf("balance = %s, want %s", got, want)
+	}
+}
+
+// ── Balance updates ──────────────────────────────────────────────────────────
+
+func TestPlaceBetStaleBalanceLoses(t *testing.T) {
+	db := testDB(t)
+	player := testAccount(t, db, "100.00")
+
+	// Every writer read 100.00; updateBalance must let exactly one through
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = db.PlaceBet(player, "100.00", "90.00", "10.00", "")
+		}(i)
+	}
+	wg.Wait()
+
+	won := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case errors.Is(err, errBalanceChanged):
+		default:
+			t.Errorf("place bet: %v", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d of %d stale writers succeeded, want 1", won, n)
+	}
+	assertBalance(t, db, player, "90.00")
+	if bets, err := db.GetOpenBets(player); err != nil || len(bets) != 1 {
+		t.Errorf("open bets = %v, %v; want exactly one", bets, err)
+	}
+}
+
+// ── Holds ────────────────────────────────────────────────────────────────────
+
+func TestHoldCommit(t *testing.T) {
+	db := testDB(t)
+	player := testAccount(t, db, "100.00")
+
+	hold, err := db.PlaceHold(player, "100.00", "75.00", "25.00", time.Minute)
+	if err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+	assertBalance(t, db, player, "75.00")
+
+	txID, err := db.CommitHold(hold.HoldID)
+	if err != nil {
+		t.Fatalf("commit hold: %v", err)
+	}
+	// The funds moved at hold time; commit only opens the bet
+	assertBalance(t, db, player, "75.00")
+	bet, err := db.GetOpenBet(txID)
+	if err != nil || bet == nil {
+		t.Fatalf("open bet after commit: %v (bet=%v)", err, bet)
+	}
+	if bet.PlayerID != player || bet.Amount != "25.00" {
+		t.Errorf("open bet = %+v, want %s for 25.00", *bet, player)
+	}
+	// The bet row carries its own transaction ID, as a PlaceBet row does
+	var refID, note string
+	err = db.pool.QueryRow(
+		`SELECT ref_id, note FROM transactions WHERE player_id=$1 AND type='bet'`, player,
+	).Scan(&refID, &note)
+	if err != nil || refID != txID || note != "hold "+hold.HoldID {
+		t.Errorf("bet row ref_id=%q note=%q (%v), want %s and hold %s", refID, note, err, txID, hold.HoldID)
+	}
+
+	if _, err := db.CommitHold(hold.HoldID); err != errHoldNotFound {
+		t.Errorf("second commit: err = %v, want errHoldNotFound", err)
+	}
+	if err := db.ReleaseHold(hold.HoldID, player, "75.00", "100.00", "25.00", "hold_release"); err != errHoldNotFound {
+		t.Errorf("release after commit: err = %v, want errHoldNotFound", err)
+	}
+}
+
+func TestHoldRelease(t *testing.T) {
+	db := testDB(t)
+	player := testAccount(t, db, "100.00")
+
+	hold, err := db.PlaceHold(player, "100.00", "60.00", "40.00", time.Minute)
+	if err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+	if err := db.ReleaseHold(hold.HoldID, player, "60.00", "100.00", "40.00", "hold_release"); err != nil {
+		t.Fatalf("release hold: %v", err)
+	}
+	assertBalance(t, db, player, "100.00")
+
+	if h, err := db.GetHold(hold.HoldID); err != nil || h != nil {
+		t.Errorf("hold after release = %v, %v; want gone", h, err)
+	}
+	if _, err := db.CommitHold(hold.HoldID); err != errHoldNotFound {
+		t.Errorf("commit after release: err = %v, want errHoldNotFound", err)
+	}
+}
+
+func TestHoldExpiry(t *testing.T) {
+	db := testDB(t)
+	player := testAccount(t, db, "100.00")
+
+	hold, err := db.PlaceHold(player, "100.00", "90.00", "10.00", -time.Second)
+	if err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+
+	// Past its TTL the hold can't become a bet, even before the sweep
+	if _, err := db.CommitHold(hold.HoldID); err != errHoldExpired {
+		t.Fatalf("commit expired hold: err = %v, want errHoldExpired", err)
+	}
+	assertBalance(t, db, player, "90.00")
+
+	expired, err := db.ExpiredHolds()
+	if err != nil {
+		t.Fatalf("expired holds: %v", err)
+	}
+	found := false
+	for _, h := range expired {
+		found = found || h.HoldID == hold.HoldID
+	}
+	if !found {
+		t.Fatalf("expired hold %s not listed for the sweeper", hold.HoldID)
+	}
+
+	// What the sweeper does once CALC-CREDIT has computed the new balance
+	if err := db.ReleaseHold(hold.HoldID, player, "90.00", "100.00", "10.00", "hold_expired"); err != nil {
+		t.Fatalf("sweep release: %v", err)
+	}
+	assertBalance(t, db, player, "100.00")
+	if _, err := db.CommitHold(hold.HoldID); err != errHoldNotFound {
+		t.Errorf("commit after sweep: err = %v, want errHoldNotFound", err)
+	}
+}
+
+// ── Summary ──────────────────────────────────────────────────────────────────
+
+func TestSummaryCountsHandsNotSideBets(t *testing.T) {
+	db := testDB(t)
+	player := testAccount(t, db, "1000.00")
+
+	mustBet := func(before, after, amount, parent string) string {
+		t.Helper()
+		txID, err := db.PlaceBet(player, before, after, amount, parent)
+		if err != nil {
+			t.Fatalf("place bet %s: %v", amount, err)
+		}
+		return txID
+	}
+	mustSettle := func(txID, before, after, returned, payoutType string) {
+		t.Helper()
+		if err := db.SettlePayout(txID, player, before, after, returned, payoutType); err != nil {
+			t.Fatalf("settle %s: %v", payoutType, err)
+		}
+	}
+
+	// One hand: insurance on it pays, the hand itself loses
+	base := mustBet("1000.00", "900.00", "100.00", "")
+	insurance := mustBet("900.00", "850.00", "50.00", base)
+	mustSettle(insurance, "850.00", "1000.00", "150.00", "payout_insurance")
+	mustSettle(base, "1000.00", "1000.00", "0.00", "payout_loss")
+	// Insurance placed before side bets were linked
+	legacy := mustBet("1000.00", "990.00", "10.00", "")
+	mustSettle(legacy, "990.00", "1020.00", "30.00", "payout_insurance")
+
+	s, err := db.GetSummary(player)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	want := PlayerSummary{Wagered: "160.00", Returned: "180.00", Net: "20.00", OpenBets: "0.00", Hands: 1}
+	if s != want {
+		t.Errorf("summary = %+v, want %+v", s, want)
+	}
+}
+
+// ── Statements ───────────────────────────────────────────────────────────────
+
+func TestTransactionsAsOfTiesOrderByID(t *testing.T) {
+	db := testDB(t)
+	player := testAccount(t, db, "100.00")
+
+	// Rows committed in one instant share created_at; the statement must
+	// still pick and order them the same way on every read.
+	at := time.Now().UTC().Add(-time.Minute).Truncate(time.Microsecond)
+	var ids []string
+	for i := 0; i < 4; i++ {
+		var id string
+		err := db.pool.QueryRow(
+			`INSERT INTO transactions(player_id, type, amount, balance_before, balance_after, created_at)
+			 VALUES($1, 'deposit', 1, 100, 100, $2) RETURNING id::text`, player, at,
+		).Scan(&id)
+		if err != nil {
+			t.Fatalf("insert transaction: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	for run := 0; run < 5; run++ {
+		txns, err := db.GetTransactionsAsOf(player, time.Now().UTC(), TxRange{}, 3)
+		if err != nil {
+			t.Fatalf("transactions as of: %v", err)
+		}
+		if len(txns) != 3 {
+			t.Fatalf("got %d rows, want 3", len(txns))
+		}
+		for i, tx := range txns {
+			if tx.ID != ids[i] {
+				t.Fatalf("run %d row %d = %s, want %s (id DESC)", run, i, tx.ID, ids[i])
+			}
+		}
+	}
+}
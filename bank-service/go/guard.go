@@ -0,0 +1,61 @@
+package main
+
+import "strings"
+
+// ── Settlement guard ─────────────────────────────────────────────────────────
+// game-state decides hand outcomes and the bank trusts the result string.
+// As defense in depth, a payout may carry the final hand totals; the bank
+// re-derives the outcome from them and compares. Mismatches are logged and
+// written to payout_audit. With PAYOUT_STRICT=true they are rejected before
+// any money moves — a circuit breaker against a settlement bug.
+
+// HandEvidence is the final state of the hand a payout settles.
+type HandEvidence struct {
+	PlayerValue int `json:"playerValue"`
+	PlayerCards int `json:"playerCards"`
+	DealerValue int `json:"dealerValue"`
+	DealerCards int `json:"dealerCards"`
+}
+
+// payoutStrict rejects payouts whose result contradicts their evidence.
+// Set at startup from PAYOUT_STRICT.
+var payoutStrict = false
+
+// ExpectedResult derives the CALC-PAYOUT result the hand totals imply.
+func (e HandEvidence) ExpectedResult() string {
+	playerNatural := e.PlayerValue == 21 && e.PlayerCards == 2
+	dealerNatural := e.DealerValue == 21 && e.DealerCards == 2
+	switch {
+	case e.PlayerValue > 21:
+		return "LOSS"
+	case playerNatural && dealerNatural:
+		return "PUSH"
+	case playerNatural:
+		return "BLACKJACK"
+	case dealerNatural:
+		return "LOSS"
+	case e.DealerValue > 21 || e.PlayerValue > e.DealerValue:
+		return "WIN"
+	case e.PlayerValue == e.DealerValue:
+		return "PUSH"
+	default:
+		return "LOSS"
+	}
+}
+
+// CheckResult reports whether a claimed result is consistent with the hand.
+// Surrender can't be derived from totals, so it only has to be plausible:
+// the player gave up a live two-card hand. Insurance wins only against a
+// dealer natural.
+func (e HandEvidence) CheckResult(claimed string) (expected string, ok bool) {
+	claimed = strings.ToUpper(strings.TrimSpace(claimed))
+	switch claimed {
+	case "SURRENDER":
+		return "SURRENDER", e.PlayerCards == 2 && e.PlayerValue <= 21
+	case "INSURANCE":
+		// Insurance is a side bet on the dealer alone
+		return "INSURANCE", e.DealerValue == 21 && e.DealerCards == 2
+	}
+	expected = e.ExpectedResult()
+	return expected, claimed == expected
+}
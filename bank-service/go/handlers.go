@@ -1,14 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -40,29 +50,211 @@ func queryParam(q url.Values, key string) string {
 }
 
 // publishBalance publishes a balance update to Redis.
-// Fire-and-forget: errors are logged but not returned.
+// Fire-and-forget: errors are logged but not returned. While Redis is down
+// the publish is skipped rather than left to hit a dial timeout in the
+// request path; a failed publish marks Redis down until watchRedis sees it
+// answer again.
 func publishBalance(rdb *redis.Client, playerID, balance string) {
-	if rdb == nil {
+	if rdb == nil || !redisUp.Load() {
 		return
 	}
 	payload := fmt.Sprintf(`{"playerId":"%s","balance":%s}`, playerID, balance)
 	if err := rdb.Publish(context.Background(), "swarm:balance", payload).Err(); err != nil {
-		log.Printf("[bank] Redis publish failed (non-fatal): %v", err)
+		redisUp.Store(false)
+		logf("warn", logFields{"playerId": playerID}, "[bank] Redis publish failed (non-fatal): %v", err)
 	}
 }
 
+// redisUp is true while Redis is answering. Reported on /health and /ready.
+var redisUp atomic.Bool
+
+func redisState() string {
+	if redisUp.Load() {
+		return "connected"
+	}
+	return "disconnected"
+}
+
+// redisMaxBackoff caps the delay between pings while Redis is down.
+const redisMaxBackoff = 30 * time.Second
+
+// watchRedis pings Redis every interval for the life of the process. The
+// client's pool re-dials on each ping, so a late start or a Redis restart is
+// picked up without a bank restart; while it's down, pings back off up to
+// redisMaxBackoff.
+func watchRedis(rdb *redis.Client, interval time.Duration) {
+	delay := interval
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := rdb.Ping(ctx).Err()
+		cancel()
+		wasUp := redisUp.Swap(err == nil)
+		switch {
+		case err == nil:
+			if !wasUp {
+				log.Printf("[bank] Redis connected")
+			}
+			delay = interval
+		case wasUp || attempt == 1:
+			log.Printf("[bank] Redis unavailable, retrying (balance updates paused): %v", err)
+			fallthrough
+		default:
+			delay = min(delay*2, redisMaxBackoff)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// rejectIfFrozen writes a 403 and returns true when the player is self-excluded.
+func rejectIfFrozen(w http.ResponseWriter, db *DB, playerID string) bool {
+	until, err := db.FrozenUntil(playerID)
+	if err != nil {
+		logf("error", logFields{"playerId": playerID}, "[bank] frozen check: %v", err)
+		writeError(w, 500, "db_error", "database error")
+		return true
+	}
+	if until == nil {
+		return false
+	}
+	writeJSON(w, 403, map[string]any{
+		"error":         "self_excluded",
+		"excludedUntil": until.Format(time.RFC3339),
+	})
+	return true
+}
+
+// rejectIfConflict writes a 409 and returns true when err is a lost balance
+// race (see updateBalance). Nothing was written, so the caller can retry.
+func rejectIfConflict(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, errBalanceChanged) {
+		return false
+	}
+	writeError(w, 409, "balance_conflict", "balance changed during the request — retry")
+	return true
+}
+
+// writeCOBOLError reports a failed COBOL call: 503 cobol_timeout when it ran
+// out of time (safe to retry — nothing was written), 500 otherwise.
+func writeCOBOLError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, errCOBOLTimeout) {
+		writeError(w, 503, "cobol_timeout", "COBOL engine busy or hung — retry")
+		return
+	}
+	if errors.Is(err, errBalanceCap) {
+		writeError(w, 409, "balance_cap_exceeded",
+			"credit would take the balance over the maximum of "+CentsToDollars(maxBalanceCents))
+		return
+	}
+	writeError(w, 500, "cobol_error", message)
+}
+
 // ── Health ────────────────────────────────────────────────────────────────────
 
-func healthHandler(db *DB) http.HandlerFunc {
+// healthHandler is the liveness check: it answers as soon as the listener is
+// up and never touches the database. Readiness is /ready.
+func healthHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			writeError(w, 405, "method_not_allowed", "GET only")
 			return
 		}
-		writeJSON(w, 200, map[string]string{
+		writeJSON(w, 200, map[string]any{
 			"status":   "healthy",
 			"service":  "bank-service",
 			"language": "Go + COBOL (GnuCOBOL)",
+			"redis":    redisState(),
+			"limits": map[string]any{
+				"maxBalance":         maxBalanceLabel(),
+				"maxStartingBalance": MaxStartingBalance,
+			},
+			"cobol": map[string]any{
+				"timeoutMs":     cobolTimeout.Milliseconds(),
+				"maxConcurrent": cap(cobolSlots),
+				"inFlight":      len(cobolSlots),
+				"timeouts":      cobolTimeouts.Load(),
+			},
+			"payoutCache": map[string]any{
+				"entries": payoutCache.size(),
+				"hits":    payoutCache.hits.Load(),
+				"misses":  payoutCache.misses.Load(),
+			},
+		})
+	}
+}
+
+// maxBalanceLabel is the balance cap in dollars, or "none" when disabled.
+func maxBalanceLabel() string {
+	if maxBalanceCents <= 0 {
+		return "none"
+	}
+	return CentsToDollars(maxBalanceCents)
+}
+
+// readyDB is set once the database is connected, migrated and seeded.
+// The listener starts before that, so /health answers while waitReady is
+// still retrying and /ready holds traffic off until this is set.
+var readyDB atomic.Pointer[DB]
+
+// readyHandler reports whether the bank can serve traffic: the database
+// must be connected and answering pings. Redis only carries balance
+// notifications, so its state is reported but doesn't gate readiness.
+func readyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		dbStatus := "connecting"
+		if db := readyDB.Load(); db != nil {
+			dbStatus = "connected"
+			if err := db.Ping(ctx); err != nil {
+				dbStatus = "disconnected"
+			}
+		}
+
+		status, code := "ready", 200
+		if dbStatus != "connected" {
+			status, code = "not_ready", 503
+		}
+		writeJSON(w, code, map[string]any{
+			"status":   status,
+			"service":  "bank-service",
+			"database": dbStatus,
+			"redis":    redisState(),
+		})
+	}
+}
+
+// ── Rules ─────────────────────────────────────────────────────────────────────
+
+// rulesHandler documents the active payout schedule.
+func rulesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		push := "return"
+		if !payoutSchedule.PushReturnsStake {
+			push = "lose"
+		}
+		writeJSON(w, 200, map[string]any{
+			"payouts": map[string]string{
+				"win":       payoutSchedule.Win.String(),
+				"blackjack": payoutSchedule.Blackjack.String(),
+				"push":      push,
+				"surrender": payoutSchedule.Surrender.String(),
+				"insurance": payoutSchedule.Insurance.String(),
+			},
+			"results": payoutResults,
 		})
 	}
 }
@@ -95,10 +287,18 @@ func accountHandler(db *DB) http.HandlerFunc {
 		starting := req.StartingBalance
 		if starting == "" {
 			starting = StartingBalance
+		} else {
+			cents, err := DollarsToCents(starting)
+			if err != nil || !validStartingBalance(cents) {
+				writeError(w, 400, "invalid_amount",
+					"startingBalance must be positive and at most "+MaxStartingBalance)
+				return
+			}
+			starting = CentsToDollars(cents)
 		}
 		exists, err := db.AccountExists(req.PlayerID)
 		if err != nil {
-			log.Printf("[bank] account exists check: %v", err)
+			logf("error", logFields{"requestId": requestID(r), "playerId": req.PlayerID}, "[bank] account exists check: %v", err)
 			writeError(w, 500, "db_error", "database error")
 			return
 		}
@@ -107,7 +307,7 @@ func accountHandler(db *DB) http.HandlerFunc {
 			return
 		}
 		if err := db.CreateAccount(req.PlayerID, starting); err != nil {
-			log.Printf("[bank] create account: %v", err)
+			logf("error", logFields{"requestId": requestID(r), "playerId": req.PlayerID}, "[bank] create account: %v", err)
 			writeError(w, 500, "db_error", "database error")
 			return
 		}
@@ -118,6 +318,12 @@ func accountHandler(db *DB) http.HandlerFunc {
 	}
 }
 
+// validStartingBalance reports whether cents is a usable opening balance.
+func validStartingBalance(cents int64) bool {
+	max, err := DollarsToCents(MaxStartingBalance)
+	return err == nil && cents > 0 && cents <= max
+}
+
 // ── Balance ───────────────────────────────────────────────────────────────────
 
 func balanceHandler(db *DB) http.HandlerFunc {
@@ -138,7 +344,7 @@ func balanceHandler(db *DB) http.HandlerFunc {
 		}
 		balance, found, err := db.GetBalance(playerID)
 		if err != nil {
-			log.Printf("[bank] get balance: %v", err)
+			logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] get balance: %v", err)
 			writeError(w, 500, "db_error", "database error")
 			return
 		}
@@ -153,8 +359,76 @@ func balanceHandler(db *DB) http.HandlerFunc {
 	}
 }
 
+// ── Summary ───────────────────────────────────────────────────────────────────
+
+// summaryHandler reports whether a player is up or down overall.
+//
+//	GET /summary?playerId=
+func summaryHandler(db *DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		playerID := queryParam(r.URL.Query(), "playerId")
+		if playerID == "" {
+			writeError(w, 400, "missing_param", "playerId required")
+			return
+		}
+		if _, found, err := db.GetBalance(playerID); err != nil || !found {
+			if err != nil {
+				logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] summary get balance: %v", err)
+				writeError(w, 500, "db_error", "database error")
+				return
+			}
+			writeError(w, 404, "not_found", "player account not found")
+			return
+		}
+		s, err := db.GetSummary(playerID)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] get summary: %v", err)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		winRate := 0.0
+		if s.Hands > 0 {
+			winRate = math.Round(float64(s.Wins)/float64(s.Hands)*10000) / 10000
+		}
+		writeJSON(w, 200, map[string]any{
+			"playerId":      playerID,
+			"totalWagered":  s.Wagered,
+			"totalReturned": s.Returned,
+			"net":           s.Net,
+			"openBets":      s.OpenBets,
+			"hands":         s.Hands,
+			"wins":          s.Wins,
+			"pushes":        s.Pushes,
+			"losses":        s.Hands - s.Wins - s.Pushes,
+			"winRate":       winRate,
+		})
+	}
+}
+
 // ── Transactions ──────────────────────────────────────────────────────────────
 
+// Transaction history page sizes.
+const (
+	defaultTxPageSize = 50
+	maxTxPageSize     = 200
+)
+
+// transactionsHandler returns a player's history newest first, one page at
+// a time.
+//
+//	GET /transactions?playerId=&limit=&before=
+//
+// before is an RFC3339 timestamp or the nextCursor of the previous page;
+// nextCursor is present only when older rows remain.
 func transactionsHandler(db *DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setCORSHeaders(w)
@@ -171,16 +445,218 @@ func transactionsHandler(db *DB) http.HandlerFunc {
 			writeError(w, 400, "missing_param", "playerId required")
 			return
 		}
-		limit := 50
-		txns, err := db.GetTransactions(playerID, limit)
+		limit := defaultTxPageSize
+		if v := queryParam(r.URL.Query(), "limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				writeError(w, 400, "invalid_param", "limit must be a positive integer")
+				return
+			}
+			limit = min(n, maxTxPageSize)
+		}
+		rng, err := parseTxRange(r.URL.Query())
+		if err != nil {
+			writeError(w, 400, "invalid_param", err.Error())
+			return
+		}
+		var before *TxCursor
+		if v := queryParam(r.URL.Query(), "before"); v != "" {
+			c, err := ParseTxCursor(v)
+			if err != nil {
+				writeError(w, 400, "invalid_param", "before must be an RFC3339 timestamp or a nextCursor")
+				return
+			}
+			before = &c
+		}
+		types, ignored := parseTxTypes(queryParam(r.URL.Query(), "type"))
+		resp := map[string]any{
+			"playerId":     playerID,
+			"transactions": []Transaction{},
+		}
+		if len(ignored) > 0 {
+			resp["ignoredTypes"] = ignored
+		}
+		// A filter made up only of unknown types matches nothing
+		if len(types) == 0 && len(ignored) > 0 {
+			writeJSON(w, 200, resp)
+			return
+		}
+		txns, next, err := db.GetTransactions(playerID, rng, types, before, limit)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] get transactions: %v", err)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		resp["transactions"] = txns
+		if next != nil {
+			resp["nextCursor"] = next.Encode()
+		}
+		writeJSON(w, 200, resp)
+	}
+}
+
+// parseTxTypes splits the comma-separated type filter into known
+// transaction types and the unknown ones, which are reported back rather
+// than failing the request.
+func parseTxTypes(v string) (types, ignored []string) {
+	seen := map[string]bool{}
+	for _, t := range strings.Split(v, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		if TxTypes[t] {
+			types = append(types, t)
+		} else {
+			ignored = append(ignored, t)
+		}
+	}
+	return types, ignored
+}
+
+// parseTxRange reads the optional from/to RFC3339 bounds shared by history,
+// export and statement verification.
+func parseTxRange(q url.Values) (TxRange, error) {
+	var rng TxRange
+	var err error
+	if v := queryParam(q, "from"); v != "" {
+		if rng.From, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return rng, fmt.Errorf("from must be an RFC3339 timestamp")
+		}
+	}
+	if v := queryParam(q, "to"); v != "" {
+		if rng.To, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return rng, fmt.Errorf("to must be an RFC3339 timestamp")
+		}
+	}
+	if !rng.From.IsZero() && !rng.To.IsZero() && rng.From.After(rng.To) {
+		return rng, fmt.Errorf("from must not be after to")
+	}
+	return rng, nil
+}
+
+// ── Bet cancel ────────────────────────────────────────────────────────────────
+
+// betCancelHandler reverses a bet whose hand never played (e.g. the betting
+// window closed while the bet was in flight). Unlike a push payout, the log
+// shows a bet_cancelled entry rather than a settled hand.
+//
+//	POST /bet/cancel  {"transactionId"}
+func betCancelHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, 405, "method_not_allowed", "POST only")
+			return
+		}
+		var req struct {
+			TransactionID string `json:"transactionId"`
+		}
+		if err := parseBody(r, &req); err != nil {
+			writeError(w, 400, "bad_request", "invalid JSON")
+			return
+		}
+		if req.TransactionID == "" {
+			writeError(w, 400, "missing_field", "transactionId required")
+			return
+		}
+
+		bet, err := db.GetOpenBet(req.TransactionID)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] cancel get open bet: %v", err)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		if bet == nil {
+			writeError(w, 404, "not_found", "transaction not found or already settled")
+			return
+		}
+
+		balanceStr, found, err := db.GetBalance(bet.PlayerID)
+		if err != nil || !found {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] cancel get balance: %v (found=%v)", err, found)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		balanceCents, err := DollarsToCents(balanceStr)
+		if err != nil {
+			writeError(w, 500, "internal_error", "balance format error")
+			return
+		}
+		betCents, err := DollarsToCents(bet.Amount)
+		if err != nil {
+			writeError(w, 500, "internal_error", "bet amount format error")
+			return
+		}
+
+		// COBOL: credit the stake back
+		newBalCents, err := CalcCredit(balanceCents, betCents)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] COBOL calc-credit: %v", err)
+			writeCOBOLError(w, err, "credit calculation failed")
+			return
+		}
+		newBalStr := CentsToDollars(newBalCents)
+
+		if err := db.CancelBet(req.TransactionID, bet.PlayerID, balanceStr, newBalStr, bet.Amount); err != nil {
+			if err == errBetNotFound {
+				writeError(w, 404, "not_found", "transaction not found or already settled")
+				return
+			}
+			if rejectIfConflict(w, err) {
+				return
+			}
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] cancel bet: %v", err)
+			writeError(w, 500, "db_error", "bet cancellation failed")
+			return
+		}
+
+		logf("info", logFields{"requestId": requestID(r), "playerId": bet.PlayerID, "txId": req.TransactionID},
+			"[bank] bet cancelled: returned=%s newBalance=%s", bet.Amount, newBalStr)
+		publishBalance(rdb, bet.PlayerID, newBalStr)
+
+		writeJSON(w, 200, map[string]string{
+			"transactionId": req.TransactionID,
+			"playerId":      bet.PlayerID,
+			"returned":      bet.Amount,
+			"newBalance":    newBalStr,
+		})
+	}
+}
+
+// ── Open bets ─────────────────────────────────────────────────────────────────
+
+// openBetsHandler lists a player's unsettled bets so a caller can find ones
+// orphaned by a crash mid-hand. The bank never times bets out on its own:
+// only game-state knows whether a hand is still live, so game-state owns the
+// orphan timeout and settles stale bets through the normal /payout flow.
+//
+//	GET /open-bets?playerId=
+func openBetsHandler(db *DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		playerID := queryParam(r.URL.Query(), "playerId")
+		if playerID == "" {
+			writeError(w, 400, "missing_param", "playerId required")
+			return
+		}
+		bets, err := db.GetOpenBets(playerID)
 		if err != nil {
-			log.Printf("[bank] get transactions: %v", err)
+			logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] get open bets: %v", err)
 			writeError(w, 500, "db_error", "database error")
 			return
 		}
 		writeJSON(w, 200, map[string]any{
-			"playerId":     playerID,
-			"transactions": txns,
+			"playerId": playerID,
+			"openBets": bets,
 		})
 	}
 }
@@ -201,6 +677,8 @@ func betHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 		var req struct {
 			PlayerID string `json:"playerId"`
 			Amount   string `json:"amount"`
+			// ParentTransactionID links a double-down or insurance bet to the open bet it rides on
+			ParentTransactionID string `json:"parentTransactionId"`
 		}
 		if err := parseBody(r, &req); err != nil {
 			writeError(w, 400, "bad_request", "invalid JSON")
@@ -217,9 +695,22 @@ func betHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 
+		if req.ParentTransactionID != "" {
+			parent, err := db.GetOpenBet(req.ParentTransactionID)
+			if err != nil {
+				logf("error", logFields{"requestId": requestID(r)}, "[bank] bet get parent: %v", err)
+				writeError(w, 500, "db_error", "database error")
+				return
+			}
+			if parent == nil || parent.PlayerID != req.PlayerID {
+				writeError(w, 400, "invalid_parent", "parentTransactionId must be an open bet of the same player")
+				return
+			}
+		}
+
 		balanceStr, found, err := db.GetBalance(req.PlayerID)
 		if err != nil {
-			log.Printf("[bank] bet get balance: %v", err)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] bet get balance: %v", err)
 			writeError(w, 500, "db_error", "database error")
 			return
 		}
@@ -227,10 +718,13 @@ func betHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			writeError(w, 404, "not_found", "player account not found")
 			return
 		}
+		if rejectIfFrozen(w, db, req.PlayerID) {
+			return
+		}
 
 		balanceCents, err := DollarsToCents(balanceStr)
 		if err != nil {
-			log.Printf("[bank] bet parse balance %q: %v", balanceStr, err)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] bet parse balance %q: %v", balanceStr, err)
 			writeError(w, 500, "internal_error", "balance format error")
 			return
 		}
@@ -238,8 +732,8 @@ func betHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 		// COBOL: validate sufficient funds
 		debit, err := ValidateDebit(balanceCents, betCents)
 		if err != nil {
-			log.Printf("[bank] COBOL validate-debit: %v", err)
-			writeError(w, 500, "cobol_error", "bet validation failed")
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] COBOL validate-debit: %v", err)
+			writeCOBOLError(w, err, "bet validation failed")
 			return
 		}
 
@@ -248,7 +742,7 @@ func betHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			if req.PlayerID == DemoPlayerID {
 				newBalStr, err := db.ReplenishDemoPlayer()
 				if err != nil {
-					log.Printf("[bank] demo replenish: %v", err)
+					logf("error", logFields{"requestId": requestID(r)}, "[bank] demo replenish: %v", err)
 					writeError(w, 500, "db_error", "replenish failed")
 					return
 				}
@@ -273,22 +767,31 @@ func betHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 
 		newBalStr := CentsToDollars(debit.NewBalanceCents)
 
-		txID, err := db.PlaceBet(req.PlayerID, balanceStr, newBalStr, req.Amount)
+		txID, err := db.PlaceBet(req.PlayerID, balanceStr, newBalStr, req.Amount, req.ParentTransactionID)
+		if rejectIfConflict(w, err) {
+			return
+		}
 		if err != nil {
-			log.Printf("[bank] place bet: %v", err)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] place bet: %v", err)
 			writeError(w, 500, "db_error", "bet placement failed")
 			return
 		}
 
-		log.Printf("[bank] bet: player=%s amount=%s txId=%s newBalance=%s",
-			req.PlayerID, req.Amount, txID, newBalStr)
+		logf("info", logFields{"requestId": requestID(r), "playerId": req.PlayerID, "txId": txID},
+			"[bank] bet: amount=%s parent=%s newBalance=%s", req.Amount, req.ParentTransactionID, newBalStr)
+		// Same payload as payouts — the chip count drops as soon as the bet lands
+		publishBalance(rdb, req.PlayerID, newBalStr)
 
-		writeJSON(w, 200, map[string]string{
+		resp := map[string]string{
 			"transactionId": txID,
 			"playerId":      req.PlayerID,
 			"amount":        req.Amount,
 			"newBalance":    newBalStr,
-		})
+		}
+		if req.ParentTransactionID != "" {
+			resp["parentTransactionId"] = req.ParentTransactionID
+		}
+		writeJSON(w, 200, resp)
 	}
 }
 
@@ -306,8 +809,9 @@ func payoutHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 		var req struct {
-			TransactionID string `json:"transactionId"`
-			Result        string `json:"result"`
+			TransactionID string        `json:"transactionId"`
+			Result        string        `json:"result"`
+			Hand          *HandEvidence `json:"hand,omitempty"`
 		}
 		if err := parseBody(r, &req); err != nil {
 			writeError(w, 400, "bad_request", "invalid JSON")
@@ -317,10 +821,15 @@ func payoutHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			writeError(w, 400, "missing_field", "transactionId and result required")
 			return
 		}
+		if !IsPayoutResult(req.Result) {
+			writeError(w, 400, "invalid_result",
+				"result must be one of: "+strings.Join(payoutResults, ", "))
+			return
+		}
 
 		bet, err := db.GetOpenBet(req.TransactionID)
 		if err != nil {
-			log.Printf("[bank] payout get open bet: %v", err)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] payout get open bet: %v", err)
 			writeError(w, 500, "db_error", "database error")
 			return
 		}
@@ -329,24 +838,48 @@ func payoutHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 
+		// Settlement guard: cross-check the claimed result against the hand
+		if req.Hand != nil {
+			if expected, ok := req.Hand.CheckResult(req.Result); !ok {
+				logf("warn", logFields{"requestId": requestID(r), "playerId": bet.PlayerID, "txId": req.TransactionID},
+					"[bank] payout mismatch: claimed=%s expected=%s hand=%+v strict=%v", req.Result, expected, *req.Hand, payoutStrict)
+				if err := db.RecordPayoutMismatch(req.TransactionID, bet.PlayerID, req.Result, expected, *req.Hand, payoutStrict); err != nil {
+					logf("error", logFields{"requestId": requestID(r)}, "[bank] %v", err)
+				}
+				if payoutStrict {
+					writeJSON(w, 409, map[string]string{
+						"error":    "result_mismatch",
+						"message":  "claimed result is inconsistent with the hand",
+						"claimed":  req.Result,
+						"expected": expected,
+					})
+					return
+				}
+			}
+		}
+
 		betCents, err := DollarsToCents(bet.Amount)
 		if err != nil {
-			log.Printf("[bank] payout parse bet amount: %v", err)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] payout parse bet amount: %v", err)
 			writeError(w, 500, "internal_error", "bet amount format error")
 			return
 		}
 
 		// COBOL: calculate payout amount
 		payout, err := CalcPayout(betCents, req.Result)
+		if errors.Is(err, errCOBOLTimeout) {
+			writeCOBOLError(w, err, "")
+			return
+		}
 		if err != nil {
-			log.Printf("[bank] COBOL calc-payout: %v", err)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] COBOL calc-payout: %v", err)
 			writeError(w, 400, "invalid_result", err.Error())
 			return
 		}
 
 		balanceStr, found, err := db.GetBalance(bet.PlayerID)
 		if err != nil || !found {
-			log.Printf("[bank] payout get balance: %v (found=%v)", err, found)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] payout get balance: %v (found=%v)", err, found)
 			writeError(w, 500, "db_error", "database error")
 			return
 		}
@@ -357,11 +890,23 @@ func payoutHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 
-		// COBOL: credit payout to balance
-		newBalCents, err := CalcCredit(balanceCents, payout.ReturnedCents)
+		// COBOL: credit payout to balance. The bet is already lost or won at
+		// the table, so a payout over the balance cap is clamped, not refused;
+		// the response says how much was withheld.
+		var withheldCents int64
+		newBalCents, err := CalcCappedCredit(balanceCents, payout.ReturnedCents)
+		if errors.Is(err, errBalanceCap) {
+			withheldCents = min(newBalCents-maxBalanceCents, payout.ReturnedCents)
+			logf("warn", logFields{"requestId": requestID(r), "playerId": bet.PlayerID, "txId": req.TransactionID},
+				"[bank] payout capped: returned=%s withheld=%s (max balance %s)",
+				CentsToDollars(payout.ReturnedCents), CentsToDollars(withheldCents), CentsToDollars(maxBalanceCents))
+			payout.ReturnedCents -= withheldCents
+			newBalCents -= withheldCents
+			err = nil
+		}
 		if err != nil {
-			log.Printf("[bank] COBOL calc-credit: %v", err)
-			writeError(w, 500, "cobol_error", "credit calculation failed")
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] COBOL calc-credit: %v", err)
+			writeCOBOLError(w, err, "credit calculation failed")
 			return
 		}
 
@@ -373,31 +918,50 @@ func payoutHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			balanceStr, newBalStr,
 			returnedStr, payout.PayoutType,
 		); err != nil {
-			log.Printf("[bank] settle payout: %v", err)
+			if err == errBetNotFound {
+				writeError(w, 404, "not_found", "transaction not found or already settled")
+				return
+			}
+			if rejectIfConflict(w, err) {
+				return
+			}
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] settle payout: %v", err)
 			writeError(w, 500, "db_error", "payout settlement failed")
 			return
 		}
 
-		log.Printf("[bank] payout: player=%s txId=%s result=%s returned=%s newBalance=%s",
-			bet.PlayerID, req.TransactionID, req.Result, returnedStr, newBalStr)
+		logf("info", logFields{"requestId": requestID(r), "playerId": bet.PlayerID, "txId": req.TransactionID},
+			"[bank] payout: result=%s returned=%s newBalance=%s", req.Result, returnedStr, newBalStr)
 
 		// Publish balance update to Redis for real-time UI
 		publishBalance(rdb, bet.PlayerID, newBalStr)
 
-		writeJSON(w, 200, map[string]string{
+		resp := map[string]string{
 			"transactionId": req.TransactionID,
 			"playerId":      bet.PlayerID,
 			"result":        req.Result,
 			"betAmount":     bet.Amount,
 			"returned":      returnedStr,
+			"payoutType":    payout.PayoutType,
 			"newBalance":    newBalStr,
-		})
+		}
+		if withheldCents > 0 {
+			resp["code"] = "balance_cap_exceeded"
+			resp["withheld"] = CentsToDollars(withheldCents)
+			resp["maxBalance"] = CentsToDollars(maxBalanceCents)
+		}
+		writeJSON(w, 200, resp)
 	}
 }
 
-// ── Deposit ───────────────────────────────────────────────────────────────────
+// ── Hold (two-phase bet) ──────────────────────────────────────────────────────
 
-func depositHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
+// holdTTL is how long a hold reserves funds before the sweeper returns them.
+// Set at startup from HOLD_TTL.
+var holdTTL = 5 * time.Minute
+
+// holdHandler reserves funds without opening a bet: POST /hold.
+func holdHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setCORSHeaders(w)
 		if r.Method == http.MethodOptions {
@@ -411,7 +975,429 @@ func depositHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 		var req struct {
 			PlayerID string `json:"playerId"`
 			Amount   string `json:"amount"`
-			Note     string `json:"note"`
+		}
+		if err := parseBody(r, &req); err != nil {
+			writeError(w, 400, "bad_request", "invalid JSON")
+			return
+		}
+		if req.PlayerID == "" || req.Amount == "" {
+			writeError(w, 400, "missing_field", "playerId and amount required")
+			return
+		}
+		holdCents, err := DollarsToCents(req.Amount)
+		if err != nil || holdCents <= 0 {
+			writeError(w, 400, "invalid_amount", "amount must be a positive decimal")
+			return
+		}
+
+		balanceStr, found, err := db.GetBalance(req.PlayerID)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] hold get balance: %v", err)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		if !found {
+			writeError(w, 404, "not_found", "player account not found")
+			return
+		}
+		if rejectIfFrozen(w, db, req.PlayerID) {
+			return
+		}
+		balanceCents, _ := DollarsToCents(balanceStr)
+
+		// COBOL: validate sufficient funds
+		debit, err := ValidateDebit(balanceCents, holdCents)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] COBOL validate-debit: %v", err)
+			writeCOBOLError(w, err, "hold validation failed")
+			return
+		}
+		if debit.Status == "INSUFFICIENT" {
+			writeJSON(w, 409, map[string]any{
+				"error":     "insufficient_funds",
+				"balance":   balanceStr,
+				"requested": req.Amount,
+			})
+			return
+		}
+
+		amount := CentsToDollars(holdCents)
+		newBalStr := CentsToDollars(debit.NewBalanceCents)
+		hold, err := db.PlaceHold(req.PlayerID, balanceStr, newBalStr, amount, holdTTL)
+		if rejectIfConflict(w, err) {
+			return
+		}
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] place hold: %v", err)
+			writeError(w, 500, "db_error", "hold placement failed")
+			return
+		}
+
+		logf("info", logFields{"requestId": requestID(r), "playerId": req.PlayerID},
+			"[bank] hold: amount=%s holdId=%s expires=%s", amount, hold.HoldID, hold.ExpiresAt.Format(time.RFC3339))
+		publishBalance(rdb, req.PlayerID, newBalStr)
+
+		writeJSON(w, 201, map[string]string{
+			"holdId":     hold.HoldID,
+			"playerId":   req.PlayerID,
+			"amount":     amount,
+			"newBalance": newBalStr,
+			"expiresAt":  hold.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+}
+
+// holdActionHandler handles POST /hold/{id}/commit and POST /hold/{id}/release.
+func holdActionHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, 405, "method_not_allowed", "POST only")
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/hold/"), "/")
+		if len(parts) != 2 || parts[0] == "" {
+			writeError(w, 404, "not_found", "unknown hold action")
+			return
+		}
+		holdID, action := parts[0], parts[1]
+
+		switch action {
+		case "commit":
+			txID, err := db.CommitHold(holdID)
+			if err == errHoldNotFound {
+				writeError(w, 404, "not_found", "hold not found, released, or expired")
+				return
+			}
+			if err == errHoldExpired {
+				writeError(w, 409, "hold_expired", "hold is past its TTL — funds are being returned")
+				return
+			}
+			if err != nil {
+				logf("error", logFields{"requestId": requestID(r), "holdId": holdID}, "[bank] commit hold: %v", err)
+				writeError(w, 500, "db_error", "hold commit failed")
+				return
+			}
+			logf("info", logFields{"requestId": requestID(r), "holdId": holdID, "txId": txID}, "[bank] hold committed")
+			writeJSON(w, 200, map[string]string{
+				"holdId":        holdID,
+				"transactionId": txID,
+			})
+
+		case "release":
+			hold, err := db.GetHold(holdID)
+			if err != nil {
+				logf("error", logFields{"requestId": requestID(r), "holdId": holdID}, "[bank] release get hold: %v", err)
+				writeError(w, 500, "db_error", "database error")
+				return
+			}
+			if hold == nil {
+				writeError(w, 404, "not_found", "hold not found, committed, or expired")
+				return
+			}
+			newBalStr, err := releaseHold(db, rdb, *hold, "hold_release")
+			if err == errHoldNotFound {
+				writeError(w, 404, "not_found", "hold not found, committed, or expired")
+				return
+			}
+			if rejectIfConflict(w, err) {
+				return
+			}
+			if err != nil {
+				logf("error", logFields{"requestId": requestID(r), "holdId": holdID, "playerId": hold.PlayerID}, "[bank] release hold: %v", err)
+				writeError(w, 500, "db_error", "hold release failed")
+				return
+			}
+			writeJSON(w, 200, map[string]string{
+				"holdId":     holdID,
+				"playerId":   hold.PlayerID,
+				"returned":   hold.Amount,
+				"newBalance": newBalStr,
+			})
+
+		default:
+			writeError(w, 404, "not_found", "unknown hold action")
+		}
+	}
+}
+
+// releaseHold credits a hold's amount back via COBOL and deletes the hold.
+// Shared by the release endpoint and the expiry sweeper.
+func releaseHold(db *DB, rdb *redis.Client, hold Hold, txType string) (string, error) {
+	balanceStr, found, err := db.GetBalance(hold.PlayerID)
+	if err != nil || !found {
+		return "", fmt.Errorf("get balance: %v (found=%v)", err, found)
+	}
+	balanceCents, err := DollarsToCents(balanceStr)
+	if err != nil {
+		return "", err
+	}
+	holdCents, err := DollarsToCents(hold.Amount)
+	if err != nil {
+		return "", err
+	}
+	newBalCents, err := CalcCredit(balanceCents, holdCents)
+	if err != nil {
+		return "", err
+	}
+	newBalStr := CentsToDollars(newBalCents)
+	if err := db.ReleaseHold(hold.HoldID, hold.PlayerID, balanceStr, newBalStr, hold.Amount, txType); err != nil {
+		return "", err
+	}
+	logf("info", logFields{"playerId": hold.PlayerID, "holdId": hold.HoldID},
+		"[bank] %s: returned=%s newBalance=%s", txType, hold.Amount, newBalStr)
+	publishBalance(rdb, hold.PlayerID, newBalStr)
+	return newBalStr, nil
+}
+
+// sweepExpiredHolds returns the funds of expired holds. Runs for the life
+// of the process.
+func sweepExpiredHolds(db *DB, rdb *redis.Client, interval time.Duration) {
+	for range time.Tick(interval) {
+		holds, err := db.ExpiredHolds()
+		if err != nil {
+			logf("error", nil, "[bank] hold sweep: %v", err)
+			continue
+		}
+		for _, h := range holds {
+			if _, err := releaseHold(db, rdb, h, "hold_expired"); err != nil && err != errHoldNotFound {
+				logf("error", logFields{"holdId": h.HoldID, "playerId": h.PlayerID}, "[bank] hold sweep release: %v", err)
+			}
+		}
+	}
+}
+
+// ── Transfer ──────────────────────────────────────────────────────────────────
+
+// transferHandler sends chips from one player to another (tipping).
+//
+//	POST /transfer  {"fromPlayerId", "toPlayerId", "amount"}
+func transferHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, 405, "method_not_allowed", "POST only")
+			return
+		}
+		var req struct {
+			FromPlayerID string `json:"fromPlayerId"`
+			ToPlayerID   string `json:"toPlayerId"`
+			Amount       string `json:"amount"`
+		}
+		if err := parseBody(r, &req); err != nil {
+			writeError(w, 400, "bad_request", "invalid JSON")
+			return
+		}
+		if req.FromPlayerID == "" || req.ToPlayerID == "" || req.Amount == "" {
+			writeError(w, 400, "missing_field", "fromPlayerId, toPlayerId and amount required")
+			return
+		}
+		// Through the gateway, X-Player-ID is the session's player — they can
+		// only send their own chips
+		if caller := r.Header.Get("X-Player-ID"); caller != "" && caller != req.FromPlayerID {
+			writeError(w, 403, "forbidden", "can only transfer from your own account")
+			return
+		}
+		if req.FromPlayerID == req.ToPlayerID {
+			writeError(w, 400, "self_transfer", "cannot transfer to yourself")
+			return
+		}
+		amountCents, err := DollarsToCents(req.Amount)
+		if err != nil || amountCents <= 0 {
+			writeError(w, 400, "invalid_amount", "amount must be a positive decimal")
+			return
+		}
+
+		fromBal, found, err := db.GetBalance(req.FromPlayerID)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r), "playerId": req.FromPlayerID}, "[bank] transfer get balance: %v", err)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		if !found {
+			writeError(w, 404, "not_found", "sender account not found")
+			return
+		}
+		toBal, found, err := db.GetBalance(req.ToPlayerID)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r), "playerId": req.ToPlayerID}, "[bank] transfer get balance: %v", err)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		if !found {
+			writeError(w, 404, "recipient_not_found", "recipient account not found")
+			return
+		}
+		if rejectIfFrozen(w, db, req.FromPlayerID) {
+			return
+		}
+
+		fromCents, err := DollarsToCents(fromBal)
+		if err != nil {
+			writeError(w, 500, "internal_error", "balance format error")
+			return
+		}
+		toCents, err := DollarsToCents(toBal)
+		if err != nil {
+			writeError(w, 500, "internal_error", "balance format error")
+			return
+		}
+
+		// COBOL: the sender must cover the amount
+		debit, err := ValidateDebit(fromCents, amountCents)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] COBOL validate-debit: %v", err)
+			writeCOBOLError(w, err, "transfer validation failed")
+			return
+		}
+		if debit.Status == "INSUFFICIENT" {
+			writeJSON(w, 409, map[string]any{
+				"error":     "insufficient_funds",
+				"balance":   fromBal,
+				"requested": req.Amount,
+			})
+			return
+		}
+		// COBOL: credit the recipient
+		toNewCents, err := CalcCappedCredit(toCents, amountCents)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] COBOL calc-credit: %v", err)
+			writeCOBOLError(w, err, "credit calculation failed")
+			return
+		}
+
+		amountStr := CentsToDollars(amountCents)
+		from := TransferSide{PlayerID: req.FromPlayerID, BalanceBefore: fromBal, BalanceAfter: CentsToDollars(debit.NewBalanceCents)}
+		to := TransferSide{PlayerID: req.ToPlayerID, BalanceBefore: toBal, BalanceAfter: CentsToDollars(toNewCents)}
+		refID, err := db.Transfer(from, to, amountStr)
+		if rejectIfConflict(w, err) {
+			return
+		}
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] transfer: %v", err)
+			writeError(w, 500, "db_error", "transfer failed")
+			return
+		}
+
+		logf("info", logFields{"requestId": requestID(r), "playerId": from.PlayerID},
+			"[bank] transfer: to=%s amount=%s refId=%s", to.PlayerID, amountStr, refID)
+		publishBalance(rdb, from.PlayerID, from.BalanceAfter)
+		publishBalance(rdb, to.PlayerID, to.BalanceAfter)
+
+		writeJSON(w, 200, map[string]string{
+			"refId":          refID,
+			"fromPlayerId":   from.PlayerID,
+			"toPlayerId":     to.PlayerID,
+			"amount":         amountStr,
+			"fromNewBalance": from.BalanceAfter,
+		})
+	}
+}
+
+// ── Self-exclusion ────────────────────────────────────────────────────────────
+
+// maxSelfExcludeHours caps a single self-exclusion request at one year.
+const maxSelfExcludeHours = 24 * 365
+
+// selfExcludeHandler freezes betting on an account for a cooldown period.
+//
+//	GET  /self-exclude?playerId=  — current exclusion (excludedUntil or null)
+//	POST /self-exclude            — {"playerId", "hours"}; extends, never shortens
+func selfExcludeHandler(db *DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(204)
+
+		case http.MethodGet:
+			playerID := queryParam(r.URL.Query(), "playerId")
+			if playerID == "" {
+				writeError(w, 400, "missing_param", "playerId required")
+				return
+			}
+			until, err := db.FrozenUntil(playerID)
+			if err != nil {
+				logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] self-exclude get: %v", err)
+				writeError(w, 500, "db_error", "database error")
+				return
+			}
+			var excludedUntil any
+			if until != nil {
+				excludedUntil = until.Format(time.RFC3339)
+			}
+			writeJSON(w, 200, map[string]any{"playerId": playerID, "excludedUntil": excludedUntil})
+
+		case http.MethodPost:
+			var req struct {
+				PlayerID string `json:"playerId"`
+				Hours    int    `json:"hours"`
+			}
+			if err := parseBody(r, &req); err != nil {
+				writeError(w, 400, "bad_request", "invalid JSON")
+				return
+			}
+			if req.PlayerID == "" {
+				writeError(w, 400, "missing_field", "playerId required")
+				return
+			}
+			// The gateway injects the token's subject — a player can only exclude themselves
+			if caller := r.Header.Get("X-Player-ID"); caller != "" && caller != req.PlayerID {
+				writeError(w, 403, "forbidden", "cannot self-exclude another player")
+				return
+			}
+			if req.Hours < 1 || req.Hours > maxSelfExcludeHours {
+				writeError(w, 400, "invalid_duration", fmt.Sprintf("hours must be 1-%d", maxSelfExcludeHours))
+				return
+			}
+			until, found, err := db.FreezeUntil(req.PlayerID, time.Now().Add(time.Duration(req.Hours)*time.Hour))
+			if err != nil {
+				logf("error", logFields{"requestId": requestID(r), "playerId": req.PlayerID}, "[bank] self-exclude: %v", err)
+				writeError(w, 500, "db_error", "database error")
+				return
+			}
+			if !found {
+				writeError(w, 404, "not_found", "player account not found")
+				return
+			}
+			logf("info", logFields{"requestId": requestID(r), "playerId": req.PlayerID}, "[bank] self-exclusion until %s", until.Format(time.RFC3339))
+			writeJSON(w, 200, map[string]string{
+				"playerId":      req.PlayerID,
+				"excludedUntil": until.Format(time.RFC3339),
+			})
+
+		default:
+			writeError(w, 405, "method_not_allowed", "GET or POST only")
+		}
+	}
+}
+
+// ── Deposit ───────────────────────────────────────────────────────────────────
+
+func depositHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, 405, "method_not_allowed", "POST only")
+			return
+		}
+		var req struct {
+			PlayerID string `json:"playerId"`
+			Amount   string `json:"amount"`
+			Note     string `json:"note"`
+			RefID    string `json:"refId"` // optional idempotency key
 		}
 		if err := parseBody(r, &req); err != nil {
 			writeError(w, 400, "bad_request", "invalid JSON")
@@ -426,6 +1412,13 @@ func depositHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			writeError(w, 400, "invalid_amount", "amount must be positive")
 			return
 		}
+		if len(req.RefID) > 100 {
+			writeError(w, 400, "invalid_param", "refId must be at most 100 characters")
+			return
+		}
+		if replayByRef(w, db, req.PlayerID, "deposit", req.RefID, depositCents) {
+			return
+		}
 
 		balanceStr, found, err := db.GetBalance(req.PlayerID)
 		if err != nil || !found {
@@ -434,14 +1427,24 @@ func depositHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 		}
 
 		balanceCents, _ := DollarsToCents(balanceStr)
-		newBalCents, err := CalcCredit(balanceCents, depositCents)
+		newBalCents, err := CalcCappedCredit(balanceCents, depositCents)
 		if err != nil {
-			writeError(w, 500, "cobol_error", "deposit calculation failed")
+			writeCOBOLError(w, err, "deposit calculation failed")
 			return
 		}
 		newBalStr := CentsToDollars(newBalCents)
 
-		if err := db.ApplyBalanceChange(req.PlayerID, balanceStr, newBalStr, req.Amount, "deposit", req.Note); err != nil {
+		if err := db.ApplyBalanceChange(req.PlayerID, balanceStr, newBalStr, req.Amount, "deposit", req.Note, req.RefID); err != nil {
+			if err == errDuplicateRef {
+				// A concurrent retry with the same refId won the race
+				if !replayByRef(w, db, req.PlayerID, "deposit", req.RefID, depositCents) {
+					writeError(w, 500, "db_error", "deposit failed")
+				}
+				return
+			}
+			if rejectIfConflict(w, err) {
+				return
+			}
 			writeError(w, 500, "db_error", "deposit failed")
 			return
 		}
@@ -451,6 +1454,35 @@ func depositHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 	}
 }
 
+// replayByRef answers a deposit or withdrawal whose refId was already applied
+// with the original result, so a retried request never moves money twice.
+// Reusing a refId for a different amount is a 409. Returns false — nothing
+// written — when refID is empty or unused.
+func replayByRef(w http.ResponseWriter, db *DB, playerID, txType, refID string, amountCents int64) bool {
+	if refID == "" {
+		return false
+	}
+	prior, err := db.GetTransactionByRef(playerID, txType, refID)
+	if err != nil {
+		writeError(w, 500, "db_error", "could not check refId")
+		return true
+	}
+	if prior == nil {
+		return false
+	}
+	if priorCents, _ := DollarsToCents(prior.Amount); priorCents != amountCents {
+		writeError(w, 409, "ref_id_conflict", "refId was already used for a different amount")
+		return true
+	}
+	writeJSON(w, 200, map[string]any{
+		"playerId":      playerID,
+		"newBalance":    prior.BalanceAfter,
+		"transactionId": prior.ID,
+		"replayed":      true,
+	})
+	return true
+}
+
 // ── Withdraw ──────────────────────────────────────────────────────────────────
 
 func withdrawHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
@@ -468,6 +1500,7 @@ func withdrawHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			PlayerID string `json:"playerId"`
 			Amount   string `json:"amount"`
 			Note     string `json:"note"`
+			RefID    string `json:"refId"` // optional idempotency key
 		}
 		if err := parseBody(r, &req); err != nil {
 			writeError(w, 400, "bad_request", "invalid JSON")
@@ -482,6 +1515,13 @@ func withdrawHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 			writeError(w, 400, "invalid_amount", "amount must be positive")
 			return
 		}
+		if len(req.RefID) > 100 {
+			writeError(w, 400, "invalid_param", "refId must be at most 100 characters")
+			return
+		}
+		if replayByRef(w, db, req.PlayerID, "withdrawal", req.RefID, withdrawCents) {
+			return
+		}
 
 		balanceStr, found, err := db.GetBalance(req.PlayerID)
 		if err != nil || !found {
@@ -492,7 +1532,7 @@ func withdrawHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 		balanceCents, _ := DollarsToCents(balanceStr)
 		debit, err := ValidateDebit(balanceCents, withdrawCents)
 		if err != nil {
-			writeError(w, 500, "cobol_error", "withdrawal validation failed")
+			writeCOBOLError(w, err, "withdrawal validation failed")
 			return
 		}
 		if debit.Status == "INSUFFICIENT" {
@@ -505,7 +1545,17 @@ func withdrawHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 		}
 
 		newBalStr := CentsToDollars(debit.NewBalanceCents)
-		if err := db.ApplyBalanceChange(req.PlayerID, balanceStr, newBalStr, req.Amount, "withdrawal", req.Note); err != nil {
+		if err := db.ApplyBalanceChange(req.PlayerID, balanceStr, newBalStr, req.Amount, "withdrawal", req.Note, req.RefID); err != nil {
+			if err == errDuplicateRef {
+				// A concurrent retry with the same refId won the race
+				if !replayByRef(w, db, req.PlayerID, "withdrawal", req.RefID, withdrawCents) {
+					writeError(w, 500, "db_error", "withdrawal failed")
+				}
+				return
+			}
+			if rejectIfConflict(w, err) {
+				return
+			}
 			writeError(w, 500, "db_error", "withdrawal failed")
 			return
 		}
@@ -515,10 +1565,159 @@ func withdrawHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
 	}
 }
 
-// ── Export (PDF via document-service) ────────────────────────────────────────
+// cashoutHandler withdraws a player's whole balance in one step: POST /cashout.
+// Refused while the player has unsettled bets — their payouts would land on
+// an account the player believes is empty.
+func cashoutHandler(db *DB, rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, 405, "method_not_allowed", "POST only")
+			return
+		}
+		var req struct {
+			PlayerID string `json:"playerId"`
+			Note     string `json:"note"`
+		}
+		if err := parseBody(r, &req); err != nil {
+			writeError(w, 400, "bad_request", "invalid JSON")
+			return
+		}
+		if req.PlayerID == "" {
+			writeError(w, 400, "missing_field", "playerId required")
+			return
+		}
+
+		bets, err := db.GetOpenBets(req.PlayerID)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] cashout open bets: %v", err)
+			writeError(w, 500, "db_error", "database error")
+			return
+		}
+		if len(bets) > 0 {
+			writeJSON(w, 409, map[string]any{
+				"error":    "open_bets",
+				"message":  "settle open bets before cashing out",
+				"openBets": len(bets),
+			})
+			return
+		}
+
+		balanceStr, found, err := db.GetBalance(req.PlayerID)
+		if err != nil || !found {
+			writeError(w, 404, "not_found", "player account not found")
+			return
+		}
+		balanceCents, err := DollarsToCents(balanceStr)
+		if err != nil {
+			writeError(w, 500, "internal_error", "balance format error")
+			return
+		}
+		if balanceCents == 0 {
+			writeJSON(w, 200, map[string]string{
+				"playerId": req.PlayerID, "withdrawn": "0.00", "newBalance": "0.00",
+			})
+			return
+		}
+
+		// COBOL: the debit of the full balance must come out at exactly zero
+		debit, err := ValidateDebit(balanceCents, balanceCents)
+		if err != nil {
+			writeCOBOLError(w, err, "cashout validation failed")
+			return
+		}
+		if debit.Status == "INSUFFICIENT" || debit.NewBalanceCents != 0 {
+			logf("error", logFields{"requestId": requestID(r), "playerId": req.PlayerID},
+				"[bank] cashout: balance=%s validate-debit=%s newBalance=%d", balanceStr, debit.Status, debit.NewBalanceCents)
+			writeError(w, 500, "internal_error", "cashout did not clear the balance")
+			return
+		}
+
+		amountStr := CentsToDollars(balanceCents)
+		newBalStr := CentsToDollars(debit.NewBalanceCents)
+		if err := db.ApplyBalanceChange(req.PlayerID, balanceStr, newBalStr, amountStr, "cashout", req.Note, ""); err != nil {
+			if rejectIfConflict(w, err) {
+				return
+			}
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] cashout: %v", err)
+			writeError(w, 500, "db_error", "cashout failed")
+			return
+		}
+
+		logf("info", logFields{"requestId": requestID(r), "playerId": req.PlayerID}, "[bank] cashout: withdrawn=%s", amountStr)
+		publishBalance(rdb, req.PlayerID, newBalStr)
+		writeJSON(w, 200, map[string]string{
+			"playerId":   req.PlayerID,
+			"withdrawn":  amountStr,
+			"newBalance": newBalStr,
+		})
+	}
+}
+
+// ── Export (PDF via document-service, or CSV) ───────────────────────────────
 
 var documentServiceURL = "http://document-service:3011"
 
+// documentTimeout bounds a whole PDF render, body included — generous,
+// since long statements make large PDFs. Set at startup from
+// DOCUMENT_TIMEOUT.
+var documentTimeout = 60 * time.Second
+
+// documentClient is a dedicated, pooled client for document-service, so a
+// slow render can't hang an export forever or starve other outbound calls.
+var documentClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        8,
+		MaxIdleConnsPerHost: 8,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// statementSecret keys the HMAC that makes an exported statement verifiable.
+// Set at startup from STATEMENT_SECRET.
+var statementSecret []byte
+
+// statementLimit is the number of transactions a statement covers.
+const statementLimit = 200
+
+// statementRows renders transactions as the CSV rows shown in the PDF.
+// The signature is computed over exactly these rows.
+func statementRows(txns []Transaction) []string {
+	rows := make([]string, 0, len(txns))
+	for _, t := range txns {
+		// CSV row: ID(short),Type,Amount,Balance After,Time
+		id := t.ID
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		rows = append(rows, fmt.Sprintf("%s,%s,%s,%s,%s",
+			id, t.Type, t.Amount, t.BalanceAfter, t.CreatedAt))
+	}
+	return rows
+}
+
+// signStatement returns a hex HMAC-SHA256 over the player, the statement
+// timestamp, the date range (if any), and every row — any edit to the
+// document breaks it. An unbounded range signs exactly as before ranges
+// existed, so older statements still verify.
+func signStatement(playerID, asOf string, rng TxRange, rows []string) string {
+	mac := hmac.New(sha256.New, statementSecret)
+	fmt.Fprintf(mac, "%s\n%s\n", playerID, asOf)
+	if !rng.IsZero() {
+		fmt.Fprintf(mac, "%s\n", rng)
+	}
+	for _, row := range rows {
+		mac.Write([]byte(row))
+		mac.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func exportHandler(db *DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		setCORSHeaders(w)
@@ -535,29 +1734,41 @@ func exportHandler(db *DB) http.HandlerFunc {
 			writeError(w, 400, "missing_param", "playerId required")
 			return
 		}
+		rng, err := parseTxRange(r.URL.Query())
+		if err != nil {
+			writeError(w, 400, "invalid_param", err.Error())
+			return
+		}
+		switch strings.ToLower(queryParam(r.URL.Query(), "format")) {
+		case "", "pdf":
+		case "csv":
+			exportCSV(w, db, playerID, rng)
+			return
+		default:
+			writeError(w, 400, "invalid_param", "format must be pdf or csv")
+			return
+		}
 
-		txns, err := db.GetTransactions(playerID, 200)
+		asOfTime := time.Now().UTC()
+		asOf := asOfTime.Format(time.RFC3339Nano)
+		txns, err := db.GetTransactionsAsOf(playerID, asOfTime, rng, statementLimit)
 		if err != nil {
 			writeError(w, 500, "db_error", "failed to fetch transactions")
 			return
 		}
 
 		// Build document request
-		rows := make([]string, 0, len(txns))
-		for _, t := range txns {
-			// CSV row: ID(short),Type,Amount,Balance After,Time
-			id := t.ID
-			if len(id) > 8 {
-				id = id[:8]
-			}
-			rows = append(rows, fmt.Sprintf("%s,%s,%s,%s,%s",
-				id, t.Type, t.Amount, t.BalanceAfter, t.CreatedAt))
+		rows := statementRows(txns)
+		signature := signStatement(playerID, asOf, rng, rows)
+		heading := fmt.Sprintf("Bank Transactions — Player %s", playerID)
+		if !rng.IsZero() {
+			heading += " · " + rng.String()
 		}
 
 		docReq := map[string]any{
 			"caller":  "bank-service",
 			"title":   "Transaction History",
-			"heading": fmt.Sprintf("Bank Transactions — Player %s", playerID),
+			"heading": heading,
 			"blocks": []any{
 				map[string]any{
 					"table": map[string]any{
@@ -566,22 +1777,144 @@ func exportHandler(db *DB) http.HandlerFunc {
 						"rows":    rows,
 					},
 				},
+				map[string]any{"footer": fmt.Sprintf("Statement as of %s · signature (HMAC-SHA256) %s", asOf, signature)},
 				map[string]any{"footer": "Generated by Swarm Blackjack · bank-service (Go + COBOL)"},
 			},
 		}
 
 		body, _ := json.Marshal(docReq)
-		resp, err := http.Post(documentServiceURL+"/document", "application/json", strings.NewReader(string(body)))
+		ctx, cancel := context.WithTimeout(r.Context(), documentTimeout)
+		defer cancel()
+		docHTTPReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, documentServiceURL+"/document", bytes.NewReader(body))
+		docHTTPReq.Header.Set("Content-Type", "application/json")
+		docHTTPReq.Header.Set("X-Request-ID", requestID(r))
+		resp, err := documentClient.Do(docHTTPReq)
 		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				logf("error", logFields{"requestId": requestID(r), "playerId": playerID},
+					"[bank] export: document-service timed out after %s", documentTimeout)
+				writeError(w, 504, "upstream_timeout", "document service timed out")
+				return
+			}
 			writeError(w, 502, "upstream_error", "document service unavailable")
 			return
 		}
 		defer resp.Body.Close()
 
+		// Only a 200 is a PDF — anything else would be an error body served
+		// as a corrupt application/pdf download
+		if resp.StatusCode != http.StatusOK {
+			detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			logf("error", logFields{"requestId": requestID(r), "playerId": playerID},
+				"[bank] export: document-service returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+			writeError(w, 502, "upstream_error", fmt.Sprintf("document service returned %d", resp.StatusCode))
+			return
+		}
+
+		w.Header().Set("X-Statement-As-Of", asOf)
+		w.Header().Set("X-Statement-Signature", signature)
 		w.Header().Set("Content-Type", "application/pdf")
 		w.Header().Set("Content-Disposition", `attachment; filename="transactions.pdf"`)
-		w.WriteHeader(resp.StatusCode)
-		io.Copy(w, resp.Body)
+		if resp.ContentLength >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		// Headers are sent — a failure now can only cut the download short
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] export: streaming PDF: %v", err)
+		}
+	}
+}
+
+// exportCSV streams the player's full history as text/csv, newest first,
+// straight from the database — no document-service round trip, so it works
+// when that service is down. Pages are written as they are read.
+func exportCSV(w http.ResponseWriter, db *DB, playerID string, rng TxRange) {
+	txns, next, err := db.GetTransactions(playerID, rng, nil, nil, maxTxPageSize)
+	if err != nil {
+		writeError(w, 500, "db_error", "failed to fetch transactions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="transactions.csv"`)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "type", "amount", "balance_before", "balance_after",
+		"ref_id", "parent_transaction_id", "note", "created_at"})
+	for {
+		for _, t := range txns {
+			cw.Write([]string{t.ID, t.Type, t.Amount, t.BalanceBefore, t.BalanceAfter,
+				derefOr(t.RefID), derefOr(t.ParentTxID), derefOr(t.Note), t.CreatedAt})
+		}
+		cw.Flush()
+		if next == nil {
+			return
+		}
+		// Headers are sent — a failure now can only end the stream early
+		txns, next, err = db.GetTransactions(playerID, rng, nil, next, maxTxPageSize)
+		if err != nil {
+			logf("error", logFields{"playerId": playerID}, "[bank] csv export: %v", err)
+			return
+		}
+	}
+}
+
+func derefOr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// statementVerifyHandler recomputes a statement's signature:
+// GET /statement/verify?playerId=&asOf=&signature=
+//
+// A statement exported with from/to must be verified with the same bounds.
+func statementVerifyHandler(db *DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(204)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeError(w, 405, "method_not_allowed", "GET only")
+			return
+		}
+		q := r.URL.Query()
+		playerID := queryParam(q, "playerId")
+		asOf := queryParam(q, "asOf")
+		signature := queryParam(q, "signature")
+		if playerID == "" || asOf == "" || signature == "" {
+			writeError(w, 400, "missing_param", "playerId, asOf and signature required")
+			return
+		}
+		asOfTime, err := time.Parse(time.RFC3339Nano, asOf)
+		if err != nil {
+			writeError(w, 400, "invalid_param", "asOf must be RFC3339")
+			return
+		}
+		rng, err := parseTxRange(q)
+		if err != nil {
+			writeError(w, 400, "invalid_param", err.Error())
+			return
+		}
+
+		txns, err := db.GetTransactionsAsOf(playerID, asOfTime, rng, statementLimit)
+		if err != nil {
+			logf("error", logFields{"requestId": requestID(r), "playerId": playerID}, "[bank] statement verify: %v", err)
+			writeError(w, 500, "db_error", "failed to fetch transactions")
+			return
+		}
+		expected := signStatement(playerID, asOf, rng, statementRows(txns))
+		valid := hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+
+		writeJSON(w, 200, map[string]any{
+			"playerId":     playerID,
+			"asOf":         asOf,
+			"transactions": len(txns),
+			"valid":        valid,
+		})
 	}
 }
 
@@ -594,11 +1927,11 @@ func devResetHandler(db *DB) http.HandlerFunc {
 			return
 		}
 		if err := db.DevReset(); err != nil {
-			log.Printf("[bank] dev reset: %v", err)
+			logf("error", logFields{"requestId": requestID(r)}, "[bank] dev reset: %v", err)
 			writeError(w, 500, "db_error", "reset failed")
 			return
 		}
-		log.Printf("[bank] DEV RESET: all accounts wiped, demo player re-seeded")
+		logf("warn", logFields{"requestId": requestID(r)}, "[bank] DEV RESET: all accounts wiped, demo player re-seeded")
 		writeJSON(w, 200, map[string]bool{"reset": true})
 	}
 }
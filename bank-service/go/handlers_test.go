@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubPrograms stand in for the compiled COBOL so handler tests run without
+// cobc. They follow the programs' env-in, KEY=VALUE-out contract and do the
+// same integer-cents arithmetic; the COBOL itself is covered by the tests in
+// cobol_test.go that run against COBOL_BIN_DIR.
+var stubPrograms = map[string]string{
+	"VALIDATE-DEBIT": `if [ "$DEBIT_CENTS" -gt "$BALANCE_CENTS" ]; then
+  echo STATUS=INSUFFICIENT; echo NEW_BALANCE_CENTS=$BALANCE_CENTS
+else
+  echo STATUS=OK; echo NEW_BALANCE_CENTS=$((BALANCE_CENTS - DEBIT_CENTS))
+fi`,
+	"CALC-CREDIT": `echo NEW_BALANCE_CENTS=$((BALANCE_CENTS + CREDIT_CENTS))`,
+	"CALC-PAYOUT": `case "$RESULT" in
+  WIN) echo RETURNED_CENTS=$((BET_CENTS + BET_CENTS * MULT_NUM / MULT_DEN)); echo PAYOUT_TYPE=payout_win ;;
+  BLACKJACK) echo RETURNED_CENTS=$((BET_CENTS + BET_CENTS * MULT_NUM / MULT_DEN)); echo PAYOUT_TYPE=payout_blackjack ;;
+  INSURANCE) echo RETURNED_CENTS=$((BET_CENTS + BET_CENTS * MULT_NUM / MULT_DEN)); echo PAYOUT_TYPE=payout_insurance ;;
+  PUSH) echo RETURNED_CENTS=$((BET_CENTS * MULT_NUM / MULT_DEN)); echo PAYOUT_TYPE=payout_push ;;
+  SURRENDER) echo RETURNED_CENTS=$((BET_CENTS * MULT_NUM / MULT_DEN)); echo PAYOUT_TYPE=payout_surrender ;;
+  *) echo RETURNED_CENTS=0; echo PAYOUT_TYPE=payout_loss ;;
+esac`,
+}
+
+// stubCOBOL points cobolDir at shell stand-ins for the COBOL programs.
+func stubCOBOL(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range stubPrograms {
+		script := "#!/bin/sh\n" + body + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatalf("write stub %s: %v", name, err)
+		}
+	}
+	saved := cobolDir
+	cobolDir = dir
+	t.Cleanup(func() { cobolDir = saved })
+	resetPayoutCache(t)
+}
+
+// doJSON sends body to h and decodes the JSON response.
+func doJSON(t *testing.T, h http.HandlerFunc, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(method, path, &buf))
+	var out map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+// ── Self-exclusion ───────────────────────────────────────────────────────────
+
+func TestBetRejectedDuringSelfExclusion(t *testing.T) {
+	db := testDB(t)
+	stubCOBOL(t)
+	player := testAccount(t, db, "100.00")
+	bet := betHandler(db, nil)
+
+	code, out := doJSON(t, selfExcludeHandler(db), http.MethodPost, "/self-exclude",
+		map[string]any{"playerId": player, "hours": 1})
+	if code != 200 {
+		t.Fatalf("self-exclude: status %d %v", code, out)
+	}
+
+	code, out = doJSON(t, bet, http.MethodPost, "/bet", map[string]string{"playerId": player, "amount": "10.00"})
+	if code != 403 || out["error"] != "self_excluded" {
+		t.Fatalf("bet during exclusion: status %d %v, want 403 self_excluded", code, out)
+	}
+	assertBalance(t, db, player, "100.00")
+}
+
+func TestBetAllowedAfterSelfExclusionExpires(t *testing.T) {
+	db := testDB(t)
+	stubCOBOL(t)
+	player := testAccount(t, db, "100.00")
+	bet := betHandler(db, nil)
+
+	if _, _, err := db.FreezeUntil(player, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("freeze: %v", err)
+	}
+	// The cooldown runs out — nothing has to lift it, FrozenUntil ignores a past freeze
+	if _, err := db.pool.Exec(
+		`UPDATE accounts SET frozen_until = NOW() - interval '1 second' WHERE player_id=$1`, player,
+	); err != nil {
+		t.Fatalf("expire freeze: %v", err)
+	}
+
+	code, out := doJSON(t, selfExcludeHandler(db), http.MethodGet, "/self-exclude?playerId="+player, nil)
+	if code != 200 || out["excludedUntil"] != nil {
+		t.Fatalf("self-exclude status after expiry: %d %v, want excludedUntil null", code, out)
+	}
+	code, out = doJSON(t, bet, http.MethodPost, "/bet", map[string]string{"playerId": player, "amount": "10.00"})
+	if code != 200 {
+		t.Fatalf("bet after expiry: status %d %v, want 200", code, out)
+	}
+	assertBalance(t, db, player, "90.00")
+}
+
+// ── Concurrent bets ──────────────────────────────────────────────────────────
+
+// errorCode reads the code out of a writeError body.
+func errorCode(out map[string]any) string {
+	e, _ := out["error"].(map[string]any)
+	code, _ := e["code"].(string)
+	return code
+}
+
+// betInParallel fires n bets of amount at once, each retrying on 409
+// balance_conflict as game-state does. It returns how many were placed and
+// how many were refused for insufficient funds.
+func betInParallel(t *testing.T, bet http.HandlerFunc, player, amount string, n int) (placed, refused int) {
+	t.Helper()
+	var okCount, insufficient, conflicts atomic.Int64
+	var wg sync.WaitGroup
+	errs := make(chan string, n)
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for attempt := 0; attempt < 200; attempt++ {
+				code, out := doJSON(t, bet, http.MethodPost, "/bet", map[string]string{"playerId": player, "amount": amount})
+				switch {
+				case code == 200:
+					okCount.Add(1)
+					return
+				case code == 409 && out["error"] == "insufficient_funds":
+					insufficient.Add(1)
+					return
+				case code == 409 && errorCode(out) == "balance_conflict":
+					conflicts.Add(1)
+				default:
+					errs <- fmt.Sprintf("status %d %v", code, out)
+					return
+				}
+			}
+			errs <- "still conflicting after 200 attempts"
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Errorf("bet: %s", e)
+	}
+	t.Logf("%d bets placed, %d refused, %d balance conflicts retried", okCount.Load(), insufficient.Load(), conflicts.Load())
+	return int(okCount.Load()), int(insufficient.Load())
+}
+
+func TestParallelBetsExactBalance(t *testing.T) {
+	db := testDB(t)
+	stubCOBOL(t)
+	player := testAccount(t, db, "100.00")
+
+	const n = 25
+	placed, refused := betInParallel(t, betHandler(db, nil), player, "2.50", n)
+	if placed != n || refused != 0 {
+		t.Fatalf("placed %d refused %d, want all %d placed", placed, refused, n)
+	}
+	// Every debit lands exactly once: no lost update, no double charge
+	assertBalance(t, db, player, "37.50")
+	bets, err := db.GetOpenBets(player)
+	if err != nil {
+		t.Fatalf("open bets: %v", err)
+	}
+	if len(bets) != n {
+		t.Errorf("%d open bets, want %d", len(bets), n)
+	}
+}
+
+func TestParallelBetsNeverOverdraw(t *testing.T) {
+	db := testDB(t)
+	stubCOBOL(t)
+	player := testAccount(t, db, "10.00")
+
+	// Twice as many bets as the balance covers
+	placed, refused := betInParallel(t, betHandler(db, nil), player, "1.00", 20)
+	if placed != 10 || refused != 10 {
+		t.Errorf("placed %d refused %d, want 10 and 10", placed, refused)
+	}
+	assertBalance(t, db, player, "0.00")
+}
+
+// ── Balance cap ──────────────────────────────────────────────────────────────
+
+func TestPayoutReportsBalanceCap(t *testing.T) {
+	db := testDB(t)
+	stubCOBOL(t)
+	saved := maxBalanceCents
+	maxBalanceCents = 120_000 // 1200.00
+	t.Cleanup(func() { maxBalanceCents = saved })
+
+	player := testAccount(t, db, "1000.00")
+	code, out := doJSON(t, betHandler(db, nil), http.MethodPost, "/bet",
+		map[string]any{"playerId": player, "amount": "200.00"})
+	if code != 200 {
+		t.Fatalf("bet: %d %v", code, out)
+	}
+	// 800.00 + 400.00 back fits; a 3:2 natural's 500.00 is 100.00 over
+	code, out = doJSON(t, payoutHandler(db, nil), http.MethodPost, "/payout",
+		map[string]any{"transactionId": out["transactionId"], "result": "blackjack"})
+	if code != 200 || out["returned"] != "400.00" || out["code"] != "balance_cap_exceeded" ||
+		out["withheld"] != "100.00" || out["maxBalance"] != "1200.00" {
+		t.Errorf("capped payout: %d %v, want 400.00 returned and 100.00 withheld", code, out)
+	}
+	assertBalance(t, db, player, "1200.00")
+}
+
+// ── Holds ────────────────────────────────────────────────────────────────────
+
+// Requests refused before any database call — these run without Postgres.
+func TestHoldRejectsBadRequests(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		body   any
+		status int
+		code   string
+	}{
+		{"wrong method", http.MethodGet, nil, 405, "method_not_allowed"},
+		{"bad JSON", http.MethodPost, "not an object", 400, "bad_request"},
+		{"no player", http.MethodPost, map[string]string{"amount": "10.00"}, 400, "missing_field"},
+		{"no amount", http.MethodPost, map[string]string{"playerId": "p1"}, 400, "missing_field"},
+		{"zero amount", http.MethodPost, map[string]string{"playerId": "p1", "amount": "0.00"}, 400, "invalid_amount"},
+		{"negative amount", http.MethodPost, map[string]string{"playerId": "p1", "amount": "-5.00"}, 400, "invalid_amount"},
+		{"not a number", http.MethodPost, map[string]string{"playerId": "p1", "amount": "ten"}, 400, "invalid_amount"},
+	}
+	for _, c := range cases {
+		status, out := doJSON(t, holdHandler(nil, nil), c.method, "/hold", c.body)
+		if status != c.status || errorCode(out) != c.code {
+			t.Errorf("%s: %d %v, want %d %s", c.name, status, out, c.status, c.code)
+		}
+	}
+}
+
+func TestHoldActionRejectsBadRequests(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{"wrong method", http.MethodGet, "/hold/h1/commit", 405, "method_not_allowed"},
+		{"no action", http.MethodPost, "/hold/h1", 404, "not_found"},
+		{"no hold ID", http.MethodPost, "/hold//commit", 404, "not_found"},
+		{"extra segment", http.MethodPost, "/hold/h1/commit/now", 404, "not_found"},
+		{"unknown action", http.MethodPost, "/hold/h1/extend", 404, "not_found"},
+	}
+	for _, c := range cases {
+		status, out := doJSON(t, holdActionHandler(nil, nil), c.method, c.path, nil)
+		if status != c.status || errorCode(out) != c.code {
+			t.Errorf("%s: %d %v, want %d %s", c.name, status, out, c.status, c.code)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ── Logging ──────────────────────────────────────────────────────────────────
+// JSON lines unless LOG_FORMAT=text. Request paths log through logf with the
+// caller's requestId; log.Printf is left to startup and background loops.
+
+var logJSON = getEnv("LOG_FORMAT", "json") != "text"
+
+// logFields are the structured fields of one log line.
+type logFields map[string]any
+
+var logMu sync.Mutex
+
+// requestID is the X-Request-ID game-state sends with each call.
+func requestID(r *http.Request) string {
+	return r.Header.Get("X-Request-ID")
+}
+
+func setupLogging() {
+	if logJSON {
+		log.SetFlags(0)
+		log.SetOutput(jsonLogWriter{})
+	}
+}
+
+// jsonLogWriter turns each line the standard logger writes into a JSON line.
+type jsonLogWriter struct{}
+
+func (jsonLogWriter) Write(p []byte) (int, error) {
+	writeLogLine("info", string(bytes.TrimRight(p, "\n")), nil)
+	return len(p), nil
+}
+
+func writeLogLine(level, msg string, fields logFields) {
+	line := make(map[string]any, len(fields)+4)
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		line[k] = v
+	}
+	line["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level
+	line["service"] = "bank-service"
+	line["msg"] = msg
+	b, err := json.Marshal(line)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"level": level, "service": "bank-service", "msg": msg})
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+	os.Stderr.Write(append(b, '\n'))
+}
+
+// logf logs at level ("info", "warn" or "error") with fields. In text mode
+// the fields are appended as key=value pairs.
+func logf(level string, fields logFields, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if logJSON {
+		writeLogLine(level, msg, fields)
+		return
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg += fmt.Sprintf(" %s=%v", k, fields[k])
+	}
+	log.Print(msg)
+}
@@ -4,7 +4,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -18,6 +20,7 @@ func getEnv(key, fallback string) string {
 
 func main() {
 	log.SetFlags(log.Ltime | log.Lshortfile)
+	setupLogging()
 	log.Printf("[bank] starting — Go + GnuCOBOL bank service")
 
 	// ── Config ────────────────────────────────────────────────────────────────
@@ -34,17 +37,65 @@ func main() {
 	redisPort := getEnv("REDIS_PORT", "6379")
 
 	documentServiceURL = getEnv("DOCUMENT_SERVICE_URL", "http://document-service:3011")
+	observabilityURL = getEnv("OBSERVABILITY_URL", "")
 
-	// ── Database ──────────────────────────────────────────────────────────────
-	db, err := NewDB(dbHost, dbPort, dbName, dbUser, dbPass)
+	statementSecret = []byte(getEnv("STATEMENT_SECRET", ""))
+	if len(statementSecret) == 0 {
+		statementSecret = []byte("swarm-blackjack-dev-statement-secret")
+		log.Printf("[bank] STATEMENT_SECRET not set — using dev secret, statements are not tamper-proof")
+	}
+
+	schedule, err := LoadPayoutSchedule()
 	if err != nil {
-		log.Fatalf("[bank] database: %v", err)
+		log.Fatalf("[bank] payout schedule: %v", err)
 	}
-	if err := db.Migrate(); err != nil {
-		log.Fatalf("[bank] migrate: %v", err)
+	payoutSchedule = schedule
+
+	payoutStrict = getEnv("PAYOUT_STRICT", "false") == "true"
+	if payoutStrict {
+		log.Printf("[bank] payout strict mode: results inconsistent with the hand are rejected")
 	}
-	if err := db.SeedDemoPlayer(); err != nil {
-		log.Fatalf("[bank] seed: %v", err)
+
+	if d, err := time.ParseDuration(getEnv("COBOL_TIMEOUT", "2s")); err == nil && d > 0 {
+		cobolTimeout = d
+	} else {
+		log.Printf("[bank] invalid COBOL_TIMEOUT — using %s", cobolTimeout)
+	}
+	if n, err := strconv.Atoi(getEnv("COBOL_MAX_CONCURRENT", "8")); err == nil && n > 0 {
+		SetCOBOLConcurrency(n)
+	} else {
+		log.Printf("[bank] invalid COBOL_MAX_CONCURRENT — using %d", cap(cobolSlots))
+	}
+
+	if cents, err := DollarsToCents(getEnv("MAX_BALANCE", CentsToDollars(maxBalanceCents))); err == nil && cents >= 0 {
+		maxBalanceCents = cents
+	} else {
+		log.Printf("[bank] invalid MAX_BALANCE — using %s", CentsToDollars(maxBalanceCents))
+	}
+	log.Printf("[bank] max account balance: %s", maxBalanceLabel())
+	if cents, err := DollarsToCents(getEnv("MAX_STARTING_BALANCE", MaxStartingBalance)); err == nil && cents > 0 {
+		MaxStartingBalance = CentsToDollars(cents)
+	} else {
+		log.Printf("[bank] invalid MAX_STARTING_BALANCE — using %s", MaxStartingBalance)
+	}
+	if cents, err := DollarsToCents(getEnv("STARTING_BALANCE", StartingBalance)); err == nil && validStartingBalance(cents) {
+		StartingBalance = CentsToDollars(cents)
+	} else {
+		log.Printf("[bank] invalid STARTING_BALANCE — using %s", StartingBalance)
+	}
+	log.Printf("[bank] starting balance for new accounts: %s (max %s)", StartingBalance, MaxStartingBalance)
+
+	if d, err := time.ParseDuration(getEnv("DOCUMENT_TIMEOUT", "60s")); err == nil && d > 0 {
+		documentTimeout = d
+	} else {
+		log.Printf("[bank] invalid DOCUMENT_TIMEOUT — using %s", documentTimeout)
+	}
+	log.Printf("[bank] payout schedule: win=%s blackjack=%s push-returns-stake=%v surrender=%s insurance=%s",
+		schedule.Win, schedule.Blackjack, schedule.PushReturnsStake, schedule.Surrender, schedule.Insurance)
+	if ttl, err := time.ParseDuration(getEnv("HOLD_TTL", "5m")); err == nil && ttl > 0 {
+		holdTTL = ttl
+	} else {
+		log.Printf("[bank] invalid HOLD_TTL — using %s", holdTTL)
 	}
 
 	// ── Redis (optional — balance pub/sub) ────────────────────────────────────
@@ -53,23 +104,56 @@ func main() {
 		Addr: redisHost + ":" + redisPort,
 	})
 	log.Printf("[bank] Redis configured at %s:%s", redisHost, redisPort)
+	go watchRedis(rdb, 5*time.Second)
 
-	// ── Routes ────────────────────────────────────────────────────────────────
+	// ── Liveness / readiness ──────────────────────────────────────────────────
+	// Listen before the database is up so /health can answer while NewDB is
+	// still retrying; /ready stays 503 until readyDB is set below.
 	mux := http.NewServeMux()
+	mux.HandleFunc("/health",        healthHandler())
+	mux.HandleFunc("/ready",         readyHandler())
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- http.ListenAndServe(":"+port, mux) }()
+	log.Printf("[bank] listening on :%s", port)
 
-	mux.HandleFunc("/health",        healthHandler(db))
+	// ── Database ──────────────────────────────────────────────────────────────
+	db, err := NewDB(dbHost, dbPort, dbName, dbUser, dbPass)
+	if err != nil {
+		log.Fatalf("[bank] database: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		log.Fatalf("[bank] migrate: %v", err)
+	}
+	if err := db.SeedDemoPlayer(); err != nil {
+		log.Fatalf("[bank] seed: %v", err)
+	}
+
+	// Return funds from holds that were never committed or released
+	go sweepExpiredHolds(db, rdb, 30*time.Second)
+
+	// ── Routes ────────────────────────────────────────────────────────────────
+	mux.HandleFunc("/rules",         rulesHandler())
 	mux.HandleFunc("/account",       accountHandler(db))
 	mux.HandleFunc("/balance",       balanceHandler(db))
 	mux.HandleFunc("/transactions",  transactionsHandler(db))
+	mux.HandleFunc("/summary",       summaryHandler(db))
 	mux.HandleFunc("/bet",           betHandler(db, rdb))
+	mux.HandleFunc("/bet/cancel",    betCancelHandler(db, rdb))
 	mux.HandleFunc("/payout",        payoutHandler(db, rdb))
+	mux.HandleFunc("/open-bets",     openBetsHandler(db))
+	mux.HandleFunc("/hold",          holdHandler(db, rdb))
+	mux.HandleFunc("/hold/",         holdActionHandler(db, rdb))
 	mux.HandleFunc("/deposit",       depositHandler(db, rdb))
 	mux.HandleFunc("/withdraw",      withdrawHandler(db, rdb))
+	mux.HandleFunc("/cashout",       cashoutHandler(db, rdb))
+	mux.HandleFunc("/transfer",      transferHandler(db, rdb))
+	mux.HandleFunc("/self-exclude",  selfExcludeHandler(db))
 	mux.HandleFunc("/export",        exportHandler(db))
+	mux.HandleFunc("/statement/verify", statementVerifyHandler(db))
 	mux.HandleFunc("/dev/reset",     devResetHandler(db))
 
-	log.Printf("[bank] listening on :%s", port)
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
-		log.Fatalf("[bank] server: %v", err)
-	}
+	readyDB.Store(db)
+	log.Printf("[bank] ready")
+	log.Fatalf("[bank] server: %v", <-serveErr)
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ── Observability ────────────────────────────────────────────────────────────
+// The Go↔COBOL hop is the point of this service, so every COBOL run is
+// reported to the observability service as bank-service → "cobol", with the
+// program in the path and the exec latency. Reports are fire and forget —
+// a bet never waits on the dashboard.
+
+// observabilityURL is set at startup from OBSERVABILITY_URL; empty turns
+// reporting off.
+var observabilityURL = ""
+
+var observabilityClient = &http.Client{Timeout: 2 * time.Second}
+
+// reportCOBOL reports one COBOL run. The status is 200 on success, 504 when
+// it timed out or never got a slot, and 500 for any other failure.
+func reportCOBOL(program string, latency time.Duration, err error) {
+	if observabilityURL == "" {
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case errors.Is(err, errCOBOLTimeout):
+		status = http.StatusGatewayTimeout
+	case err != nil:
+		status = http.StatusInternalServerError
+	}
+	go func() {
+		body, _ := json.Marshal(map[string]any{
+			"caller":      "bank-service",
+			"callee":      "cobol",
+			"method":      "POST",
+			"path":        "/" + program,
+			"status_code": status,
+			"latency_ms":  latency.Milliseconds(),
+			"protocol":    "exec",
+		})
+		resp, err := observabilityClient.Post(observabilityURL+"/event", "application/json", bytes.NewReader(body))
+		if err != nil {
+			logf("warn", nil, "[bank] observability report: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
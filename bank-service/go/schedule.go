@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ── Payout schedule ──────────────────────────────────────────────────────────
+// House rules are a data decision, not COBOL source. The schedule is loaded
+// once at startup and the relevant ratio is handed to CALC-PAYOUT per call,
+// so the COBOL program stays a pure arithmetic engine.
+
+// Ratio is an integer fraction — COBOL works in integer cents, so multipliers
+// are never floats. "3:2" is Ratio{3, 2}.
+type Ratio struct {
+	Num int64 `json:"num"`
+	Den int64 `json:"den"`
+}
+
+func (r Ratio) String() string {
+	return fmt.Sprintf("%d:%d", r.Num, r.Den)
+}
+
+// ParseRatio parses "N:D" (e.g. "3:2", "6:5", "1:2").
+func ParseRatio(s string) (Ratio, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+	if len(parts) != 2 {
+		return Ratio{}, fmt.Errorf("ratio %q must be N:D", s)
+	}
+	num, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil {
+		return Ratio{}, fmt.Errorf("ratio %q: invalid numerator", s)
+	}
+	den, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		return Ratio{}, fmt.Errorf("ratio %q: invalid denominator", s)
+	}
+	if num <= 0 || den <= 0 {
+		return Ratio{}, fmt.Errorf("ratio %q must be positive", s)
+	}
+	return Ratio{Num: num, Den: den}, nil
+}
+
+// PayoutSchedule holds the house payout rules.
+//   - Win and Blackjack are profit ratios (stake is always returned on top).
+//   - Surrender is the fraction of the stake returned.
+//   - Insurance is the profit ratio on a won insurance side bet.
+//   - PushReturnsStake=false makes a push a house win (some variants).
+type PayoutSchedule struct {
+	Win              Ratio `json:"win"`
+	Blackjack        Ratio `json:"blackjack"`
+	PushReturnsStake bool  `json:"pushReturnsStake"`
+	Surrender        Ratio `json:"surrender"`
+	Insurance        Ratio `json:"insurance"`
+}
+
+// DefaultPayoutSchedule is standard casino rules: 1:1 win, 3:2 blackjack,
+// push returns the stake, surrender returns half, insurance pays 2:1.
+var DefaultPayoutSchedule = PayoutSchedule{
+	Win:              Ratio{1, 1},
+	Blackjack:        Ratio{3, 2},
+	PushReturnsStake: true,
+	Surrender:        Ratio{1, 2},
+	Insurance:        Ratio{2, 1},
+}
+
+// payoutSchedule is set at startup from LoadPayoutSchedule.
+var payoutSchedule = DefaultPayoutSchedule
+
+// LoadPayoutSchedule reads the schedule from environment variables:
+//
+//	PAYOUT_WIN        profit ratio on a win        (default 1:1)
+//	PAYOUT_BLACKJACK  profit ratio on a natural    (default 3:2)
+//	PAYOUT_PUSH       "return" or "lose"           (default return)
+//	PAYOUT_SURRENDER  fraction of stake returned   (default 1:2)
+//	PAYOUT_INSURANCE  profit ratio on insurance    (default 2:1)
+func LoadPayoutSchedule() (PayoutSchedule, error) {
+	s := DefaultPayoutSchedule
+	var err error
+	if v := getEnv("PAYOUT_WIN", ""); v != "" {
+		if s.Win, err = ParseRatio(v); err != nil {
+			return s, fmt.Errorf("PAYOUT_WIN: %w", err)
+		}
+	}
+	if v := getEnv("PAYOUT_BLACKJACK", ""); v != "" {
+		if s.Blackjack, err = ParseRatio(v); err != nil {
+			return s, fmt.Errorf("PAYOUT_BLACKJACK: %w", err)
+		}
+	}
+	switch strings.ToLower(getEnv("PAYOUT_PUSH", "return")) {
+	case "return":
+		s.PushReturnsStake = true
+	case "lose":
+		s.PushReturnsStake = false
+	default:
+		return s, fmt.Errorf("PAYOUT_PUSH must be \"return\" or \"lose\"")
+	}
+	if v := getEnv("PAYOUT_SURRENDER", ""); v != "" {
+		if s.Surrender, err = ParseRatio(v); err != nil {
+			return s, fmt.Errorf("PAYOUT_SURRENDER: %w", err)
+		}
+	}
+	if v := getEnv("PAYOUT_INSURANCE", ""); v != "" {
+		if s.Insurance, err = ParseRatio(v); err != nil {
+			return s, fmt.Errorf("PAYOUT_INSURANCE: %w", err)
+		}
+	}
+	return s, s.Validate()
+}
+
+// Validate enforces sane house rules: a natural never pays less than a
+// regular win, and surrender never returns more than the stake.
+func (s PayoutSchedule) Validate() error {
+	// Compare Blackjack >= Win without floats: a/b >= c/d ⇔ a*d >= c*b
+	if s.Blackjack.Num*s.Win.Den < s.Win.Num*s.Blackjack.Den {
+		return fmt.Errorf("blackjack payout %s must be >= win payout %s", s.Blackjack, s.Win)
+	}
+	if s.Surrender.Num > s.Surrender.Den {
+		return fmt.Errorf("surrender fraction %s must not exceed the stake", s.Surrender)
+	}
+	return nil
+}
+
+// payoutResults are the results POST /payout accepts (case-insensitive).
+var payoutResults = []string{"win", "blackjack", "insurance", "push", "surrender", "loss"}
+
+// IsPayoutResult reports whether result is one CALC-PAYOUT can settle.
+func IsPayoutResult(result string) bool {
+	result = strings.ToLower(strings.TrimSpace(result))
+	for _, r := range payoutResults {
+		if r == result {
+			return true
+		}
+	}
+	return false
+}
+
+// RatioFor returns the ratio CALC-PAYOUT needs for a given result.
+func (s PayoutSchedule) RatioFor(result string) Ratio {
+	switch strings.ToUpper(strings.TrimSpace(result)) {
+	case "WIN":
+		return s.Win
+	case "BLACKJACK":
+		return s.Blackjack
+	case "PUSH":
+		if s.PushReturnsStake {
+			return Ratio{1, 1}
+		}
+		return Ratio{0, 1}
+	case "SURRENDER":
+		return s.Surrender
+	case "INSURANCE":
+		return s.Insurance
+	default:
+		return Ratio{0, 1}
+	}
+}
@@ -48,11 +48,27 @@ def evaluate_hand(cards: list[dict]) -> tuple[int, bool]:
     return total, soft
 
 
-def dealer_decision(hand: list[dict]) -> dict:
+# Dealer strategies, keyed by the name game-state sends per table. The value
+# says whether the dealer hits a soft 17 (e.g. A+6).
+#   standard     — stand on all 17s (S17)
+#   hit-soft-17  — hit soft 17 (H17)
+#   spanish      — Spanish 21 dealer: hits soft 17. The short deck is
+#                  deck-service's concern, not the dealer's.
+STRATEGIES = {
+    'standard': False,
+    'hit-soft-17': True,
+    'spanish': True,
+}
+
+# Callers that don't name a strategy get the rule this service always used
+DEFAULT_STRATEGY = 'hit-soft-17'
+
+
+def dealer_decision(hand: list[dict], strategy: str = DEFAULT_STRATEGY) -> dict:
     """
-    Standard casino dealer rules:
+    Casino dealer rules under the given strategy:
     - Stand on hard 17+
-    - Hit on soft 17 (house rule — configurable in production)
+    - Hit on soft 17 only if the strategy says so
     - Hit on 16 and below
     """
     total, is_soft = evaluate_hand(hand)
@@ -73,13 +89,12 @@ def dealer_decision(hand: list[dict]) -> dict:
             "reasoning": f"Dealer hits on {total} (below 17)"
         }
 
-    if total == 17 and is_soft:
-        # Soft 17 — house rules typically say hit
+    if total == 17 and is_soft and STRATEGIES[strategy]:
         return {
             "action": "hit",
             "handValue": total,
             "isSoft": True,
-            "reasoning": "Dealer hits soft 17 (house rule)"
+            "reasoning": f"Dealer hits soft 17 ({strategy})"
         }
 
     return {
@@ -96,7 +111,8 @@ def health():
         "status": "healthy",
         "service": "dealer-ai",
         "language": "Python",
-        "note": "Rule-based now. ML upgrade path: replace dealer_decision(), keep endpoint."
+        "note": "Rule-based now. ML upgrade path: replace dealer_decision(), keep endpoint.",
+        "strategies": sorted(STRATEGIES),
     })
 
 
@@ -114,7 +130,12 @@ def decide():
         return jsonify({"error": "missing 'hand' in request body"}), 400
 
     hand = data['hand']
-    decision = dealer_decision(hand)
+    strategy = data.get('strategy') or DEFAULT_STRATEGY
+    if strategy not in STRATEGIES:
+        return jsonify({"error": f"unknown strategy '{strategy}'",
+                        "strategies": sorted(STRATEGIES)}), 400
+    decision = dealer_decision(hand, strategy)
+    decision["strategy"] = strategy
 
     log.info(f"Decision for hand of {len(hand)} cards: {decision['action']} ({decision['reasoning']})")
 
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 type Card struct {
@@ -14,10 +23,29 @@ type Card struct {
 	Rank string `json:"rank"`
 }
 
+// Shoe modes. A traditional shoe is dealt down across hands; a continuous
+// shuffling machine (CSM) takes every dealt card back after each hand.
+const (
+	ModeShoe = "shoe"
+	ModeCSM  = "csm"
+)
+
+// defaultPenetration is where the cut card sits: the fraction of the shoe
+// dealt before it is reshuffled.
+const defaultPenetration = 0.75
+
 type Shoe struct {
-	Cards     []Card
-	TableID   string
-	DeckCount int
+	Cards       []Card  `json:"cards"`
+	TableID     string  `json:"tableId"`
+	DeckCount   int     `json:"deckCount"`
+	Mode        string  `json:"mode"`
+	Penetration float64 `json:"penetration"`        // cut card position, fraction of the shoe dealt
+	Seed        *int64  `json:"seed,omitempty"`     // set for a deterministic shoe; nil uses the global source
+	Discards    []Card  `json:"discards,omitempty"` // dealt cards handed back; out of play until the next reshuffle
+	BurnCount   int     `json:"burnCount"`          // cards burned off the top after every shuffle
+	HandOpen    bool    `json:"handOpen,omitempty"` // cards dealt since the last end-hand; blocks a forced reshuffle
+
+	rng *rand.Rand // seeded source, owned by the shoe; guarded by shoesMu
 }
 
 var (
@@ -28,17 +56,77 @@ var (
 	ranks = []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
 )
 
-func newShoe(tableID string, deckCount int) *Shoe {
-	cards := make([]Card, 0, 52*deckCount)
-	for d := 0; d < deckCount; d++ {
-		for _, s := range suits {
+// maxBurnCount bounds the burn cards a shoe can be configured with.
+const maxBurnCount = 10
+
+// Shoe sizes POST /shoe accepts; defaultDeckCount is also what a deal
+// against a table with no shoe gets.
+const (
+	defaultDeckCount = 6
+	maxDeckCount     = 8
+)
+
+// newShoe builds and shuffles a shoe. With a seed the shoe gets its own
+// RNG, so the deal order — including every later reshuffle — is the same
+// for that seed across restarts.
+func newShoe(tableID string, deckCount int, mode string, penetration float64, seed *int64, burnCount int) *Shoe {
+	shoe := &Shoe{
+		TableID: tableID, DeckCount: deckCount, Mode: mode,
+		Penetration: penetration, Seed: seed, BurnCount: burnCount,
+	}
+	if seed != nil {
+		shoe.rng = rand.New(rand.NewSource(*seed))
+	}
+	shoe.reshuffle()
+	return shoe
+}
+
+// reshuffle gathers every card back and shuffles a full shoe, keeping the
+// shoe's configuration, then burns BurnCount cards off the top. Burn cards
+// are positions 0..BurnCount-1 of the shuffled order, so under a fixed seed
+// the first card dealt is position BurnCount. Caller must hold shoesMu if
+// the shoe is shared.
+func (s *Shoe) reshuffle() {
+	cards := make([]Card, 0, 52*s.DeckCount)
+	for d := 0; d < s.DeckCount; d++ {
+		for _, suit := range suits {
 			for _, r := range ranks {
-				cards = append(cards, Card{Suit: s, Rank: r})
+				cards = append(cards, Card{Suit: suit, Rank: r})
 			}
 		}
 	}
-	rand.Shuffle(len(cards), func(i, j int) { cards[i], cards[j] = cards[j], cards[i] })
-	return &Shoe{Cards: cards, TableID: tableID, DeckCount: deckCount}
+	swap := func(i, j int) { cards[i], cards[j] = cards[j], cards[i] }
+	if s.rng != nil {
+		s.rng.Shuffle(len(cards), swap)
+	} else {
+		rand.Shuffle(len(cards), swap)
+	}
+	s.Cards = cards[s.BurnCount:]
+	s.Discards = nil
+}
+
+// position is the index in the shuffled order of the next card to deal.
+// Caller must hold shoesMu.
+func (s *Shoe) position() int {
+	return s.totalCards() - len(s.Cards)
+}
+
+// validCard reports whether c is a real card from a standard deck.
+func validCard(c Card) bool {
+	suitOK, rankOK := false, false
+	for _, s := range suits {
+		suitOK = suitOK || c.Suit == s
+	}
+	for _, r := range ranks {
+		rankOK = rankOK || c.Rank == r
+	}
+	return suitOK && rankOK
+}
+
+// cutCardReached reports whether dealing has passed the cut card.
+// Caller must hold shoesMu.
+func (s *Shoe) cutCardReached() bool {
+	return float64(s.position()) >= s.Penetration*float64(s.totalCards())
 }
 
 func getOrCreateShoe(tableID string) *Shoe {
@@ -47,11 +135,47 @@ func getOrCreateShoe(tableID string) *Shoe {
 	if shoe, ok := shoes[tableID]; ok {
 		return shoe
 	}
-	shoe := newShoe(tableID, 6)
+	shoe := newShoe(tableID, defaultDeckCount, ModeShoe, defaultPenetration, nil, 0)
 	shoes[tableID] = shoe
+	persistShoe(shoe)
 	return shoe
 }
 
+// totalCards is the size of a full shoe.
+func (s *Shoe) totalCards() int {
+	return 52 * s.DeckCount
+}
+
+// status reports the shoe for API responses. Penetration is the percentage
+// of the shoe already out — burn cards included, as the cut card sees it.
+// Caller must hold shoesMu.
+func (s *Shoe) status() map[string]interface{} {
+	total := s.totalCards()
+	dealt := s.position() - s.BurnCount
+	penetration := 0.0
+	if total > 0 {
+		penetration = math.Round(float64(s.position())*1000/float64(total)) / 10
+	}
+	status := map[string]interface{}{
+		"tableId":            s.TableID,
+		"totalCards":         total,
+		"remainingCards":     len(s.Cards),
+		"dealtCards":         dealt,
+		"burned":             s.BurnCount,
+		"penetration":        penetration,
+		"penetrationReached": s.cutCardReached(),
+		"discardCount":       len(s.Discards),
+		"reshuffleAt":        math.Round(s.Penetration * 100),
+		"deckCount":          s.DeckCount,
+		"mode":               s.Mode,
+		"handInProgress":     s.HandOpen,
+	}
+	if s.Seed != nil {
+		status["seed"] = *s.Seed
+	}
+	return status
+}
+
 func main() {
 	mux := http.NewServeMux()
 
@@ -60,47 +184,138 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "service": "deck-service", "language": "Go"})
 	})
 
+	// POST /shoe — initialize a table's shoe with a deck count, mode and
+	// cut card penetration. 201 with the new shoe's status; if the table
+	// already has one it's left untouched and returned with 409, so repeated
+	// inits are harmless and callers can tell new from reused. replace
+	// rebuilds an existing shoe from the request instead (200), e.g. to
+	// change a table's deck count.
+	mux.HandleFunc("/shoe", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost {
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			TableID     string  `json:"tableId"`
+			DeckCount   int     `json:"deckCount"`
+			Mode        string  `json:"mode"`
+			Penetration float64 `json:"penetration"`
+			Seed        *int64  `json:"seed"`
+			BurnCount   int     `json:"burnCount"`
+			Replace     bool    `json:"replace"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TableID == "" {
+			http.Error(w, `{"error":"tableId required"}`, http.StatusBadRequest)
+			return
+		}
+		if req.DeckCount == 0 {
+			req.DeckCount = defaultDeckCount
+		}
+		if req.DeckCount < 1 || req.DeckCount > maxDeckCount {
+			http.Error(w, `{"error":"deckCount must be between 1 and 8"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = ModeShoe
+		}
+		if req.Mode != ModeShoe && req.Mode != ModeCSM {
+			http.Error(w, `{"error":"mode must be shoe or csm"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Penetration == 0 {
+			req.Penetration = defaultPenetration
+		}
+		if req.Penetration < 0.5 || req.Penetration > 0.9 {
+			http.Error(w, `{"error":"penetration must be between 0.5 and 0.9"}`, http.StatusBadRequest)
+			return
+		}
+		if req.BurnCount < 0 || req.BurnCount > maxBurnCount {
+			http.Error(w, `{"error":"burnCount must be between 0 and 10"}`, http.StatusBadRequest)
+			return
+		}
+
+		shoesMu.Lock()
+		shoe, exists := shoes[req.TableID]
+		if !exists || req.Replace {
+			shoe = newShoe(req.TableID, req.DeckCount, req.Mode, req.Penetration, req.Seed, req.BurnCount)
+			shoes[req.TableID] = shoe
+			persistShoe(shoe)
+		}
+		status := shoe.status()
+		shoesMu.Unlock()
+
+		switch {
+		case exists && !req.Replace:
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(status)
+		case exists:
+			log.Printf("[deck-service] shoe replaced for table %s (%d decks, mode=%s, penetration=%.2f, burn=%d)",
+				req.TableID, req.DeckCount, req.Mode, req.Penetration, req.BurnCount)
+			json.NewEncoder(w).Encode(status)
+		default:
+			log.Printf("[deck-service] shoe created for table %s (%d decks, mode=%s, penetration=%.2f, burn=%d)",
+				req.TableID, req.DeckCount, req.Mode, req.Penetration, req.BurnCount)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(status)
+		}
+	})
+
+	// GET  /shoe/{tableId}
 	// POST /shoe/{tableId}/deal
+	// POST /shoe/{tableId}/end-hand
+	// POST /shoe/{tableId}/return
+	// POST /shoe/{tableId}/reshuffle
+	// GET  /shoe/{tableId}/peek?count=  (DECK_DEBUG=1 only)
 	mux.HandleFunc("/shoe/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 
 		// Extract tableId from path
 		path := r.URL.Path // /shoe/{tableId}/deal or /shoe/{tableId}
+		if r.Method == http.MethodGet && len(path) > 6 && !strings.Contains(path[6:], "/") {
+			shoeStatus(w, path[6:])
+			return
+		}
+		if r.Method == http.MethodGet && deckDebug && strings.HasSuffix(path, "/peek") {
+			peekCards(w, r, extractTableID(path))
+			return
+		}
+		if r.Method == http.MethodPost && strings.HasSuffix(path, "/end-hand") {
+			endHand(w, r, extractTableID(path))
+			return
+		}
+		if r.Method == http.MethodPost && strings.HasSuffix(path, "/return") {
+			returnCards(w, r, extractTableID(path))
+			return
+		}
+		if r.Method == http.MethodPost && strings.HasSuffix(path, "/reshuffle") {
+			forceReshuffle(w, r, extractTableID(path))
+			return
+		}
 		if r.Method == http.MethodPost && len(path) > 6 {
 			// POST /shoe/{tableId}/deal
-			var req struct {
-				Count int `json:"count"`
-			}
-			req.Count = 1
-			json.NewDecoder(r.Body).Decode(&req)
-
-			tableID := extractTableID(path)
-			shoe := getOrCreateShoe(tableID)
-
-			shoesMu.Lock()
-			dealt := make([]Card, 0, req.Count)
-			for i := 0; i < req.Count && len(shoe.Cards) > 0; i++ {
-				dealt = append(dealt, shoe.Cards[0])
-				shoe.Cards = shoe.Cards[1:]
-			}
-			remaining := len(shoe.Cards)
-			shoesMu.Unlock()
-
-			log.Printf("[deck-service] dealt %d cards to table %s (%d remaining)", len(dealt), tableID, remaining)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"cards": dealt,
-				"shoeStatus": map[string]interface{}{
-					"tableId":        tableID,
-					"remainingCards": remaining,
-					"deckCount":      shoe.DeckCount,
-				},
-			})
+			dealCards(w, r, extractTableID(path))
 			return
 		}
 		http.NotFound(w, r)
 	})
 
+	// Shoe persistence is optional — without REDIS_URL shoes live in memory only
+	if addr := getEnv("REDIS_URL", ""); addr != "" {
+		if ttl, err := time.ParseDuration(getEnv("SHOE_TTL", "2h")); err == nil && ttl > 0 {
+			shoeTTL = ttl
+		} else {
+			log.Printf("[deck-service] invalid SHOE_TTL — using %s", shoeTTL)
+		}
+		connectRedis(addr)
+		loadShoes()
+	}
+
+	if deckDebug {
+		log.Printf("[deck-service] DECK_DEBUG on — GET /shoe/{tableId}/peek exposes the deal order")
+	}
+
 	port := getEnv("PORT", "3002")
 	log.Printf("🃏 Deck Service (Go) starting on :%s", port)
 	if err := http.ListenAndServe(":"+port, mux); err != nil {
@@ -108,6 +323,344 @@ func main() {
 	}
 }
 
+// dealCards deals count cards (default 1) off the top of the table's shoe,
+// creating a default shoe on first use. Past the cut card, or when the shoe
+// can't cover the request, it reshuffles first.
+func dealCards(w http.ResponseWriter, r *http.Request, tableID string) {
+	var req struct {
+		Count int `json:"count"`
+	}
+	req.Count = 1
+	json.NewDecoder(r.Body).Decode(&req)
+
+	shoe := getOrCreateShoe(tableID)
+
+	shoesMu.Lock()
+	// Cut card: once it's out — or the shoe can't cover the request —
+	// shuffle a fresh shoe before dealing rather than come up short
+	reshuffled := false
+	var shuffleMs int64
+	if shoe.cutCardReached() || len(shoe.Cards) < req.Count {
+		start := time.Now()
+		shoe.reshuffle()
+		shuffleMs = time.Since(start).Milliseconds()
+		reshuffled = true
+	}
+	from := shoe.position()
+	dealt := make([]Card, 0, req.Count)
+	for i := 0; i < req.Count && len(shoe.Cards) > 0; i++ {
+		dealt = append(dealt, shoe.Cards[0])
+		shoe.Cards = shoe.Cards[1:]
+	}
+	shoe.HandOpen = true
+	persistShoe(shoe)
+	status := shoe.status()
+	shoesMu.Unlock()
+
+	if reshuffled {
+		log.Printf("[deck-service] cut card reached — reshuffled shoe for table %s", tableID)
+		reportEvent(r, "/shoe/"+tableID+"/reshuffle/cut-card", shuffleMs)
+	}
+	log.Printf("[deck-service] dealt %d cards to table %s (%v remaining)", len(dealt), tableID, status["remainingCards"])
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cards":      dealt,
+		"shoeStatus": status,
+		"reshuffled": reshuffled,
+		"dealOrder": map[string]interface{}{
+			"burned":   status["burned"],
+			"position": from,
+		},
+	})
+}
+
+// shoeStatus reports a shoe without dealing from it. Unlike deal it never
+// creates a shoe — inspecting an unknown table is a 404.
+func shoeStatus(w http.ResponseWriter, tableID string) {
+	shoesMu.RLock()
+	shoe, ok := shoes[tableID]
+	if !ok {
+		shoesMu.RUnlock()
+		http.Error(w, `{"error":"no shoe for table"}`, http.StatusNotFound)
+		return
+	}
+	status := shoe.status()
+	shoesMu.RUnlock()
+	json.NewEncoder(w).Encode(status)
+}
+
+// returnCards takes dealt cards back onto the discard pile. They stay out of
+// play until the next reshuffle, so the remaining deal order is untouched.
+// The pile can never hold more cards than have left the shoe.
+func returnCards(w http.ResponseWriter, r *http.Request, tableID string) {
+	var req struct {
+		Cards []Card `json:"cards"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Cards) == 0 {
+		http.Error(w, `{"error":"cards required"}`, http.StatusBadRequest)
+		return
+	}
+	for _, c := range req.Cards {
+		if !validCard(c) {
+			http.Error(w, `{"error":"invalid card"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	shoesMu.Lock()
+	shoe, ok := shoes[tableID]
+	if !ok {
+		shoesMu.Unlock()
+		http.Error(w, `{"error":"no shoe for table"}`, http.StatusNotFound)
+		return
+	}
+	out := shoe.position() - shoe.BurnCount
+	if len(shoe.Discards)+len(req.Cards) > out {
+		shoesMu.Unlock()
+		http.Error(w, `{"error":"more cards returned than dealt"}`, http.StatusConflict)
+		return
+	}
+	shoe.Discards = append(shoe.Discards, req.Cards...)
+	persistShoe(shoe)
+	status := shoe.status()
+	shoesMu.Unlock()
+
+	log.Printf("[deck-service] %d cards returned to table %s discards (%v total)", len(req.Cards), tableID, status["discardCount"])
+	json.NewEncoder(w).Encode(status)
+}
+
+// endHand marks a hand boundary. In CSM mode every dealt card goes back into
+// the machine and the shoe is reshuffled to full; in shoe mode the shoe keeps
+// depleting across hands until the cut card comes out, and is reshuffled
+// here so the next hand starts fresh.
+func endHand(w http.ResponseWriter, r *http.Request, tableID string) {
+	shoesMu.Lock()
+	shoe, ok := shoes[tableID]
+	if !ok {
+		shoesMu.Unlock()
+		http.Error(w, `{"error":"no shoe for table"}`, http.StatusNotFound)
+		return
+	}
+	reshuffled := false
+	var shuffleMs int64
+	if shoe.Mode == ModeCSM || shoe.cutCardReached() {
+		start := time.Now()
+		shoe.reshuffle()
+		shuffleMs = time.Since(start).Milliseconds()
+		reshuffled = true
+	}
+	if reshuffled || shoe.HandOpen {
+		shoe.HandOpen = false
+		persistShoe(shoe)
+	}
+	status := shoe.status()
+	shoesMu.Unlock()
+
+	if reshuffled {
+		log.Printf("[deck-service] %v reshuffle for table %s (%v cards)", status["mode"], tableID, status["remainingCards"])
+		reportEvent(r, fmt.Sprintf("/shoe/%s/reshuffle/%v", tableID, status["mode"]), shuffleMs)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reshuffled": reshuffled,
+		"shoeStatus": status,
+	})
+}
+
+// deckDebug enables diagnostic endpoints that expose the deal order. Off,
+// they 404 like any unknown path — never set it in production.
+var deckDebug = getEnv("DECK_DEBUG", "") == "1"
+
+// peekCards shows the next count cards (default 1) without dealing them, for
+// investigating fairness complaints. Read-only: the shoe isn't touched, and
+// a deal that trips the cut card will reshuffle before these come out.
+func peekCards(w http.ResponseWriter, r *http.Request, tableID string) {
+	count := 1
+	if q := r.URL.Query().Get("count"); q != "" {
+		n, err := strconv.Atoi(q)
+		if err != nil || n < 1 {
+			http.Error(w, `{"error":"count must be a positive integer"}`, http.StatusBadRequest)
+			return
+		}
+		count = n
+	}
+
+	shoesMu.RLock()
+	shoe, ok := shoes[tableID]
+	if !ok {
+		shoesMu.RUnlock()
+		http.Error(w, `{"error":"no shoe for table"}`, http.StatusNotFound)
+		return
+	}
+	count = min(count, len(shoe.Cards))
+	next := append([]Card(nil), shoe.Cards[:count]...)
+	from := shoe.position()
+	shoesMu.RUnlock()
+
+	log.Printf("[deck-service] DEBUG peek at %d cards on table %s by %s", len(next), tableID, r.RemoteAddr)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tableId":  tableID,
+		"cards":    next,
+		"position": from,
+	})
+}
+
+// forceReshuffle rebuilds a table's shoe on demand — a new player sits down,
+// counting is suspected — keeping its deck count, mode, penetration and burn.
+// Refused with 409 while a hand is open (cards dealt since the last
+// end-hand), so it can never pull cards out from under a hand in play.
+// Every forced reshuffle is logged with the caller's address for audit.
+func forceReshuffle(w http.ResponseWriter, r *http.Request, tableID string) {
+	shoesMu.Lock()
+	shoe, ok := shoes[tableID]
+	if !ok {
+		shoesMu.Unlock()
+		http.Error(w, `{"error":"no shoe for table"}`, http.StatusNotFound)
+		return
+	}
+	if shoe.HandOpen {
+		shoesMu.Unlock()
+		http.Error(w, `{"error":"hand in progress — end the hand before reshuffling"}`, http.StatusConflict)
+		return
+	}
+	start := time.Now()
+	shoe.reshuffle()
+	shuffleMs := time.Since(start).Milliseconds()
+	persistShoe(shoe)
+	status := shoe.status()
+	shoesMu.Unlock()
+
+	reportEvent(r, "/shoe/"+tableID+"/reshuffle/forced", shuffleMs)
+	log.Printf("[deck-service] AUDIT forced reshuffle for table %s by %s (%v decks, %v cards)",
+		tableID, r.RemoteAddr, status["deckCount"], status["remainingCards"])
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reshuffled":     true,
+		"remainingCards": status["remainingCards"],
+		"shoeStatus":     status,
+	})
+}
+
+// ── Persistence ───────────────────────────────────────────────────────────────
+// With REDIS_URL set, every shoe is written to shoe:{tableId} whenever it
+// changes, and reloaded at startup, so a restart mid-hand keeps dealing from
+// the same shoe. Keys expire after SHOE_TTL without a write, which cleans up
+// abandoned tables. A seeded shoe's remaining cards survive exactly; its RNG
+// restarts from the seed, so only reshuffles after a restart diverge.
+
+var (
+	rdb     *redis.Client
+	shoeTTL = 2 * time.Hour
+)
+
+func shoeKey(tableID string) string {
+	return "shoe:" + tableID
+}
+
+// connectRedis waits for Redis to come up; persistence stays off if it never does.
+func connectRedis(addr string) {
+	for i := 0; i < 10; i++ {
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := client.Ping(ctx).Err()
+		cancel()
+		if err == nil {
+			rdb = client
+			log.Printf("[deck-service] Redis connected at %s — shoes persisted (ttl=%s)", addr, shoeTTL)
+			return
+		}
+		log.Printf("[deck-service] Redis not ready (%d/10), retrying...", i+1)
+		client.Close()
+		time.Sleep(2 * time.Second)
+	}
+	log.Printf("[deck-service] Redis unavailable — shoes are in-memory only")
+}
+
+// persistShoe writes the shoe through to Redis. Caller must hold shoesMu so
+// writes for a table land in the order the shoe changed.
+func persistShoe(s *Shoe) {
+	if rdb == nil {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("[deck-service] persist shoe %s: %v", s.TableID, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := rdb.Set(ctx, shoeKey(s.TableID), data, shoeTTL).Err(); err != nil {
+		log.Printf("[deck-service] persist shoe %s: %v", s.TableID, err)
+	}
+}
+
+// loadShoes restores every persisted shoe into memory at startup.
+func loadShoes() {
+	if rdb == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	shoesMu.Lock()
+	defer shoesMu.Unlock()
+	loaded := 0
+	iter := rdb.Scan(ctx, 0, shoeKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		data, err := rdb.Get(ctx, iter.Val()).Bytes()
+		if err != nil {
+			continue
+		}
+		var shoe Shoe
+		if err := json.Unmarshal(data, &shoe); err != nil || shoe.TableID == "" {
+			log.Printf("[deck-service] skipping unreadable %s: %v", iter.Val(), err)
+			continue
+		}
+		if shoe.Seed != nil {
+			shoe.rng = rand.New(rand.NewSource(*shoe.Seed))
+		}
+		shoes[shoe.TableID] = &shoe
+		loaded++
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("[deck-service] load shoes: %v", err)
+	}
+	log.Printf("[deck-service] restored %d shoes from Redis", loaded)
+}
+
+// ── Observability ─────────────────────────────────────────────────────────────
+// game-state already reports its calls in here. These events cover what
+// deck-service does on its own account — reshuffles — so they show up on
+// the dashboard as deck-service → deck-service, with the path naming the
+// trigger (cut-card, csm, shoe, forced).
+
+var observabilityURL = getEnv("OBSERVABILITY_URL", "http://observability-service:3009")
+
+var observabilityClient = &http.Client{Timeout: 2 * time.Second}
+
+// reportEvent fires a non-blocking event report to the observability
+// service, carrying the triggering request's ID. Fire and forget — never
+// blocks a deal.
+func reportEvent(r *http.Request, path string, latencyMs int64) {
+	rid := r.Header.Get("X-Request-ID")
+	url := observabilityURL + "/event" // read now; the report may outlive a config swap
+	go func() {
+		body, _ := json.Marshal(map[string]interface{}{
+			"caller":      "deck-service",
+			"callee":      "deck-service",
+			"method":      "POST",
+			"path":        path,
+			"status_code": http.StatusOK,
+			"latency_ms":  latencyMs,
+			"protocol":    "http",
+			"request_id":  rid,
+		})
+		resp, err := observabilityClient.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("[observability] report error: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
 func extractTableID(path string) string {
 	// /shoe/{tableId}/deal  or  /shoe/{tableId}
 	parts := []rune(path[6:]) // strip /shoe/
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testShoe installs a fresh shoe for tableID and removes it afterwards.
+func testShoe(t *testing.T, tableID, mode string) {
+	t.Helper()
+	saved := observabilityURL
+	observabilityURL = "http://127.0.0.1:0" // reshuffle events go nowhere
+	shoesMu.Lock()
+	shoes[tableID] = newShoe(tableID, 1, mode, defaultPenetration, nil, 0)
+	shoesMu.Unlock()
+	t.Cleanup(func() {
+		shoesMu.Lock()
+		delete(shoes, tableID)
+		shoesMu.Unlock()
+		observabilityURL = saved
+	})
+}
+
+// playHand deals count cards, ends the hand, and returns the remaining
+// count after the deal and after end-hand.
+func playHand(t *testing.T, tableID string, count int) (afterDeal, afterEnd int) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(fmt.Sprintf(`{"count":%d}`, count))
+	dealCards(rec, httptest.NewRequest(http.MethodPost, "/shoe/"+tableID+"/deal", body), tableID)
+	var deal struct {
+		Cards      []Card         `json:"cards"`
+		ShoeStatus map[string]any `json:"shoeStatus"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &deal); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("deal: status %d, %v", rec.Code, err)
+	}
+	if len(deal.Cards) != count {
+		t.Fatalf("dealt %d cards, want %d", len(deal.Cards), count)
+	}
+
+	rec = httptest.NewRecorder()
+	endHand(rec, httptest.NewRequest(http.MethodPost, "/shoe/"+tableID+"/end-hand", nil), tableID)
+	var end struct {
+		ShoeStatus map[string]any `json:"shoeStatus"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &end); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("end-hand: status %d, %v", rec.Code, err)
+	}
+	return int(deal.ShoeStatus["remainingCards"].(float64)), int(end.ShoeStatus["remainingCards"].(float64))
+}
+
+func TestCSMRemainingResetsEachHand(t *testing.T) {
+	testShoe(t, "test-csm", ModeCSM)
+	for hand := 1; hand <= 20; hand++ {
+		afterDeal, afterEnd := playHand(t, "test-csm", 5)
+		if afterDeal != 52-5 {
+			t.Fatalf("hand %d: %d remaining after deal, want %d", hand, afterDeal, 52-5)
+		}
+		if afterEnd != 52 {
+			t.Fatalf("hand %d: %d remaining after end-hand, want a full 52", hand, afterEnd)
+		}
+	}
+}
+
+func TestShoeRemainingStrictlyDecreases(t *testing.T) {
+	testShoe(t, "test-shoe", ModeShoe)
+	// One deck cuts at 39 cards; seven 5-card hands stay short of it
+	prev := 52
+	for hand := 1; hand <= 7; hand++ {
+		afterDeal, afterEnd := playHand(t, "test-shoe", 5)
+		if afterDeal != prev-5 {
+			t.Fatalf("hand %d: %d remaining after deal, want %d", hand, afterDeal, prev-5)
+		}
+		if afterEnd != afterDeal {
+			t.Fatalf("hand %d: end-hand changed remaining %d → %d before the cut card", hand, afterDeal, afterEnd)
+		}
+		if afterEnd >= prev {
+			t.Fatalf("hand %d: remaining %d did not drop below %d", hand, afterEnd, prev)
+		}
+		prev = afterEnd
+	}
+
+	// Past the cut card the next end-hand starts a fresh shoe
+	if _, afterEnd := playHand(t, "test-shoe", 5); afterEnd != 52 {
+		t.Errorf("after the cut card: %d remaining, want a reshuffled 52", afterEnd)
+	}
+}
@@ -2,14 +2,23 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -21,22 +30,32 @@ type Card struct {
 }
 
 type PlayerState struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Chips       int    `json:"chips"`
-	CurrentBet  int    `json:"currentBet"`
-	Hand        []Card `json:"hand"`
-	HandValue   int    `json:"handValue"`
-	IsSoftHand  bool   `json:"isSoftHand"`
-	Status      string `json:"status"`
-	BankTxID    string `json:"-"` // internal only — never sent to frontend
-	BankTxID2   string `json:"-"` // double-down additional bet transaction
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Chips         int    `json:"chips"`
+	CurrentBet    int    `json:"currentBet"`
+	Hand          []Card `json:"hand"`
+	HandValue     int    `json:"handValue"`
+	IsSoftHand    bool   `json:"isSoftHand"`
+	Status        string `json:"status"`
+	InsuranceBet  int    `json:"insuranceBet,omitempty"`
+	ExcludedUntil string `json:"excludedUntil,omitempty"` // self-exclusion end (RFC3339)
+	BankTxID      string `json:"-"`                       // internal only — never sent to frontend
+	BankTxID2     string `json:"-"`                       // double-down additional bet transaction
+	InsuranceTxID string `json:"-"`                       // insurance side bet transaction
+	InsuranceDone bool   `json:"-"`                       // answered the insurance offer this hand
+
+	// Settled before payout (surrender) — reported with the rest of the hand
+	EarlyResult *SeatResult `json:"-"`
+	// Decisions taken this hand, in order — kept for the hand history
+	Actions []string `json:"-"`
 }
 
 type DealerState struct {
 	Hand        []Card `json:"hand"`
 	HandValue   int    `json:"handValue"`
 	IsRevealed  bool   `json:"isRevealed"`
+	HoleCard    *Card  `json:"-"` // drawn early when the dealer checks for blackjack; never sent
 }
 
 type GameState struct {
@@ -45,10 +64,35 @@ type GameState struct {
 	Players        []PlayerState `json:"players"`
 	Dealer         DealerState   `json:"dealer"`
 	ActivePlayerID *string       `json:"activePlayerId"`
+	DealOrder      []DealStep    `json:"dealOrder,omitempty"` // initial deal sequence, for UI animation
+	BetWindowSecs  int           `json:"betWindowSeconds,omitempty"`
+	BetDeadline    string        `json:"betDeadline,omitempty"` // betting window close (RFC3339), for the countdown
+	TurnExpiresAt  string        `json:"turnExpiresAt,omitempty"` // active decision deadline (RFC3339), for the clock
+	DealerHitsSoft17 bool        `json:"dealerHitsSoft17"`        // table rule: H17 when true, S17 when false
+	DealerStrategy   string      `json:"dealerStrategy"`          // dealer-ai policy: standard, hit-soft-17, spanish
 	MinBet         int           `json:"minBet"`
 	MaxBet         int           `json:"maxBet"`
 	HandledBy      string        `json:"handledBy"`
 	Timestamp      string        `json:"timestamp"`
+	Version        int64         `json:"version"` // bumped on every state change; a gap means a client missed updates
+	LastResult     *HandSummary  `json:"lastResult,omitempty"` // settled result of the last hand; cleared at the next deal
+}
+
+// SeatResult is how one seat's main bet settled. Payout comes from the
+// bank's balances, so it reflects the house payout schedule.
+type SeatResult struct {
+	PlayerID    string `json:"playerId"`
+	Outcome     string `json:"outcome"`   // win, loss, push, blackjack, surrender
+	BetAmount   int    `json:"betAmount"` // total staked, doubles included
+	Payout      int    `json:"payout"`    // returned by the bank, stake included
+	NetChange   int    `json:"netChange"` // payout - betAmount
+	PlayerValue int    `json:"playerValue"`
+}
+
+// HandSummary is the outcome of a finished hand, for the UI's result banner.
+type HandSummary struct {
+	DealerValue int          `json:"dealerValue"`
+	Results     []SeatResult `json:"results"`
 }
 
 type SSEEvent struct {
@@ -67,31 +111,49 @@ type PlayerActionRequest struct {
 type Table struct {
 	mu        sync.RWMutex
 	state     GameState
-	clients   map[chan GameState]struct{}
+	clients   map[chan GameState]int // subscriber → consecutive dropped updates
 	isDemo    bool
 	phase     int // cycling demo phases
-}
+	betRound  int // bumped whenever a betting window opens or closes; stale timers compare against it
+
+	insuranceOpen bool // insurance offered and not yet resolved
 
-func NewTable(tableID string) *Table {
-	playerID := "player-00000000-0000-0000-0000-000000000001"
+	turnSeq   int    // bumped on every turn arm/claim; stale turn timers compare against it
+	turnOwner string // player whose decision the clock is running for
 
+	betTimer  *time.Timer // pending betting-window close; guarded by mu
+	turnTimer *time.Timer // pending decision or insurance clock; guarded by mu
+
+	closed bool // last player left and the table is out of the registry; guarded by mu
+
+	lastActivity int64 // unix nanos of the last broadcast or subscribe; atomic, read by the sweeper
+
+	history     []HandRecord // finished hands, oldest first, at most handHistorySize; guarded by mu
+	handsPlayed int          // hands finished at this table; guarded by mu
+
+	actionMu sync.Mutex      // held while an action or clock expiry plays the hand — one at a time
+	inFlight map[string]bool // players with an action queued or running; guarded by mu
+
+	requestID atomic.Value // string — X-Request-ID of the action driving the table, forwarded upstream
+}
+
+// NewTable creates a demo table seated with playerID, a demo account.
+func NewTable(tableID, playerID string) *Table {
 	// Seed starting balance — idempotent, bank ignores if player already exists
-	startingChips := 1000
-	http.Post(bankServiceURL+"/account",
-		"application/json",
-		bytes.NewReader([]byte(fmt.Sprintf(
-			`{"playerId":"%s","startingBalance":"%d.00"}`, playerID, startingChips,
-		))),
-	)
+	rid := newRequestID()
+	startingChips := demoStartingChips
+	sendUpstream(bankClient, rid, http.MethodPost, bankServiceURL+"/account", []byte(fmt.Sprintf(
+		`{"playerId":"%s","startingBalance":"%d.00"}`, playerID, startingChips,
+	)))
 
 	// Read authoritative balance from bank
-	if balance := callBankBalance(playerID); balance >= 0 {
+	if balance := callBankBalance(rid, playerID); balance >= 0 {
 		startingChips = balance
 	}
 
 	return &Table{
 		isDemo:  true,
-		clients: make(map[chan GameState]struct{}),
+		clients: make(map[chan GameState]int),
 		state: GameState{
 			TableID: tableID,
 			Phase:   "waiting",
@@ -108,20 +170,28 @@ func NewTable(tableID string) *Table {
 				Hand:       []Card{},
 				IsRevealed: false,
 			},
-			MinBet:    10,
-			MaxBet:    500,
-			HandledBy: hostname(),
-			Timestamp: now(),
+			MinBet:           defaultMinBet,
+			MaxBet:           defaultMaxBet,
+			DealerHitsSoft17: defaultDealerHitsSoft17,
+			DealerStrategy:   strategyFor(defaultDealerHitsSoft17),
+			HandledBy:        hostname(),
+			Timestamp:        now(),
 		},
 	}
 }
 
+// Table betting limits when the creator doesn't set them.
+const (
+	defaultMinBet = 10
+	defaultMaxBet = 500
+)
+
 // NewPlayerTable creates an event-driven table for a real authenticated player.
 // Bank calls are made BEFORE this is called — do not hold the registry lock here.
 func NewPlayerTable(tableID, playerID, playerName string, startingChips int) *Table {
 	return &Table{
 		isDemo:  false,
-		clients: make(map[chan GameState]struct{}),
+		clients: make(map[chan GameState]int),
 		state: GameState{
 			TableID: tableID,
 			Phase:   "waiting",
@@ -132,52 +202,319 @@ func NewPlayerTable(tableID, playerID, playerName string, startingChips int) *Ta
 				Hand:   []Card{},
 				Status: "waiting",
 			}},
-			Dealer:    DealerState{Hand: []Card{}, IsRevealed: false},
-			MinBet:    10,
-			MaxBet:    500,
-			HandledBy: hostname(),
-			Timestamp: now(),
+			Dealer:           DealerState{Hand: []Card{}, IsRevealed: false},
+			MinBet:           defaultMinBet,
+			MaxBet:           defaultMaxBet,
+			BetWindowSecs:    defaultBetWindowSecs,
+			DealerHitsSoft17: defaultDealerHitsSoft17,
+			DealerStrategy:   strategyFor(defaultDealerHitsSoft17),
+			HandledBy:        hostname(),
+			Timestamp:        now(),
 		},
 	}
 }
 
-func (t *Table) Subscribe() chan GameState {
+// maxSubscribers caps SSE streams per table. Each one holds a 16-deep
+// buffer of full snapshots, so an unbounded burst of connections could
+// exhaust memory.
+var maxSubscribers = getEnvInt("SSE_MAX_SUBSCRIBERS", 50)
+
+var errTooManySubscribers = errors.New("too many subscribers")
+
+// Subscribe registers an SSE stream on the table. It fails with
+// errTooManySubscribers once the table has maxSubscribers streams open.
+func (t *Table) Subscribe() (chan GameState, error) {
+	t.mu.Lock()
+	if len(t.clients) >= maxSubscribers {
+		t.mu.Unlock()
+		return nil, errTooManySubscribers
+	}
 	ch := make(chan GameState, 16)
+	t.clients[ch] = 0
+	t.mu.Unlock()
+	t.touch()
+	return ch, nil
+}
+
+// subscriberCount reports how many SSE streams are open on the table.
+func (t *Table) subscriberCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.clients)
+}
+
+// RequestID returns the correlation ID for the table's current work: the
+// last accepted player action, or the current hand on a demo table. Timer-
+// driven phases inherit it. Safe to call with t.mu held.
+func (t *Table) RequestID() string {
+	rid, _ := t.requestID.Load().(string)
+	return rid
+}
+
+func (t *Table) setRequestID(rid string) {
+	t.requestID.Store(rid)
+}
+
+// touch records activity on the table so the sweeper leaves it alone.
+func (t *Table) touch() {
+	atomic.StoreInt64(&t.lastActivity, time.Now().UnixNano())
+}
+
+// idleFor reports how long the table has gone without activity.
+func (t *Table) idleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&t.lastActivity)))
+}
+
+// beginAction marks an action from playerID as in flight. False means one
+// is already queued or running — the caller rejects the new one, so a
+// double-clicked "hit" draws one card, not two.
+func (t *Table) beginAction(playerID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inFlight[playerID] {
+		return false
+	}
+	if t.inFlight == nil {
+		t.inFlight = make(map[string]bool)
+	}
+	t.inFlight[playerID] = true
+	return true
+}
+
+// endAction clears playerID's in-flight mark.
+func (t *Table) endAction(playerID string) {
 	t.mu.Lock()
-	t.clients[ch] = struct{}{}
+	delete(t.inFlight, playerID)
 	t.mu.Unlock()
-	return ch
 }
 
+// serialized runs fn holding the action lock. Hand logic reads state, calls
+// out, and writes it back, so two of these interleaving would lose updates.
+// Everything that plays the hand from outside an action — the turn clock,
+// the insurance clock, the betting window — goes through here too.
+func (t *Table) serialized(fn func()) {
+	t.actionMu.Lock()
+	defer t.actionMu.Unlock()
+	fn()
+}
+
+// Unsubscribe removes and closes a subscriber channel. Safe to call twice —
+// shutdown closes every stream while each handler still defers its own.
 func (t *Table) Unsubscribe(ch chan GameState) {
 	t.mu.Lock()
+	_, ok := t.clients[ch]
 	delete(t.clients, ch)
 	t.mu.Unlock()
-	close(ch)
+	if ok {
+		close(ch)
+	}
 }
 
-func (t *Table) Broadcast(state GameState) {
+// closeSubscribers ends every SSE stream on the table.
+func (t *Table) closeSubscribers() {
 	t.mu.RLock()
-	defer t.mu.RUnlock()
+	chs := make([]chan GameState, 0, len(t.clients))
 	for ch := range t.clients {
+		chs = append(chs, ch)
+	}
+	t.mu.RUnlock()
+	for _, ch := range chs {
+		t.Unsubscribe(ch)
+	}
+}
+
+// hasOpenBets reports whether any seat holds an unsettled bank transaction.
+func (t *Table) hasOpenBets() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, p := range t.state.Players {
+		if p.BankTxID != "" || p.BankTxID2 != "" || p.InsuranceTxID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ── Hand History ──────────────────────────────────────────────────────────────
+// Each table keeps its last HAND_HISTORY finished hands in memory: the cards,
+// each seat's decisions, and how it settled. This is gameplay — the money
+// side is the bank's transaction log. History goes with the table.
+
+var handHistorySize = getEnvInt("HAND_HISTORY", 50)
+
+// HandRecord is one finished hand as it was played.
+type HandRecord struct {
+	Hand        int          `json:"hand"` // 1-based count of hands at this table
+	CompletedAt string       `json:"completedAt"`
+	DealerHand  []Card       `json:"dealerHand"`
+	DealerValue int          `json:"dealerValue"`
+	Seats       []SeatRecord `json:"seats"`
+}
+
+// SeatRecord is one seat's part in a hand.
+type SeatRecord struct {
+	SeatResult
+	Cards   []Card   `json:"cards"`
+	Actions []string `json:"actions"`
+}
+
+// recordHandLocked appends the finished hand in s to the table history.
+// Caller must hold t.mu.
+func (t *Table) recordHandLocked(s *GameState, summary *HandSummary) {
+	t.handsPlayed++
+	rec := HandRecord{
+		Hand:        t.handsPlayed,
+		CompletedAt: now(),
+		DealerHand:  append([]Card{}, s.Dealer.Hand...),
+		DealerValue: summary.DealerValue,
+		Seats:       make([]SeatRecord, 0, len(summary.Results)),
+	}
+	for _, r := range summary.Results {
+		seat := SeatRecord{SeatResult: r, Cards: []Card{}, Actions: []string{}}
+		if i := seatIndex(*s, r.PlayerID); i >= 0 {
+			seat.Cards = append(seat.Cards, s.Players[i].Hand...)
+			seat.Actions = append(seat.Actions, s.Players[i].Actions...)
+		}
+		rec.Seats = append(rec.Seats, seat)
+	}
+	t.history = append(t.history, rec)
+	if over := len(t.history) - handHistorySize; over > 0 {
+		t.history = append([]HandRecord(nil), t.history[over:]...)
+	}
+}
+
+// History returns up to limit of the most recent hands, newest first.
+// limit <= 0 means all that are kept.
+func (t *Table) History(limit int) []HandRecord {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	n := len(t.history)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	out := make([]HandRecord, 0, n)
+	for i := len(t.history) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, t.history[i])
+	}
+	return out
+}
+
+// noteAction records a decision on playerID's seat. Call inside Update.
+func noteAction(s *GameState, playerID, action string) {
+	if i := seatIndex(*s, playerID); i >= 0 {
+		s.Players[i].Actions = append(s.Players[i].Actions, action)
+	}
+}
+
+// ── Broadcast size guard ──────────────────────────────────────────────────────
+// Sanity bounds, not table limits: a legitimate hand never gets near them.
+// They exist to catch a runaway bug (e.g. a dealer that never stops drawing)
+// before it floods every connected client.
+
+var (
+	maxBroadcastPlayers = getEnvInt("SSE_MAX_PLAYERS", 8)
+	maxBroadcastCards   = getEnvInt("SSE_MAX_CARDS_PER_HAND", 16)
+	maxSSEPayloadBytes  = getEnvInt("SSE_MAX_PAYLOAD_BYTES", 32*1024)
+)
+
+// guardState returns a copy of state trimmed to the sanity bounds, logging
+// whenever it has to trim. The table's own state is never modified.
+func guardState(state GameState) GameState {
+	if len(state.Players) > maxBroadcastPlayers {
+		logf("warn", logFields{"tableId": state.TableID}, "[guard] %d players exceeds bound %d — truncating broadcast",
+			len(state.Players), maxBroadcastPlayers)
+		state.Players = state.Players[:maxBroadcastPlayers]
+	}
+	trimmed := false
+	players := make([]PlayerState, len(state.Players))
+	copy(players, state.Players)
+	for i := range players {
+		if len(players[i].Hand) > maxBroadcastCards {
+			logf("warn", logFields{"tableId": state.TableID, "playerId": players[i].ID}, "[guard] hand has %d cards — truncating broadcast",
+				len(players[i].Hand))
+			players[i].Hand = players[i].Hand[:maxBroadcastCards]
+			trimmed = true
+		}
+	}
+	if trimmed {
+		state.Players = players
+	}
+	if len(state.Dealer.Hand) > maxBroadcastCards {
+		logf("warn", logFields{"tableId": state.TableID}, "[guard] dealer hand has %d cards — truncating broadcast",
+			len(state.Dealer.Hand))
+		state.Dealer.Hand = state.Dealer.Hand[:maxBroadcastCards]
+	}
+	return state
+}
+
+// maxSSEDrops is how many updates in a row a subscriber may miss before it
+// is evicted. A client that far behind is showing a stale table; closing
+// its stream makes it reconnect and start again from a fresh snapshot.
+var maxSSEDrops = getEnvInt("SSE_MAX_DROPS", 8)
+
+// Broadcast fans the state out to subscribers. Every state change — SetState
+// or a locked update — ends here, so it also marks the table active. A full
+// channel counts as a drop; maxSSEDrops in a row evicts the subscriber.
+func (t *Table) Broadcast(state GameState) {
+	t.touch()
+	state = guardState(state)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ch, drops := range t.clients {
 		select {
 		case ch <- state:
+			t.clients[ch] = 0
 		default:
+			drops++
+			if drops < maxSSEDrops {
+				t.clients[ch] = drops
+				continue
+			}
+			delete(t.clients, ch)
+			close(ch)
+			logf("warn", logFields{"tableId": state.TableID}, "[sse] evicted slow subscriber after %d dropped updates", drops)
 		}
 	}
 }
 
+// Update applies fn to the table state under the write lock, stamps it and
+// bumps its version, and broadcasts the result once. fn must not block or call out — make service
+// calls first and apply their results inside fn, re-finding seats by ID since
+// they may have moved. Returns the new state.
+func (t *Table) Update(fn func(s *GameState)) GameState {
+	t.mu.Lock()
+	fn(&t.state)
+	t.state.HandledBy = hostname()
+	t.state.Timestamp = now()
+	t.state.Version++
+	snapshot := t.state
+	t.mu.Unlock()
+	t.Broadcast(snapshot)
+	return snapshot
+}
+
+// SetState replaces the whole state. Writing back a GetState snapshot is
+// unsafe: anything that changed since it was read (a bet landing, another
+// seat's move) is silently overwritten. Use Update for read-modify-write.
 func (t *Table) SetState(state GameState) {
 	t.mu.Lock()
+	state.Version = t.state.Version + 1
 	t.state = state
 	t.mu.Unlock()
 	t.Broadcast(state)
 }
 
+// GetState returns a snapshot. Players is copied so callers can work on
+// seats locally without writing through to the table.
 func (t *Table) GetState() GameState {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.state
+	s := t.state
+	if t.state.Players != nil {
+		s.Players = make([]PlayerState, len(t.state.Players))
+		copy(s.Players, t.state.Players)
+	}
+	return s
 }
 
 // ── Table Registry ─────────────────────────────────────────────────────────────
@@ -185,10 +522,26 @@ func (t *Table) GetState() GameState {
 type Registry struct {
 	mu     sync.RWMutex
 	tables map[string]*Table
+	recent map[string][]TableRecord // playerID → recently closed tables, newest first
+}
+
+// TableRecord summarizes a table a player sits (or sat) at — the lobby's
+// "resume your game" entry point.
+type TableRecord struct {
+	TableID  string `json:"tableId"`
+	Phase    string `json:"phase"`
+	Chips    int    `json:"chips"`
+	ClosedAt string `json:"closedAt,omitempty"`
 }
 
+// maxRecentTables bounds the per-player closed-table history.
+const maxRecentTables = 10
+
 func NewRegistry() *Registry {
-	return &Registry{tables: make(map[string]*Table)}
+	return &Registry{
+		tables: make(map[string]*Table),
+		recent: make(map[string][]TableRecord),
+	}
 }
 
 func (r *Registry) Get(id string) (*Table, bool) {
@@ -210,11 +563,29 @@ func (r *Registry) GetOrCreate(id string) *Table {
 	if len(id) > 13 && id[:13] == "player-table-" {
 		return nil
 	}
-	t := NewTable(id)
+	t := NewTable(id, demoPlayerID(1))
 	r.tables[id] = t
 	return t
 }
 
+// Count returns the number of live tables, demo included.
+func (r *Registry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tables)
+}
+
+// SSEConnections totals open SSE streams across every table.
+func (r *Registry) SSEConnections() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := 0
+	for _, t := range r.tables {
+		n += t.subscriberCount()
+	}
+	return n
+}
+
 func (r *Registry) List() []GameState {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -225,9 +596,229 @@ func (r *Registry) List() []GameState {
 	return states
 }
 
-// CreatePlayerTable creates or refreshes a player-owned table.
-// Bank HTTP calls happen outside the registry lock to avoid blocking SSE connections.
-func (r *Registry) CreatePlayerTable(playerID, playerName string) *Table {
+// Remove drops a table from the registry and records it in the recent-table
+// history of every player seated at it. Callers settle open bets first.
+func (r *Registry) Remove(tableID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tables[tableID]
+	if !ok {
+		return
+	}
+	delete(r.tables, tableID)
+	s := t.GetState()
+	for _, p := range s.Players {
+		r.rememberLocked(p.ID, TableRecord{TableID: tableID, Phase: s.Phase, Chips: p.Chips, ClosedAt: now()})
+	}
+}
+
+// rememberLocked adds rec to playerID's recent-table history. Caller must
+// hold r.mu.
+func (r *Registry) rememberLocked(playerID string, rec TableRecord) {
+	hist := append([]TableRecord{rec}, r.recent[playerID]...)
+	if len(hist) > maxRecentTables {
+		hist = hist[:maxRecentTables]
+	}
+	r.recent[playerID] = hist
+}
+
+// ── Idle Table Sweeper ────────────────────────────────────────────────────────
+// Player tables otherwise live forever. A table with no SSE subscribers and
+// no activity for TABLE_IDLE_MINUTES is retired: open bets are refunded as
+// pushes, pending timers are invalidated, and the table is removed. The demo
+// table is never swept.
+
+var tableIdleTimeout = time.Duration(getEnvInt("TABLE_IDLE_MINUTES", 30)) * time.Minute
+
+// sweepIdleTables runs forever, retiring idle tables every interval.
+func (r *Registry) sweepIdleTables(interval time.Duration) {
+	for range time.Tick(interval) {
+		r.mu.RLock()
+		idle := []*Table{}
+		for _, t := range r.tables {
+			if t.isDemo || t.idleFor() < tableIdleTimeout {
+				continue
+			}
+			t.mu.RLock()
+			subscribers := len(t.clients)
+			t.mu.RUnlock()
+			if subscribers == 0 {
+				idle = append(idle, t)
+			}
+		}
+		r.mu.RUnlock()
+
+		// Bank calls happen outside the registry lock
+		for _, t := range idle {
+			tableID := t.GetState().TableID
+			refundOpenBets(t)
+			r.Remove(tableID)
+			logf("info", logFields{"tableId": tableID}, "[game-state] swept idle table (idle %s)", t.idleFor().Round(time.Second))
+		}
+	}
+}
+
+// refundOpenBets returns every unsettled stake at the table as a push and
+// retires its timers, so nothing fires against the table after it's gone.
+func refundOpenBets(t *Table) {
+	t.mu.Lock()
+	t.retireTimersLocked()
+	t.insuranceOpen = false
+	var txIDs []string
+	for i := range t.state.Players {
+		p := &t.state.Players[i]
+		for _, txID := range []string{p.BankTxID, p.BankTxID2, p.InsuranceTxID} {
+			if txID != "" {
+				txIDs = append(txIDs, txID)
+			}
+		}
+		p.BankTxID, p.BankTxID2, p.InsuranceTxID = "", "", ""
+	}
+	t.mu.Unlock()
+
+	for _, txID := range txIDs {
+		if callBankPayout(t.RequestID(), txID, "push") < 0 {
+			logf("error", logFields{"requestId": t.RequestID(), "txId": txID}, "[bank] refund failed — bet stays open at the bank")
+		}
+	}
+}
+
+// ── Graceful Shutdown ─────────────────────────────────────────────────────────
+// On SIGTERM/SIGINT new bets are refused and the demo loop stops after its
+// current hand. In-flight hands get SHUTDOWN_GRACE_SECONDS to settle on their
+// own; whatever is still open after that is refunded as a push, so no bank
+// transaction is left pending. Only then are SSE streams closed and the
+// server shut down. The default fits inside Docker's 10s stop timeout.
+
+var (
+	shutdownGrace = time.Duration(getEnvInt("SHUTDOWN_GRACE_SECONDS", 8)) * time.Second
+	draining      int32 // atomic: 1 once shutdown has begun
+)
+
+// tablesSnapshot returns the registered tables without holding the lock.
+func (r *Registry) tablesSnapshot() []*Table {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tables := make([]*Table, 0, len(r.tables))
+	for _, t := range r.tables {
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+// settleForShutdown waits until the demo loop has exited and no table holds
+// an open bet, or the grace window runs out, then refunds what remains.
+func (r *Registry) settleForShutdown(grace time.Duration, demoDone <-chan struct{}) {
+	deadline := time.After(grace)
+	settled := func() bool {
+		select {
+		case <-demoDone:
+		default:
+			return false
+		}
+		for _, t := range r.tablesSnapshot() {
+			if t.hasOpenBets() {
+				return false
+			}
+		}
+		return true
+	}
+	poll := time.NewTicker(250 * time.Millisecond)
+	defer poll.Stop()
+	for !settled() {
+		select {
+		case <-poll.C:
+		case <-deadline:
+			for _, t := range r.tablesSnapshot() {
+				if t.hasOpenBets() {
+					logf("info", logFields{"tableId": t.GetState().TableID}, "[game-state] shutdown: refunding open bets")
+					refundOpenBets(t)
+				}
+			}
+			return
+		}
+	}
+}
+
+// closeSubscribers ends every SSE stream so http.Server.Shutdown can drain.
+func (r *Registry) closeSubscribers() {
+	for _, t := range r.tablesSnapshot() {
+		t.closeSubscribers()
+	}
+}
+
+// ── Orphaned Bet Reconciliation ───────────────────────────────────────────────
+// A crash between callBankBet and its payout leaves the stake debited with no
+// hand left to settle it. game-state owns this timeout, not the bank: only
+// game-state knows whether a hand is still live. When a returning player opens
+// a table, their open bets older than ORPHAN_BET_MINUTES that no live table
+// holds are refunded as pushes.
+
+var orphanBetAge = time.Duration(getEnvInt("ORPHAN_BET_MINUTES", 10)) * time.Minute
+
+// liveTxIDs returns every bank transaction a live table is still holding.
+func (r *Registry) liveTxIDs() map[string]bool {
+	live := map[string]bool{}
+	for _, t := range r.tablesSnapshot() {
+		t.mu.RLock()
+		for _, p := range t.state.Players {
+			for _, txID := range []string{p.BankTxID, p.BankTxID2, p.InsuranceTxID} {
+				if txID != "" {
+					live[txID] = true
+				}
+			}
+		}
+		t.mu.RUnlock()
+	}
+	return live
+}
+
+// reconcileOrphanedBets refunds the player's stale open bets.
+func (r *Registry) reconcileOrphanedBets(rid, playerID string) {
+	bets := callBankOpenBets(rid, playerID)
+	if len(bets) == 0 {
+		return
+	}
+	live := r.liveTxIDs()
+	for _, b := range bets {
+		if live[b.TransactionID] || time.Duration(b.AgeSeconds)*time.Second < orphanBetAge {
+			continue
+		}
+		if callBankPayout(rid, b.TransactionID, "push") < 0 {
+			logf("error", logFields{"requestId": rid, "playerId": playerID, "txId": b.TransactionID}, "[bank] orphaned bet refund failed")
+			continue
+		}
+		logf("info", logFields{"requestId": rid, "playerId": playerID, "txId": b.TransactionID},
+			"[game-state] refunded orphaned bet amount=%s age=%ds", b.Amount, b.AgeSeconds)
+	}
+}
+
+// PlayerTables returns the live tables a player is seated at and their
+// recently closed ones. The demo table is never included.
+func (r *Registry) PlayerTables(playerID string) ([]TableRecord, []TableRecord) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	active := []TableRecord{}
+	for id, t := range r.tables {
+		if t.isDemo {
+			continue
+		}
+		s := t.GetState()
+		for _, p := range s.Players {
+			if p.ID == playerID {
+				active = append(active, TableRecord{TableID: id, Phase: s.Phase, Chips: p.Chips})
+				break
+			}
+		}
+	}
+	recent := append([]TableRecord{}, r.recent[playerID]...)
+	return active, recent
+}
+
+// CreatePlayerTable creates or refreshes a player-owned table, reporting
+// whether the table is new. Bank HTTP calls happen outside the registry lock
+// to avoid blocking SSE connections.
+func (r *Registry) CreatePlayerTable(rid, playerID, playerName string, startingBalance int) (*Table, bool) {
 	tableID := "player-table-" + playerID
 
 	// Check if table already exists (read lock only)
@@ -237,257 +828,553 @@ func (r *Registry) CreatePlayerTable(playerID, playerName string) *Table {
 
 	if ok {
 		// Refresh balance outside any lock
-		if balance := callBankBalance(playerID); balance >= 0 {
+		if balance := callBankBalance(rid, playerID); balance >= 0 {
 			existing.mu.Lock()
-			if len(existing.state.Players) > 0 {
-				existing.state.Players[0].Chips = balance
-				existing.state.Players[0].Name  = playerName
+			if i := seatIndex(existing.state, playerID); i >= 0 {
+				existing.state.Players[i].Chips = balance
+				existing.state.Players[i].Name = playerName
 			}
 			existing.mu.Unlock()
 		}
-		return existing
+		return existing, false
 	}
 
 	// New table — do bank calls before taking the registry lock
-	http.Post(bankServiceURL+"/account",
-		"application/json",
-		bytes.NewReader([]byte(fmt.Sprintf(
-			`{"playerId":"%s","startingBalance":"1000.00"}`, playerID,
-		))),
-	)
-	startingChips := 1000
-	if balance := callBankBalance(playerID); balance >= 0 {
-		startingChips = balance
-	}
-
-	t := NewPlayerTable(tableID, playerID, playerName, startingChips)
+	r.reconcileOrphanedBets(rid, playerID)
+	t := NewPlayerTable(tableID, playerID, playerName, openBankAccount(rid, playerID, startingBalance))
+	t.touch()
 
 	// Now take the write lock just to insert
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	// Double-check in case of concurrent creation
 	if existing, ok := r.tables[tableID]; ok {
-		return existing
+		return existing, false
 	}
 	r.tables[tableID] = t
-	return t
+	return t, true
 }
 
-// ── Demo Game Loop ─────────────────────────────────────────────────────────────
-// Cycles the default table through realistic game phases so the UI has
-// something to render without real players. Calls stub services so the
-// observability dashboard shows real inter-service traffic.
-
-// demoPaused controls whether the demo loop runs. Toggle via POST /demo/pause.
-var demoPaused int32 // atomic: 0=running, 1=paused
+// maxStartingBalance caps the starting balance /tables/create may ask for.
+// The bank applies its own cap too.
+var maxStartingBalance = getEnvInt("MAX_STARTING_BALANCE", 100000)
 
-func runDemoLoop(table *Table) {
-	phases := []func(*Table){
-		phaseBetting,
-		phaseDealing,
-		phasePlayerTurn,
-		phaseDealerTurn,
-		phasePayout,
+// openBankAccount makes sure the player has a bank account (idempotent) and
+// returns their balance in chips. startingBalance only applies if the account
+// is new; zero leaves it to the bank's default. An existing account keeps
+// its balance — the bank answers 409 and nothing changes.
+func openBankAccount(rid, playerID string, startingBalance int) int {
+	body := fmt.Sprintf(`{"playerId":"%s"}`, playerID)
+	startingChips := 1000
+	if startingBalance > 0 {
+		body = fmt.Sprintf(`{"playerId":"%s","startingBalance":"%d.00"}`, playerID, startingBalance)
+		startingChips = startingBalance
 	}
-	for {
-		for _, phase := range phases {
-			// Check pause between phases
-			for atomic.LoadInt32(&demoPaused) == 1 {
-				time.Sleep(500 * time.Millisecond)
-			}
-			phase(table)
-		}
+	sendUpstream(bankClient, rid, http.MethodPost, bankServiceURL+"/account", []byte(body))
+	if balance := callBankBalance(rid, playerID); balance >= 0 {
+		startingChips = balance
 	}
+	return startingChips
 }
 
-func phaseBetting(t *Table) {
-	log.Println("[demo] phase: betting")
-	s := t.GetState()
-	s.Phase = "betting"
-	s.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
+// maxSeats caps how many players share a player table.
+var maxSeats = getEnvInt("MAX_SEATS", 5)
 
-	betAmount := 50
-	for i := range s.Players {
-		s.Players[i].Hand = []Card{}
-		s.Players[i].HandValue = 0
-		s.Players[i].CurrentBet = betAmount
-		s.Players[i].Status = "betting"
+var (
+	errTableNotFound  = errors.New("table not found")
+	errTableFull      = errors.New("table full")
+	errNotSeated      = errors.New("not seated at this table")
+	errHandInProgress = errors.New("hand in progress")
+	errRefundFailed   = errors.New("open bet could not be refunded")
+)
 
-		txID, newBalance := callBankBet(s.Players[i].ID, betAmount)
-		if txID != "" {
-			s.Players[i].BankTxID = txID
-			s.Players[i].Chips = newBalance
-			log.Printf("[bank] bet placed: player=%s amount=%d txId=%s balance=%d",
-				s.Players[i].ID, betAmount, txID, newBalance)
-		} else {
-			log.Printf("[bank] bet failed for player=%s — using local fallback", s.Players[i].ID)
-			s.Players[i].Chips -= betAmount
-		}
+// Join seats a player at an existing player table. Joining a table you're
+// already seated at is a no-op. New seats start out of the hand and join the
+// next betting window.
+func (r *Registry) Join(rid, tableID, playerID, playerName string) (*Table, error) {
+	t, ok := r.Get(tableID)
+	if !ok || t.isDemo {
+		return nil, errTableNotFound
+	}
+	t.mu.RLock()
+	seated := seatIndex(t.state, playerID) >= 0
+	full := len(t.state.Players) >= maxSeats
+	t.mu.RUnlock()
+	if seated {
+		return t, nil
+	}
+	if full {
+		return nil, errTableFull
 	}
 
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
+	// Bank calls outside any lock
+	chips := openBankAccount(rid, playerID, 0)
 
-	// Show betting state long enough to read
-	time.Sleep(1500 * time.Millisecond)
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, errTableNotFound
+	}
+	if seatIndex(t.state, playerID) >= 0 {
+		t.mu.Unlock()
+		return t, nil
+	}
+	if len(t.state.Players) >= maxSeats {
+		t.mu.Unlock()
+		return nil, errTableFull
+	}
+	status := "waiting"
+	if t.state.Phase != "waiting" {
+		status = "sitting_out"
+	}
+	t.state.Players = append(t.state.Players, PlayerState{
+		ID:     playerID,
+		Name:   playerName,
+		Chips:  chips,
+		Hand:   []Card{},
+		Status: status,
+	})
+	t.state.HandledBy = hostname()
+	t.state.Timestamp = now()
+	t.state.Version++
+	snapshot := t.state
+	t.mu.Unlock()
+	t.Broadcast(snapshot)
+	logf("info", logFields{"tableId": tableID, "playerId": playerID}, "[game-state] player joined table (%d/%d seats)", len(snapshot.Players), maxSeats)
+	return t, nil
 }
 
-func phaseDealing(t *Table) {
-	log.Println("[demo] phase: dealing — calling deck-service")
-
-	// Fetch all 4 cards upfront — one service call, deal them out visually one by one
-	cards := callDeckService(t.state.TableID, 4)
-	if len(cards) < 4 {
-		cards = defaultCards()
+// Leave takes a player off a table between hands. A bet already placed in
+// the current betting window is refunded as a push first; if the bank can't
+// take it back the player stays seated rather than strand the stake. The
+// last player out closes the table — it leaves the registry and its streams
+// end. Returns the player's chips and whether the table closed.
+func (r *Registry) Leave(rid, tableID, playerID string) (int, bool, error) {
+	t, ok := r.Get(tableID)
+	if !ok || t.isDemo {
+		return 0, false, errTableNotFound
 	}
+	// No action or clock may play the hand while the seat is going
+	t.actionMu.Lock()
+	defer t.actionMu.Unlock()
 
 	s := t.GetState()
-	s.Phase = "dealing"
-	s.Players[0].Status = "playing"
-	s.Players[0].Hand = []Card{}
-	s.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
-	t.SetState(s)
-	time.Sleep(400 * time.Millisecond)
+	i := seatIndex(s, playerID)
+	if i < 0 {
+		return 0, false, errNotSeated
+	}
+	p := s.Players[i]
+	if s.Phase != "waiting" && inHand(p) {
+		return 0, false, errHandInProgress
+	}
+	chips := p.Chips
+	if p.BankTxID != "" {
+		balance := callBankPayout(rid, p.BankTxID, "push")
+		if balance < 0 {
+			return 0, false, errRefundFailed
+		}
+		chips = balance
+	}
+
+	if len(s.Players) == 1 {
+		// Check and remove under both locks: a joiner either sits down first
+		// and keeps the table open, or finds it closed
+		r.mu.Lock()
+		t.mu.Lock()
+		last := len(t.state.Players) == 1
+		if last {
+			t.retireTimersLocked()
+			t.closed = true
+			t.state.Players[0].BankTxID = ""
+			t.state.Players[0].Chips = chips
+			delete(r.tables, tableID)
+			r.rememberLocked(playerID, TableRecord{TableID: tableID, Phase: t.state.Phase, Chips: chips, ClosedAt: now()})
+		}
+		t.mu.Unlock()
+		r.mu.Unlock()
+		if last {
+			t.closeSubscribers()
+			logf("info", logFields{"tableId": tableID, "playerId": playerID}, "[game-state] player left table — table closed")
+			return chips, true, nil
+		}
+	}
 
-	// Card 1: player first card
-	s = t.GetState()
-	s.Players[0].Hand = []Card{cards[0]}
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
-	time.Sleep(600 * time.Millisecond)
+	allIn, round := false, 0
+	t.Update(func(s *GameState) {
+		i := seatIndex(*s, playerID)
+		if i < 0 {
+			return
+		}
+		// Fresh slice — broadcast snapshots still share the old one
+		players := make([]PlayerState, 0, len(s.Players)-1)
+		players = append(players, s.Players[:i]...)
+		s.Players = append(players, s.Players[i+1:]...)
+
+		// The leaver may have been the only seat still to bet
+		allIn = s.Phase == "waiting" && len(s.Players) > 0
+		for _, p := range s.Players {
+			if p.Status != "ready" {
+				allIn = false
+			}
+		}
+		round = t.betRound
+	})
+	r.mu.Lock()
+	r.rememberLocked(playerID, TableRecord{TableID: tableID, Phase: s.Phase, Chips: chips, ClosedAt: now()})
+	r.mu.Unlock()
+	logf("info", logFields{"tableId": tableID, "playerId": playerID}, "[game-state] player left")
 
-	// Card 2: dealer face-up card
-	s = t.GetState()
-	s.Dealer.Hand = []Card{cards[2]}
-	s.Dealer.HandValue = cardValue(cards[2])
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
-	time.Sleep(600 * time.Millisecond)
+	if allIn {
+		go t.serialized(func() { closeBetting(t, round) })
+	}
+	return chips, false, nil
+}
 
-	// Card 3: player second card
-	s = t.GetState()
-	s.Players[0].Hand = []Card{cards[0], cards[1]}
-	handResult := callHandEvaluator(s.Players[0].Hand)
-	s.Players[0].HandValue = handResult.Value
-	s.Players[0].IsSoftHand = handResult.IsSoft
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
-	time.Sleep(600 * time.Millisecond)
+// ── Deal Plan ─────────────────────────────────────────────────────────────────
+// The initial deal follows casino order: one card to each seated player, then
+// the dealer face-up; a second round to each player, then the dealer's hole
+// card face-down. The plan is computed from the seats and published on the
+// state so the UI can animate cards in the same sequence.
 
-	// Card 4: dealer hole card (face down)
-	s = t.GetState()
-	s.Dealer.Hand = []Card{cards[2], {Suit: "hidden", Rank: "hidden"}}
-	pid := s.Players[0].ID
-	s.ActivePlayerID = &pid
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
+const dealerRecipient = "dealer"
 
-	// Pause on the dealt hands before player turn
-	time.Sleep(1200 * time.Millisecond)
+// DealStep is one card of the initial deal.
+type DealStep struct {
+	Recipient string `json:"recipient"` // player ID or "dealer"
+	FaceDown  bool   `json:"faceDown"`
 }
 
-func phasePlayerTurn(t *Table) {
-	log.Println("[demo] phase: player_turn")
-	s := t.GetState()
-	s.Phase = "player_turn"
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
+// dealPlan builds the two-round deal sequence for the given players.
+func dealPlan(players []PlayerState) []DealStep {
+	plan := make([]DealStep, 0, 2*len(players)+2)
+	for round := 0; round < 2; round++ {
+		for _, p := range players {
+			plan = append(plan, DealStep{Recipient: p.ID})
+		}
+		plan = append(plan, DealStep{Recipient: dealerRecipient, FaceDown: round == 1})
+	}
+	return plan
+}
 
-	// Brief pause — player "thinking"
-	time.Sleep(1500 * time.Millisecond)
-
-	// Demo: player hits once
-	hitCards := callDeckService(s.TableID, 1)
-	s = t.GetState()
-	if len(hitCards) > 0 {
-		s.Players[0].Hand = append(s.Players[0].Hand, hitCards[0])
-	} else {
-		s.Players[0].Hand = append(s.Players[0].Hand, Card{Suit: "hearts", Rank: "5"})
+// runDealPlan deals cards[i] to plan[i], broadcasting after each card.
+// len(cards) must be >= len(plan). Face-down cards are shown as hidden; the
+// dealer's real hole card is drawn at reveal time.
+func runDealPlan(t *Table, plan []DealStep, cards []Card, pause time.Duration) {
+	t.Update(func(s *GameState) { s.DealOrder = plan })
+
+	// Every card is known up front, so work out each seat's hand after each
+	// of its cards and score them all in one round-trip, not one per card.
+	state := t.GetState()
+	stepHand := make([][]Card, len(plan)) // seat's hand after plan[i]; nil for the dealer
+	var batch [][]Card
+	for i, step := range plan {
+		if step.Recipient == dealerRecipient {
+			continue
+		}
+		j := seatIndex(state, step.Recipient)
+		if j < 0 {
+			continue
+		}
+		hand := append(append([]Card{}, state.Players[j].Hand...), cards[i])
+		state.Players[j].Hand = hand
+		stepHand[i] = hand
+		batch = append(batch, hand)
+	}
+	results := callHandEvaluatorBatch(t.RequestID(), batch)
+
+	next := 0
+	for i, step := range plan {
+		card := cards[i]
+		if step.Recipient == dealerRecipient {
+			t.Update(func(s *GameState) {
+				if step.FaceDown {
+					s.Dealer.Hand = append(s.Dealer.Hand, Card{Suit: "hidden", Rank: "hidden"})
+				} else {
+					s.Dealer.Hand = append(s.Dealer.Hand, card)
+					s.Dealer.HandValue += cardValue(card)
+				}
+			})
+		} else if hand := stepHand[i]; hand != nil {
+			hr := results[next]
+			next++
+			t.Update(func(s *GameState) {
+				if j := seatIndex(*s, step.Recipient); j >= 0 {
+					s.Players[j].Hand = hand
+					s.Players[j].HandValue = hr.Value
+					s.Players[j].IsSoftHand = hr.IsSoft
+				}
+			})
+		}
+		time.Sleep(pause)
 	}
+}
 
-	handResult := callHandEvaluator(s.Players[0].Hand)
-	s.Players[0].HandValue = handResult.Value
-	s.Players[0].IsSoftHand = handResult.IsSoft
-	if handResult.IsBust {
-		s.Players[0].Status = "bust"
-	} else {
-		s.Players[0].Status = "standing"
+// ── Demo Game Loop ─────────────────────────────────────────────────────────────
+// Cycles the default table through realistic game phases so the UI has
+// something to render without real players. Calls stub services so the
+// observability dashboard shows real inter-service traffic.
+
+// demoPaused controls whether the demo loop runs. Toggle via POST /demo/pause.
+var demoPaused int32 // atomic: 0=running, 1=paused
+
+// demoSpeed scales the demo pacing: 2 plays twice as fast, 0.5 at half
+// speed. Stored as float64 bits, zero meaning 1x; set via POST /demo/speed.
+var demoSpeed atomic.Uint64
+
+const (
+	minDemoSpeed = 0.25
+	maxDemoSpeed = 4.0
+)
+
+// currentDemoSpeed returns the multiplier in effect.
+func currentDemoSpeed() float64 {
+	if bits := demoSpeed.Load(); bits != 0 {
+		return math.Float64frombits(bits)
 	}
+	return 1
+}
 
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
+// setDemoSpeed clamps speed to the supported range, stores it, and returns
+// the value in effect.
+func setDemoSpeed(speed float64) float64 {
+	speed = math.Min(math.Max(speed, minDemoSpeed), maxDemoSpeed)
+	demoSpeed.Store(math.Float64bits(speed))
+	return speed
+}
 
-	// Pause to show the final player hand
-	time.Sleep(1200 * time.Millisecond)
+// demoPace scales a demo pause by the current speed.
+func demoPace(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / currentDemoSpeed())
 }
 
-func phaseDealerTurn(t *Table) {
-	log.Println("[demo] phase: dealer_turn — calling dealer-ai")
-	s := t.GetState()
-	s.Phase = "dealer_turn"
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
-	time.Sleep(600 * time.Millisecond)
+// DEMO_TABLES demo tables run side by side, each with its own loop, seat
+// and bank account, so the dashboard shows concurrent traffic. Accounts are
+// seeded with DEMO_STARTING_CHIPS (idempotent — an existing account keeps its
+// balance). The pause and speed controls apply to every demo table.
+var (
+	demoTableCount    = getEnvInt("DEMO_TABLES", 1)
+	demoStartingChips = getEnvInt("DEMO_STARTING_CHIPS", 1000)
+)
 
-	// Reveal hole card
-	s = t.GetState()
-	revealCards := callDeckService(s.TableID, 1)
-	if len(revealCards) > 0 {
-		s.Dealer.Hand[1] = revealCards[0]
-	} else {
-		s.Dealer.Hand[1] = Card{Suit: "clubs", Rank: "7"}
+// demoTableID and demoPlayerID name the n-th demo table and its player
+// (1-based). Table 1 keeps the original fixed IDs.
+func demoTableID(n int) string  { return fmt.Sprintf("demo-table-00000000-0000-0000-0000-%012d", n) }
+func demoPlayerID(n int) string { return fmt.Sprintf("player-00000000-0000-0000-0000-%012d", n) }
+
+// startDemoTables creates the demo tables and starts their loops. The
+// returned channel closes once every loop has exited.
+func (r *Registry) startDemoTables(ctx context.Context, count int) <-chan struct{} {
+	var wg sync.WaitGroup
+	for n := 1; n <= count; n++ {
+		id := demoTableID(n)
+		t := NewTable(id, demoPlayerID(n))
+		t.state.Players[0].Name = fmt.Sprintf("Player %d", n)
+		r.mu.Lock()
+		r.tables[id] = t
+		r.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runDemoLoop(ctx, t)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// demoRealShoe deals the demo table from an initialized deck-service shoe,
+// like a real player table. DEMO_REAL_SHOE=false switches to locally
+// generated random cards for environments without deck-service.
+var demoRealShoe = getEnv("DEMO_REAL_SHOE", "true") != "false"
+
+// demoDeal draws count cards for the demo table.
+func demoDeal(rid, tableID string, count int) []Card {
+	if !demoRealShoe {
+		return randomCards(count)
 	}
-	s.Dealer.IsRevealed = true
+	cards := callDeckService(rid, tableID, count)
+	if len(cards) < count {
+		// Shoe exhausted or deck-service restarted — re-init and try once more
+		logf("warn", logFields{"requestId": rid, "tableId": tableID}, "[demo] shoe returned %d/%d cards — re-initializing", len(cards), count)
+		initShoe(rid, tableID)
+		cards = callDeckService(rid, tableID, count)
+	}
+	if len(cards) < count {
+		logf("warn", logFields{"requestId": rid, "tableId": tableID}, "[demo] deck-service unavailable — using random cards")
+		return randomCards(count)
+	}
+	return cards
+}
 
-	handResult := callHandEvaluator(s.Dealer.Hand)
-	s.Dealer.HandValue = handResult.Value
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
-	time.Sleep(800 * time.Millisecond)
+// runDemoLoop deals demo hands until ctx is cancelled. Cancellation is only
+// checked between hands, so a hand in progress always settles.
+func runDemoLoop(ctx context.Context, table *Table) {
+	table.setRequestID(newRequestID())
+	if demoRealShoe {
+		initShoe(table.RequestID(), table.GetState().TableID)
+	}
+	phases := []func(*Table){
+		phaseBetting,
+		phaseDealing,
+		phasePlayerTurn,
+		phaseDealerTurn,
+		phasePayout,
+	}
+	for ctx.Err() == nil {
+		// Each demo hand is its own request chain
+		table.setRequestID(newRequestID())
+		for _, phase := range phases {
+			// Check pause between phases; shutdown overrides a pause
+			for atomic.LoadInt32(&demoPaused) == 1 && ctx.Err() == nil {
+				time.Sleep(500 * time.Millisecond)
+			}
+			phase(table)
+		}
+	}
+}
+
+func phaseBetting(t *Table) {
+	log.Println("[demo] phase: betting")
+
+	betAmount := 50
+	txIDs := make(map[string]string)
+	balances := make(map[string]int)
+	state := t.GetState()
+	for _, p := range state.Players {
+		txID, newBalance := callBankBet(t.RequestID(), p.ID, betAmount)
+		if txID != "" {
+			txIDs[p.ID], balances[p.ID] = txID, newBalance
+			logf("info", logFields{"tableId": state.TableID, "playerId": p.ID, "txId": txID}, "[bank] bet placed: amount=%d balance=%d",
+				betAmount, newBalance)
+		} else {
+			logf("warn", logFields{"tableId": state.TableID, "playerId": p.ID}, "[bank] bet failed — using local fallback")
+		}
+	}
+
+	t.Update(func(s *GameState) {
+		s.Phase = "betting"
+		s.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
+		s.LastResult = nil
+		for i := range s.Players {
+			p := &s.Players[i]
+			p.Hand = []Card{}
+			p.HandValue = 0
+			p.CurrentBet = betAmount
+			p.Status = "betting"
+			if txID := txIDs[p.ID]; txID != "" {
+				p.BankTxID = txID
+				p.Chips = balances[p.ID]
+			} else {
+				p.Chips -= betAmount
+			}
+		}
+	})
+
+	// Show betting state long enough to read
+	time.Sleep(demoPace(1500 * time.Millisecond))
+}
+
+func phaseDealing(t *Table) {
+	log.Println("[demo] phase: dealing — calling deck-service")
+
+	// Fetch the whole deal upfront — one service call, deal them out visually one by one
+	plan := dealPlan(t.GetState().Players)
+	cards := demoDeal(t.RequestID(), t.GetState().TableID, len(plan))
+
+	t.Update(func(s *GameState) {
+		s.Phase = "dealing"
+		for i := range s.Players {
+			s.Players[i].Status = "playing"
+			s.Players[i].Hand = []Card{}
+		}
+		s.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
+	})
+	time.Sleep(demoPace(400 * time.Millisecond))
 
-	// Ask dealer AI, then hit one card at a time until 17+
-	for s.Dealer.HandValue < 17 {
-		decision := callDealerAI(s.Dealer.Hand)
-		log.Printf("[demo] dealer AI decision: %s (value=%d)", decision, s.Dealer.HandValue)
+	runDealPlan(t, plan, cards, demoPace(600*time.Millisecond))
 
-		hitCards := callDeckService(s.TableID, 1)
-		s = t.GetState()
-		if len(hitCards) > 0 {
-			s.Dealer.Hand = append(s.Dealer.Hand, hitCards[0])
+	t.Update(func(s *GameState) {
+		pid := s.Players[0].ID
+		s.ActivePlayerID = &pid
+	})
+
+	// Pause on the dealt hands before player turn
+	time.Sleep(demoPace(1200 * time.Millisecond))
+}
+
+func phasePlayerTurn(t *Table) {
+	log.Println("[demo] phase: player_turn")
+	s := t.Update(func(s *GameState) { s.Phase = "player_turn" })
+
+	// Brief pause — player "thinking"
+	time.Sleep(demoPace(1500 * time.Millisecond))
+
+	// Demo: player hits once
+	hitCards := demoDeal(t.RequestID(), s.TableID, 1)
+	hand, _ := seatHand(t.GetState(), s.Players[0].ID, hitCards[0])
+	handResult := callHandEvaluator(t.RequestID(), hand)
+
+	t.Update(func(s *GameState) {
+		p := &s.Players[0]
+		p.Actions = append(p.Actions, "hit")
+		p.Hand = hand
+		p.HandValue = handResult.Value
+		p.IsSoftHand = handResult.IsSoft
+		if handResult.IsBust {
+			p.Status = "bust"
 		} else {
-			s.Dealer.Hand = append(s.Dealer.Hand, Card{Suit: "diamonds", Rank: "3"})
+			p.Status = "standing"
 		}
-		handResult = callHandEvaluator(s.Dealer.Hand)
+	})
+
+	// Pause to show the final player hand
+	time.Sleep(demoPace(1200 * time.Millisecond))
+}
+
+func phaseDealerTurn(t *Table) {
+	log.Println("[demo] phase: dealer_turn — calling dealer-ai")
+	s := t.Update(func(s *GameState) { s.Phase = "dealer_turn" })
+	time.Sleep(demoPace(600 * time.Millisecond))
+
+	// Reveal hole card
+	hand := append([]Card{}, t.GetState().Dealer.Hand...)
+	hand[1] = demoDeal(t.RequestID(), s.TableID, 1)[0]
+	handResult := callHandEvaluator(t.RequestID(), hand)
+	s = t.Update(func(s *GameState) {
+		s.Dealer.Hand = hand
+		s.Dealer.IsRevealed = true
 		s.Dealer.HandValue = handResult.Value
-		s.HandledBy = hostname()
-		s.Timestamp = now()
-		t.SetState(s)
-		time.Sleep(700 * time.Millisecond)
+	})
+	time.Sleep(demoPace(800 * time.Millisecond))
+
+	// Hit one card at a time for as long as dealer AI says so
+	for dealerHits(t.RequestID(), s, handResult.IsSoft) {
+		log.Printf("[demo] dealer AI decision: hit (value=%d, strategy=%s)", s.Dealer.HandValue, s.DealerStrategy)
+
+		hitCards := demoDeal(t.RequestID(), s.TableID, 1)
+		hand = append(append([]Card{}, s.Dealer.Hand...), hitCards[0])
+		handResult = callHandEvaluator(t.RequestID(), hand)
+		s = t.Update(func(s *GameState) {
+			s.Dealer.Hand = hand
+			s.Dealer.HandValue = handResult.Value
+		})
+		time.Sleep(demoPace(700 * time.Millisecond))
 	}
 
-	s = t.GetState()
-	s.ActivePlayerID = nil
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
+	t.Update(func(s *GameState) { s.ActivePlayerID = nil })
 
 	// Pause to show final dealer hand before payout
-	time.Sleep(1000 * time.Millisecond)
+	time.Sleep(demoPace(1000 * time.Millisecond))
 }
 
 func phasePayout(t *Table) {
 	log.Println("[demo] phase: payout")
 	s := t.GetState()
-	s.Phase = "payout"
 
 	playerVal := s.Players[0].HandValue
 	dealerVal := s.Dealer.HandValue
@@ -518,38 +1405,51 @@ func phasePayout(t *Table) {
 	}
 
 	// Settle with bank — bank owns the balance
+	chipsBefore := s.Players[0].Chips
 	txID := s.Players[0].BankTxID
 	if txID != "" {
-		newBalance := callBankPayout(txID, outcome)
+		newBalance := callBankPayoutHand(t.RequestID(), txID, outcome, handEvidence(s, 0))
 		if newBalance >= 0 {
 			s.Players[0].Chips = newBalance
-			log.Printf("[bank] payout settled: player=%s txId=%s result=%s balance=%d",
-				s.Players[0].ID, txID, outcome, newBalance)
+			logf("info", logFields{"tableId": s.TableID, "playerId": s.Players[0].ID, "txId": txID}, "[bank] payout settled: result=%s balance=%d",
+				outcome, newBalance)
 		} else {
-			log.Printf("[bank] payout failed for txId=%s — balance may be stale", txID)
+			logf("warn", logFields{"tableId": s.TableID, "txId": txID}, "[bank] payout failed — balance may be stale")
 		}
 		s.Players[0].BankTxID = ""
 	} else {
-		log.Printf("[bank] no txId for payout — bet may have failed earlier")
+		logf("warn", logFields{"tableId": s.TableID}, "[bank] no txId for payout — bet may have failed earlier")
 	}
 
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
+	settled := s.Players[0]
+	summary := &HandSummary{
+		DealerValue: dealerVal,
+		Results:     []SeatResult{newSeatResult(settled, outcome, settled.Chips-chipsBefore)},
+	}
+	t.Update(func(s *GameState) {
+		s.Phase = "payout"
+		s.LastResult = summary
+		t.recordHandLocked(s, summary)
+		s.Players[0].Status = settled.Status
+		s.Players[0].Chips = settled.Chips
+		s.Players[0].BankTxID = ""
+	})
+	if demoRealShoe {
+		endShoeHand(t.RequestID(), s.TableID)
+	}
 
 	// Show the result — long enough to read win/loss and updated chips
-	time.Sleep(2500 * time.Millisecond)
+	time.Sleep(demoPace(2500 * time.Millisecond))
 
 	// Reset to waiting — brief pause then next hand begins
-	s = t.GetState()
-	s.Phase = "waiting"
-	s.Players[0].Status = "waiting"
-	s.Players[0].CurrentBet = 0
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	t.SetState(s)
+	t.Update(func(s *GameState) {
+		s.Phase = "waiting"
+		s.Players[0].Status = "waiting"
+		s.Players[0].CurrentBet = 0
+		s.Players[0].Actions = nil
+	})
 
-	time.Sleep(800 * time.Millisecond)
+	time.Sleep(demoPace(800 * time.Millisecond))
 }
 
 // ── Player State Machine ─────────────────────────────────────────────────────
@@ -558,281 +1458,451 @@ func phasePayout(t *Table) {
 
 func processPlayerAction(table *Table, action PlayerActionRequest) {
 	s := table.GetState()
-	// Use the table's actual player ID — guards against session/table ID mismatch
-	if len(s.Players) > 0 {
-		action.PlayerID = s.Players[0].ID
+	if seatIndex(s, action.PlayerID) < 0 {
+		logf("warn", logFields{"requestId": table.RequestID(), "tableId": s.TableID, "playerId": action.PlayerID}, "[game-state] player is not seated at the table")
+		return
 	}
 	switch s.Phase {
 	case "waiting":
 		if action.Action == "bet" {
 			playerBet(table, action)
 		}
+	case "insurance":
+		playerInsurance(table, action)
 	case "player_turn":
+		// Only the seat whose turn it is may act
+		if s.ActivePlayerID == nil || *s.ActivePlayerID != action.PlayerID {
+			logf("warn", logFields{"requestId": table.RequestID(), "playerId": action.PlayerID}, "[game-state] player acted out of turn")
+			return
+		}
+		if !table.claimTurn(action.PlayerID) {
+			logf("warn", logFields{"requestId": table.RequestID(), "playerId": action.PlayerID}, "[game-state] player acted after the turn clock expired")
+			return
+		}
 		switch action.Action {
 		case "hit":
-			playerHit(table)
+			playerHit(table, action.PlayerID)
 		case "stand":
-			playerStand(table)
+			playerStand(table, action.PlayerID)
 		case "double":
-			playerDouble(table)
+			playerDouble(table, action.PlayerID)
+		case "surrender":
+			playerSurrender(table, action.PlayerID)
 		case "split":
 			// Stubbed — acknowledge but do nothing
-			log.Println("[game-state] split: stubbed, action ignored")
+			logf("info", logFields{"tableId": s.TableID, "playerId": action.PlayerID}, "[game-state] split: stubbed, action ignored")
+		}
+	}
+}
+
+// seatIndex returns the index of playerID in s.Players, or -1 if not seated.
+func seatIndex(s GameState, playerID string) int {
+	for i, p := range s.Players {
+		if p.ID == playerID {
+			return i
 		}
 	}
+	return -1
+}
+
+// seatHand returns a copy of playerID's hand with cards appended, so a draw
+// can be evaluated before it is applied with Update. False if not seated.
+func seatHand(s GameState, playerID string, cards ...Card) ([]Card, bool) {
+	i := seatIndex(s, playerID)
+	if i < 0 {
+		return nil, false
+	}
+	return append(append([]Card{}, s.Players[i].Hand...), cards...), true
+}
+
+// inHand reports whether a seat is playing the current hand — seated players
+// who haven't bet, or sat this hand out, are skipped by every later phase.
+func inHand(p PlayerState) bool {
+	switch p.Status {
+	case "waiting", "ready", "sitting_out":
+		return false
+	}
+	return true
 }
 
 func playerBet(table *Table, action PlayerActionRequest) {
 	s := table.GetState()
-	if len(s.Players) == 0 {
+	i := seatIndex(s, action.PlayerID)
+	if i < 0 {
 		return
 	}
 	amount := action.Amount
-	if amount < s.MinBet {
-		amount = s.MinBet
-	}
-	if amount > s.MaxBet {
-		amount = s.MaxBet
+	if msg := validateBetAmount(s, amount); msg != "" {
+		// actionHandler already rejected this with invalid_amount
+		logf("info", logFields{"tableId": s.TableID, "playerId": action.PlayerID}, "[game-state] %s", msg)
+		return
 	}
-	if amount > s.Players[0].Chips {
-		amount = s.Players[0].Chips
+	if s.Players[i].Status == "ready" {
+		logf("info", logFields{"tableId": s.TableID, "playerId": action.PlayerID}, "[game-state] player already has a bet in this window")
+		return
 	}
-	if amount <= 0 {
+	if amount > s.Players[i].Chips {
+		// actionHandler already rejected this with insufficient_funds
+		logf("warn", logFields{"tableId": s.TableID, "playerId": action.PlayerID}, "[game-state] bet of %d exceeds chips=%d", amount, s.Players[i].Chips)
 		return
 	}
 
-	txID, newBalance := callBankBet(s.Players[0].ID, amount)
+	txID, newBalance := callBankBet(table.RequestID(), action.PlayerID, amount)
 	if txID == "" {
-		log.Printf("[game-state] bet rejected by bank for player=%s", s.Players[0].ID)
+		logf("warn", logFields{"tableId": s.TableID, "playerId": action.PlayerID}, "[game-state] bet rejected by bank")
 		return
 	}
 
-	s.Players[0].BankTxID = txID
-	s.Players[0].CurrentBet = amount
-	s.Players[0].Chips = newBalance
-	s.Players[0].Status = "betting"
-	s.Players[0].Hand = []Card{}
-	s.Players[0].HandValue = 0
-	s.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
-	s.Phase = "betting"
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
+	table.mu.Lock()
+	i = seatIndex(table.state, action.PlayerID)
+	if table.state.Phase != "waiting" || i < 0 {
+		// Betting window closed while the bank call was in flight
+		table.mu.Unlock()
+		logf("info", logFields{"tableId": s.TableID, "playerId": action.PlayerID}, "[game-state] bet arrived after window closed — cancelling it")
+		callBankCancel(table.RequestID(), txID)
+		return
+	}
+	p := &table.state.Players[i]
+	p.BankTxID = txID
+	p.CurrentBet = amount
+	p.Chips = newBalance
+	p.Status = "ready"
+	p.Hand = []Card{}
+	p.HandValue = 0
+	if table.state.BetDeadline == "" {
+		table.armBetWindowLocked()
+	}
+	allIn := true
+	for _, pl := range table.state.Players {
+		if pl.Status != "ready" {
+			allIn = false
+		}
+	}
+	round := table.betRound
+	table.state.HandledBy = hostname()
+	table.state.Timestamp = now()
+	table.state.Version++
+	snapshot := table.state
+	table.mu.Unlock()
+	table.Broadcast(snapshot)
+
+	if allIn {
+		closeBetting(table, round)
+	}
+}
+
+// dealHand runs the deal once betting has closed. Only players with a bet
+// placed (status "betting") are dealt in.
+func dealHand(table *Table) {
+	s := table.Update(func(s *GameState) {
+		s.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
+		s.LastResult = nil
+	})
 	time.Sleep(500 * time.Millisecond)
 
 	// Initialize shoe for this table (idempotent — 409 if already exists is fine)
-	initShoe(s.TableID)
+	initShoe(table.RequestID(), s.TableID)
 
-	// Deal 4 cards: p1, dealer-up, p2, dealer-hole
-	cards := callDeckService(s.TableID, 4)
-	if len(cards) < 4 {
-		cards = defaultCards()
+	var dealtIn []PlayerState
+	for _, p := range s.Players {
+		if p.Status == "betting" {
+			dealtIn = append(dealtIn, p)
+		}
+	}
+	plan := dealPlan(dealtIn)
+	cards := callDeckService(table.RequestID(), s.TableID, len(plan))
+	if len(cards) < len(plan) {
+		// Only the shoe deals a hand with real stakes on it
+		logf("error", logFields{"tableId": s.TableID}, "[game-state] deck-service returned %d/%d cards — calling off the hand", len(cards), len(plan))
+		cancelHand(table)
+		return
 	}
 
-	s = table.GetState()
-	s.Phase = "dealing"
-	table.SetState(s)
+	table.Update(func(s *GameState) { s.Phase = "dealing" })
 	time.Sleep(300 * time.Millisecond)
 
-	// Player card 1
-	s = table.GetState()
-	s.Players[0].Hand = []Card{cards[0]}
-	hr := callHandEvaluator(s.Players[0].Hand)
-	s.Players[0].HandValue = hr.Value
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
-	time.Sleep(500 * time.Millisecond)
+	runDealPlan(table, plan, cards, 500*time.Millisecond)
 
-	// Dealer face-up
+	// Dealer shows an Ace — offer insurance before checking the hole card
 	s = table.GetState()
-	s.Dealer.Hand = []Card{cards[1]}
-	s.Dealer.HandValue = cardValue(cards[1])
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
-	time.Sleep(500 * time.Millisecond)
+	if len(s.Dealer.Hand) > 0 && s.Dealer.Hand[0].Rank == "A" {
+		table.mu.Lock()
+		table.state.Phase = "insurance"
+		for i := range table.state.Players {
+			table.state.Players[i].InsuranceDone = !inHand(table.state.Players[i])
+		}
+		table.insuranceOpen = true
+		table.armInsuranceLocked()
+		table.state.HandledBy = hostname()
+		table.state.Timestamp = now()
+		table.state.Version++
+		snapshot := table.state
+		table.mu.Unlock()
+		table.Broadcast(snapshot)
+		return
+	}
 
-	// Player card 2
-	s = table.GetState()
-	s.Players[0].Hand = append(s.Players[0].Hand, cards[2])
-	hr = callHandEvaluator(s.Players[0].Hand)
-	s.Players[0].HandValue = hr.Value
-	s.Players[0].IsSoftHand = hr.IsSoft
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
-	time.Sleep(500 * time.Millisecond)
+	// Ten-value up-card — peek now so nobody plays into a dealer natural
+	if len(s.Dealer.Hand) > 0 && cardValue(s.Dealer.Hand[0]) == 10 {
+		if peekHoleCard(table) {
+			endHandOnDealerBlackjack(table)
+			return
+		}
+	}
 
-	// Dealer hole card (hidden)
-	s = table.GetState()
-	s.Dealer.Hand = append(s.Dealer.Hand, Card{Suit: "hidden", Rank: "hidden"})
-	pid := s.Players[0].ID
-	s.ActivePlayerID = &pid
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
+	startPlayerTurn(table)
+}
+
+// peekHoleCard draws the dealer's hole card, keeps it face-down on the state
+// for the reveal, and reports whether the two dealer cards are a natural.
+// Called only for an Ace or ten-value up-card, as in a real peek game.
+func peekHoleCard(table *Table) bool {
+	s := table.GetState()
+	if len(s.Dealer.Hand) != 2 {
+		return false
+	}
+	hole := callDeckService(table.RequestID(), s.TableID, 1)
+	if len(hole) == 0 {
+		hole = randomCards(1)
+	}
+	hr := callHandEvaluator(table.RequestID(), []Card{s.Dealer.Hand[0], hole[0]})
+
+	table.mu.Lock()
+	table.state.Dealer.HoleCard = &hole[0]
+	table.mu.Unlock()
+	return hr.IsBlackjack
+}
+
+// endHandOnDealerBlackjack skips the players' turns after a peek finds a
+// dealer natural: the hole card is revealed and every seat in the hand is
+// settled — a player natural pushes, anything else loses the main bet.
+// Doubles and splits can't have been staked yet, so only main bets move.
+func endHandOnDealerBlackjack(table *Table) {
+	table.Update(func(s *GameState) {
+		for i := range s.Players {
+			p := &s.Players[i]
+			if !inHand(*p) {
+				continue
+			}
+			if p.HandValue == 21 {
+				p.Status = "blackjack"
+			} else {
+				p.Status = "standing"
+			}
+		}
+		s.ActivePlayerID = nil
+	})
 	time.Sleep(600 * time.Millisecond)
+	runDealerTurnPlayer(table)
+}
 
-	// Natural blackjack check
-	if hr.Value == 21 {
-		s = table.GetState()
-		s.Players[0].Status = "blackjack"
+// startPlayerTurn marks naturals, then hands control to the first seat.
+func startPlayerTurn(table *Table) {
+	naturals := false
+	table.Update(func(s *GameState) {
+		for i := range s.Players {
+			p := &s.Players[i]
+			if !inHand(*p) {
+				continue
+			}
+			if p.HandValue == 21 {
+				p.Status = "blackjack"
+				naturals = true
+			} else {
+				p.Status = "playing"
+			}
+		}
 		s.Phase = "player_turn"
-		s.HandledBy = hostname()
-		s.Timestamp = now()
-		table.SetState(s)
+	})
+	if naturals {
 		time.Sleep(1000 * time.Millisecond)
-		runDealerTurnPlayer(table)
-		return
 	}
-
-	s = table.GetState()
-	s.Phase = "player_turn"
-	s.Players[0].Status = "playing"
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
+	advanceTurn(table)
 }
 
-func playerHit(table *Table) {
-	s := table.GetState()
-	if len(s.Players) == 0 || s.Players[0].Status != "playing" {
-		return
+// advanceTurn gives the turn to the next seat still playing, in seat order,
+// or moves to the dealer once every seat is done.
+func advanceTurn(table *Table) {
+	table.mu.Lock()
+	next := ""
+	for _, p := range table.state.Players {
+		if p.Status == "playing" {
+			next = p.ID
+			break
+		}
 	}
-	cards := callDeckService(s.TableID, 1)
-	if len(cards) > 0 {
-		s.Players[0].Hand = append(s.Players[0].Hand, cards[0])
+	if next != "" {
+		table.state.ActivePlayerID = &next
+		table.armTurnLocked(next)
 	} else {
-		s.Players[0].Hand = append(s.Players[0].Hand, Card{Suit: "hearts", Rank: "7"})
-	}
-	hr := callHandEvaluator(s.Players[0].Hand)
-	s.Players[0].HandValue = hr.Value
-	s.Players[0].IsSoftHand = hr.IsSoft
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	if hr.IsBust {
-		s.Players[0].Status = "bust"
-		s.ActivePlayerID = nil
-		table.SetState(s)
-		time.Sleep(800 * time.Millisecond)
+		table.state.ActivePlayerID = nil
+		table.clearTurnLocked()
+	}
+	table.state.HandledBy = hostname()
+	table.state.Timestamp = now()
+	table.state.Version++
+	snapshot := table.state
+	table.mu.Unlock()
+	table.Broadcast(snapshot)
+
+	if next == "" {
 		runDealerTurnPlayer(table)
+	}
+}
+
+func playerHit(table *Table, playerID string) {
+	s := table.GetState()
+	i := seatIndex(s, playerID)
+	if i < 0 || s.Players[i].Status != "playing" {
 		return
 	}
-	if hr.Value == 21 {
-		s.Players[0].Status = "standing"
-		s.ActivePlayerID = nil
-		table.SetState(s)
+	card := Card{Suit: "hearts", Rank: "7"}
+	if cards := callDeckService(table.RequestID(), s.TableID, 1); len(cards) > 0 {
+		card = cards[0]
+	}
+	hand, _ := seatHand(s, playerID, card)
+	hr := callHandEvaluator(table.RequestID(), hand)
+	status := "playing"
+	switch {
+	case hr.IsBust:
+		status = "bust"
+	case hr.Value == 21:
+		status = "standing"
+	}
+	table.Update(func(s *GameState) {
+		if i := seatIndex(*s, playerID); i >= 0 {
+			s.Players[i].Hand = hand
+			s.Players[i].HandValue = hr.Value
+			s.Players[i].IsSoftHand = hr.IsSoft
+			s.Players[i].Status = status
+		}
+		noteAction(s, playerID, "hit")
+	})
+	switch status {
+	case "bust":
+		time.Sleep(800 * time.Millisecond)
+		advanceTurn(table)
+	case "standing":
 		time.Sleep(600 * time.Millisecond)
-		runDealerTurnPlayer(table)
-		return
+		advanceTurn(table)
+	default:
+		table.restartTurn(playerID)
 	}
-	table.SetState(s)
 }
 
-func playerStand(table *Table) {
+func playerStand(table *Table, playerID string) {
 	s := table.GetState()
-	if len(s.Players) == 0 {
+	i := seatIndex(s, playerID)
+	if i < 0 || s.Players[i].Status != "playing" {
 		return
 	}
-	s.Players[0].Status = "standing"
-	s.ActivePlayerID = nil
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
+	table.Update(func(s *GameState) {
+		if i := seatIndex(*s, playerID); i >= 0 {
+			s.Players[i].Status = "standing"
+		}
+		noteAction(s, playerID, "stand")
+	})
 	time.Sleep(400 * time.Millisecond)
-	runDealerTurnPlayer(table)
+	advanceTurn(table)
 }
 
-func playerDouble(table *Table) {
+func playerDouble(table *Table, playerID string) {
 	s := table.GetState()
-	if len(s.Players) == 0 || s.Players[0].Status != "playing" {
+	i := seatIndex(s, playerID)
+	if i < 0 || s.Players[i].Status != "playing" {
 		return
 	}
-	additionalBet := s.Players[0].CurrentBet
-	if additionalBet > s.Players[0].Chips {
+	additionalBet := s.Players[i].CurrentBet
+	if additionalBet > s.Players[i].Chips {
 		// Can't afford full double — fall back to hit
-		playerHit(table)
+		playerHit(table, playerID)
 		return
 	}
-	txID2, newBalance := callBankBet(s.Players[0].ID, additionalBet)
+	txID2, newBalance := callBankBetLinked(table.RequestID(), playerID, additionalBet, s.Players[i].BankTxID)
 	if txID2 == "" {
-		playerHit(table)
+		playerHit(table, playerID)
 		return
 	}
-	s = table.GetState()
-	s.Players[0].CurrentBet += additionalBet
-	s.Players[0].Chips = newBalance
-	s.Players[0].BankTxID2 = txID2
 
 	// One card, forced stand
-	cards := callDeckService(s.TableID, 1)
-	if len(cards) > 0 {
-		s.Players[0].Hand = append(s.Players[0].Hand, cards[0])
-	} else {
-		s.Players[0].Hand = append(s.Players[0].Hand, Card{Suit: "diamonds", Rank: "4"})
+	card := Card{Suit: "diamonds", Rank: "4"}
+	if cards := callDeckService(table.RequestID(), s.TableID, 1); len(cards) > 0 {
+		card = cards[0]
 	}
-	hr := callHandEvaluator(s.Players[0].Hand)
-	s.Players[0].HandValue = hr.Value
-	s.Players[0].IsSoftHand = hr.IsSoft
-	if hr.IsBust {
-		s.Players[0].Status = "bust"
-	} else {
-		s.Players[0].Status = "standing"
+	hand, ok := seatHand(table.GetState(), playerID, card)
+	if !ok {
+		return
 	}
-	s.ActivePlayerID = nil
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
+	hr := callHandEvaluator(table.RequestID(), hand)
+	table.Update(func(s *GameState) {
+		i := seatIndex(*s, playerID)
+		if i < 0 {
+			return
+		}
+		p := &s.Players[i]
+		p.Actions = append(p.Actions, "double")
+		p.CurrentBet += additionalBet
+		p.Chips = newBalance
+		p.BankTxID2 = txID2
+		p.Hand = hand
+		p.HandValue = hr.Value
+		p.IsSoftHand = hr.IsSoft
+		if hr.IsBust {
+			p.Status = "bust"
+		} else {
+			p.Status = "standing"
+		}
+	})
 	time.Sleep(600 * time.Millisecond)
-	runDealerTurnPlayer(table)
+	advanceTurn(table)
 }
 
 func runDealerTurnPlayer(table *Table) {
-	s := table.GetState()
-	s.Phase = "dealer_turn"
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
+	table.Update(func(s *GameState) { s.Phase = "dealer_turn" })
 	time.Sleep(600 * time.Millisecond)
 
-	// Reveal hole card — draw from shoe
-	s = table.GetState()
-	if len(s.Dealer.Hand) >= 2 {
-		realCards := callDeckService(s.TableID, 1)
-		if len(realCards) > 0 {
-			s.Dealer.Hand[1] = realCards[0]
+	// Reveal hole card — draw from shoe unless a peek already did
+	s := table.GetState()
+	hand := append([]Card{}, s.Dealer.Hand...)
+	if len(hand) >= 2 {
+		if s.Dealer.HoleCard != nil {
+			hand[1] = *s.Dealer.HoleCard
+		} else if realCards := callDeckService(table.RequestID(), s.TableID, 1); len(realCards) > 0 {
+			hand[1] = realCards[0]
 		} else {
-			s.Dealer.Hand[1] = Card{Suit: "clubs", Rank: "8"}
+			hand[1] = Card{Suit: "clubs", Rank: "8"}
 		}
 	}
-	s.Dealer.IsRevealed = true
-	hr := callHandEvaluator(s.Dealer.Hand)
-	s.Dealer.HandValue = hr.Value
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
+	hr := callHandEvaluator(table.RequestID(), hand)
+	s = table.Update(func(s *GameState) {
+		s.Dealer.Hand = hand
+		s.Dealer.HoleCard = nil
+		s.Dealer.IsRevealed = true
+		s.Dealer.HandValue = hr.Value
+	})
 	time.Sleep(800 * time.Millisecond)
 
-	// If player busted, no need to play dealer hand
-	s = table.GetState()
-	playerBust := len(s.Players) > 0 && s.Players[0].Status == "bust"
-
-	if !playerBust {
-		for s.Dealer.HandValue < 17 {
-			callDealerAI(s.Dealer.Hand)
-			hitCards := callDeckService(s.TableID, 1)
-			s = table.GetState()
-			if len(hitCards) > 0 {
-				s.Dealer.Hand = append(s.Dealer.Hand, hitCards[0])
-			} else {
-				s.Dealer.Hand = append(s.Dealer.Hand, Card{Suit: "spades", Rank: "3"})
+	// The dealer only draws if some seat is still standing against it —
+	// busted, surrendered and natural hands are already decided
+	live := false
+	for _, p := range s.Players {
+		if p.Status == "standing" {
+			live = true
+		}
+	}
+
+	if live {
+		for dealerHits(table.RequestID(), s, hr.IsSoft) {
+			card := Card{Suit: "spades", Rank: "3"}
+			if hitCards := callDeckService(table.RequestID(), s.TableID, 1); len(hitCards) > 0 {
+				card = hitCards[0]
 			}
-			hr = callHandEvaluator(s.Dealer.Hand)
-			s.Dealer.HandValue = hr.Value
-			s.HandledBy = hostname()
-			s.Timestamp = now()
-			table.SetState(s)
+			hand = append(append([]Card{}, s.Dealer.Hand...), card)
+			hr = callHandEvaluator(table.RequestID(), hand)
+			s = table.Update(func(s *GameState) {
+				s.Dealer.Hand = hand
+				s.Dealer.HandValue = hr.Value
+			})
 			time.Sleep(700 * time.Millisecond)
 		}
 	}
@@ -841,93 +1911,575 @@ func runDealerTurnPlayer(table *Table) {
 }
 
 func runPayoutPlayer(table *Table) {
+	// Seats are settled on a snapshot — bank calls can't run under the lock —
+	// and only the settled fields are written back
 	s := table.GetState()
-	s.Phase = "payout"
-
-	if len(s.Players) == 0 {
-		table.SetState(s)
-		return
+	settled := make(map[string]PlayerState)
+	summary := &HandSummary{DealerValue: s.Dealer.HandValue, Results: []SeatResult{}}
+	for i := range s.Players {
+		switch s.Players[i].Status {
+		case "standing", "bust", "blackjack":
+			summary.Results = append(summary.Results, settleSeat(table.RequestID(), &s, i))
+			settled[s.Players[i].ID] = s.Players[i]
+		case "surrendered":
+			if r := s.Players[i].EarlyResult; r != nil {
+				summary.Results = append(summary.Results, *r)
+			}
+		}
 	}
 
-	playerVal := s.Players[0].HandValue
+	s = table.Update(func(s *GameState) {
+		s.Phase = "payout"
+		s.LastResult = summary
+		table.recordHandLocked(s, summary)
+		for i := range s.Players {
+			p := &s.Players[i]
+			if done, ok := settled[p.ID]; ok {
+				p.Status = done.Status
+				p.Chips = done.Chips
+				p.BankTxID = done.BankTxID
+				p.BankTxID2 = done.BankTxID2
+			}
+		}
+	})
+	endShoeHand(table.RequestID(), s.TableID)
+	time.Sleep(2500 * time.Millisecond)
+
+	resetForNextHand(table)
+}
+
+// settleSeat decides one seat's outcome against the dealer, settles its
+// bank transactions, and reports the result.
+func settleSeat(rid string, s *GameState, i int) SeatResult {
+	p := &s.Players[i]
+	chipsBefore := p.Chips
+	playerVal := p.HandValue
 	dealerVal := s.Dealer.HandValue
-	playerStatus := s.Players[0].Status
 
-	playerBlackjack := playerVal == 21 && len(s.Players[0].Hand) == 2 && playerStatus == "blackjack"
+	playerBlackjack := playerVal == 21 && len(p.Hand) == 2 && p.Status == "blackjack"
 	dealerBlackjack := dealerVal == 21 && len(s.Dealer.Hand) == 2
 
 	var outcome string
 	switch {
-	case playerStatus == "bust":
-		s.Players[0].Status = "lost"
+	case p.Status == "bust":
+		p.Status = "lost"
 		outcome = "loss"
 	case playerBlackjack && dealerBlackjack:
-		s.Players[0].Status = "push"
+		p.Status = "push"
 		outcome = "push"
 	case playerBlackjack:
-		s.Players[0].Status = "blackjack"
+		p.Status = "blackjack"
 		outcome = "blackjack"
 	case dealerVal > 21 || playerVal > dealerVal:
-		s.Players[0].Status = "won"
+		p.Status = "won"
 		outcome = "win"
 	case playerVal == dealerVal:
-		s.Players[0].Status = "push"
+		p.Status = "push"
 		outcome = "push"
 	default:
-		s.Players[0].Status = "lost"
+		p.Status = "lost"
 		outcome = "loss"
 	}
 
 	// Settle primary bet
-	if txID := s.Players[0].BankTxID; txID != "" {
-		if newBalance := callBankPayout(txID, outcome); newBalance >= 0 {
-			s.Players[0].Chips = newBalance
+	if txID := p.BankTxID; txID != "" {
+		if newBalance := callBankPayoutHand(rid, txID, outcome, handEvidence(*s, i)); newBalance >= 0 {
+			p.Chips = newBalance
 		}
-		s.Players[0].BankTxID = ""
+		p.BankTxID = ""
 	}
 	// Settle double-down additional bet
-	if txID2 := s.Players[0].BankTxID2; txID2 != "" {
-		if newBalance := callBankPayout(txID2, outcome); newBalance >= 0 {
-			s.Players[0].Chips = newBalance
+	if txID2 := p.BankTxID2; txID2 != "" {
+		if newBalance := callBankPayoutHand(rid, txID2, outcome, handEvidence(*s, i)); newBalance >= 0 {
+			p.Chips = newBalance
 		}
-		s.Players[0].BankTxID2 = ""
+		p.BankTxID2 = ""
 	}
+	return newSeatResult(*p, outcome, p.Chips-chipsBefore)
+}
 
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
-	time.Sleep(2500 * time.Millisecond)
-
-	// Reset to waiting for next hand
-	s = table.GetState()
-	s.Phase = "waiting"
-	s.Players[0].Status = "waiting"
-	s.Players[0].CurrentBet = 0
-	s.Players[0].Hand = []Card{}
-	s.Players[0].HandValue = 0
-	s.Players[0].IsSoftHand = false
-	s.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
-	s.ActivePlayerID = nil
-	s.HandledBy = hostname()
-	s.Timestamp = now()
-	table.SetState(s)
-}
-
-func initShoe(tableID string) {
-	body, _ := json.Marshal(map[string]interface{}{
-		"tableId":   tableID,
-		"deckCount": 6,
-	})
-	resp, err := http.Post(deckServiceURL+"/shoe", "application/json", bytes.NewReader(body))
-	if err != nil {
-		log.Printf("[deck-service] initShoe error: %v", err)
-		return
+// newSeatResult builds a seat's result from what the bank paid back.
+func newSeatResult(p PlayerState, outcome string, payout int) SeatResult {
+	if payout < 0 {
+		// Balance moved for another reason mid-settlement — nothing came back
+		payout = 0
+	}
+	return SeatResult{
+		PlayerID:    p.ID,
+		Outcome:     outcome,
+		BetAmount:   p.CurrentBet,
+		Payout:      payout,
+		NetChange:   payout - p.CurrentBet,
+		PlayerValue: p.HandValue,
 	}
-	resp.Body.Close()
-	// 409 = shoe already exists, that's fine
 }
 
-// ── Upstream Service Calls ─────────────────────────────────────────────────────
+// cancelHand calls off a hand that couldn't be dealt: every stake placed for
+// it is cancelled at the bank and the table goes back to betting.
+func cancelHand(table *Table) {
+	s := table.GetState()
+	balances := map[string]int{}
+	for _, p := range s.Players {
+		if p.BankTxID == "" {
+			continue
+		}
+		if balance := callBankCancel(table.RequestID(), p.BankTxID); balance >= 0 {
+			balances[p.ID] = balance
+		} else {
+			logf("error", logFields{"tableId": s.TableID, "playerId": p.ID, "txId": p.BankTxID}, "[game-state] cancel failed — bet stays open at the bank")
+		}
+	}
+	table.mu.Lock()
+	for i := range table.state.Players {
+		p := &table.state.Players[i]
+		if balance, ok := balances[p.ID]; ok {
+			p.Chips = balance
+		}
+		p.BankTxID = ""
+	}
+	table.mu.Unlock()
+	resetForNextHand(table)
+}
+
+// resetForNextHand clears the finished hand and reopens betting.
+func resetForNextHand(table *Table) {
+	// Reset to waiting for next hand — sat-out players are back in
+	table.mu.Lock()
+	table.state.Phase = "waiting"
+	for i := range table.state.Players {
+		p := &table.state.Players[i]
+		p.Status = "waiting"
+		p.CurrentBet = 0
+		p.Hand = []Card{}
+		p.HandValue = 0
+		p.IsSoftHand = false
+		p.InsuranceBet = 0
+		p.EarlyResult = nil
+		p.Actions = nil
+	}
+	table.state.Dealer = DealerState{Hand: []Card{}, IsRevealed: false}
+	table.state.ActivePlayerID = nil
+	table.armBetWindowLocked()
+	table.state.HandledBy = hostname()
+	table.state.Timestamp = now()
+	table.state.Version++
+	snapshot := table.state
+	table.mu.Unlock()
+	table.Broadcast(snapshot)
+}
+
+// playerSurrender is late surrender: only as the first decision on a
+// two-card hand. Half the stake comes back (bank schedule) and the seat is
+// out of the hand — the dealer doesn't draw against it.
+func playerSurrender(table *Table, playerID string) {
+	s := table.GetState()
+	i := seatIndex(s, playerID)
+	if i < 0 || s.Players[i].Status != "playing" || len(s.Players[i].Hand) != 2 {
+		return
+	}
+	newBalance := -1
+	txID := s.Players[i].BankTxID
+	if txID != "" {
+		newBalance = callBankPayoutHand(table.RequestID(), txID, "surrender", handEvidence(s, i))
+	}
+	returned := 0
+	if newBalance >= 0 {
+		returned = newBalance - s.Players[i].Chips
+	}
+	result := newSeatResult(s.Players[i], "surrender", returned)
+	table.Update(func(s *GameState) {
+		i := seatIndex(*s, playerID)
+		if i < 0 {
+			return
+		}
+		s.Players[i].Status = "surrendered"
+		s.Players[i].EarlyResult = &result
+		s.Players[i].Actions = append(s.Players[i].Actions, "surrender")
+		if newBalance >= 0 {
+			s.Players[i].Chips = newBalance
+		}
+		if txID != "" {
+			s.Players[i].BankTxID = ""
+		}
+	})
+	time.Sleep(600 * time.Millisecond)
+	advanceTurn(table)
+}
+
+// ── Insurance ─────────────────────────────────────────────────────────────────
+// When the dealer shows an Ace the hand pauses in the "insurance" phase. Each
+// seat in the hand may stake up to half its main bet on the dealer holding
+// blackjack ("insurance") or decline ("no_insurance"); once every seat has
+// answered, the hole card is checked. Side bets are their own bank
+// transactions, settled here — runPayoutPlayer never sees them.
+
+func playerInsurance(table *Table, action PlayerActionRequest) {
+	s := table.GetState()
+	i := seatIndex(s, action.PlayerID)
+	if i < 0 || s.Phase != "insurance" || s.Players[i].InsuranceDone {
+		return
+	}
+	if action.Action == "insurance" {
+		// actionHandler refused anything over half the bet or the chips
+		amount := action.Amount
+		if amount == 0 {
+			amount = s.Players[i].CurrentBet / 2
+		}
+		// Linked to the main bet, so the bank doesn't count it as a hand of its own
+		if txID, newBalance := callBankBetLinked(table.RequestID(), action.PlayerID, amount, s.Players[i].BankTxID); txID != "" {
+			table.mu.Lock()
+			if j := seatIndex(table.state, action.PlayerID); j >= 0 {
+				table.state.Players[j].InsuranceBet = amount
+				table.state.Players[j].InsuranceTxID = txID
+				table.state.Players[j].Chips = newBalance
+			}
+			table.mu.Unlock()
+		} else {
+			logf("warn", logFields{"requestId": table.RequestID(), "playerId": action.PlayerID}, "[game-state] insurance bet rejected by bank")
+		}
+	}
+
+	// Record the answer; the last seat to answer resolves insurance
+	table.mu.Lock()
+	if j := seatIndex(table.state, action.PlayerID); j >= 0 {
+		table.state.Players[j].InsuranceDone = true
+		table.state.Players[j].Actions = append(table.state.Players[j].Actions, action.Action)
+	}
+	allDone := true
+	for _, p := range table.state.Players {
+		if !p.InsuranceDone {
+			allDone = false
+		}
+	}
+	resolve := allDone && table.insuranceOpen
+	if resolve {
+		table.insuranceOpen = false
+		table.clearTurnLocked()
+	}
+	table.state.HandledBy = hostname()
+	table.state.Timestamp = now()
+	table.state.Version++
+	snapshot := table.state
+	table.mu.Unlock()
+	table.Broadcast(snapshot)
+
+	if resolve {
+		resolveInsurance(table)
+	}
+}
+
+// resolveInsurance peeks at the hole card, settles every insurance bet, and
+// either ends the hand (dealer blackjack) or continues to the players' turns.
+func resolveInsurance(table *Table) {
+	dealerBlackjack := peekHoleCard(table)
+
+	result := "loss"
+	if dealerBlackjack {
+		result = "insurance"
+	}
+	settled := make(map[string]int) // playerID → balance after settling, -1 if the bank call failed
+	for _, p := range table.GetState().Players {
+		if p.InsuranceTxID != "" {
+			settled[p.ID] = callBankPayout(table.RequestID(), p.InsuranceTxID, result)
+		}
+	}
+	table.Update(func(s *GameState) {
+		for i := range s.Players {
+			p := &s.Players[i]
+			balance, ok := settled[p.ID]
+			if !ok {
+				continue
+			}
+			if balance >= 0 {
+				p.Chips = balance
+			}
+			p.InsuranceTxID = ""
+		}
+	})
+
+	if !dealerBlackjack {
+		startPlayerTurn(table)
+		return
+	}
+
+	// Dealer blackjack — the hand is over; reveal and settle the main bets
+	endHandOnDealerBlackjack(table)
+}
+
+// ── Turn Clock ────────────────────────────────────────────────────────────────
+// A decision can't stall the table: when the clock runs out the active seat
+// stands, and unanswered insurance offers are declined. (An idle seat in the
+// waiting phase is covered by the betting window — it sits the hand out.)
+// Every accepted action claims the turn, which cancels the pending timer;
+// an action arriving after expiry finds the turn gone and is dropped.
+
+var turnTimeout = time.Duration(getEnvInt("TURN_TIMEOUT_SECONDS", 30)) * time.Second
+
+// armTurnLocked starts the decision clock for playerID. Caller must hold t.mu.
+func (t *Table) armTurnLocked(playerID string) {
+	t.turnSeq++
+	t.turnOwner = playerID
+	seq := t.turnSeq
+	t.state.TurnExpiresAt = time.Now().Add(turnTimeout).UTC().Format(time.RFC3339)
+	t.setTurnTimerLocked(time.AfterFunc(turnTimeout, func() { t.serialized(func() { turnExpired(t, playerID, seq) }) }))
+}
+
+// setTurnTimerLocked replaces the pending turn clock. Caller must hold t.mu.
+func (t *Table) setTurnTimerLocked(timer *time.Timer) {
+	if t.turnTimer != nil {
+		t.turnTimer.Stop()
+	}
+	t.turnTimer = timer
+}
+
+// retireTimersLocked invalidates and stops the betting window and turn
+// clocks, so nothing fires against a table that is going away. Caller must
+// hold t.mu.
+func (t *Table) retireTimersLocked() {
+	t.betRound++
+	t.turnSeq++
+	t.setTurnTimerLocked(nil)
+	if t.betTimer != nil {
+		t.betTimer.Stop()
+		t.betTimer = nil
+	}
+}
+
+// clearTurnLocked stops any running clock. Caller must hold t.mu.
+func (t *Table) clearTurnLocked() {
+	t.turnSeq++
+	t.turnOwner = ""
+	t.state.TurnExpiresAt = ""
+}
+
+// claimTurn takes the turn for an incoming action, cancelling the clock.
+// False means the clock already ran out (or it isn't this player's turn).
+func (t *Table) claimTurn(playerID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.turnOwner != playerID {
+		return false
+	}
+	t.turnSeq++
+	return true
+}
+
+// restartTurn gives the player a fresh clock after a non-final decision (hit).
+func (t *Table) restartTurn(playerID string) {
+	t.mu.Lock()
+	if t.state.ActivePlayerID == nil || *t.state.ActivePlayerID != playerID {
+		t.mu.Unlock()
+		return
+	}
+	t.armTurnLocked(playerID)
+	t.state.Version++
+	snapshot := t.state
+	t.mu.Unlock()
+	t.Broadcast(snapshot)
+}
+
+func turnExpired(t *Table, playerID string, seq int) {
+	t.mu.Lock()
+	if seq != t.turnSeq || t.turnOwner != playerID {
+		t.mu.Unlock()
+		return
+	}
+	t.turnSeq++
+	t.turnOwner = ""
+	t.mu.Unlock()
+	logf("warn", logFields{"tableId": t.GetState().TableID, "playerId": playerID}, "[game-state] turn clock expired — auto-stand")
+	playerStand(t, playerID)
+}
+
+// armInsuranceLocked starts the clock on an insurance offer. Caller must
+// hold t.mu.
+func (t *Table) armInsuranceLocked() {
+	t.turnSeq++
+	t.turnOwner = ""
+	seq := t.turnSeq
+	t.state.TurnExpiresAt = time.Now().Add(turnTimeout).UTC().Format(time.RFC3339)
+	t.setTurnTimerLocked(time.AfterFunc(turnTimeout, func() {
+		t.actionMu.Lock()
+		defer t.actionMu.Unlock()
+		t.mu.Lock()
+		stale := seq != t.turnSeq || !t.insuranceOpen
+		tableID := t.state.TableID
+		var pending []string
+		if !stale {
+			for _, p := range t.state.Players {
+				if !p.InsuranceDone {
+					pending = append(pending, p.ID)
+				}
+			}
+		}
+		t.mu.Unlock()
+		for _, id := range pending {
+			logf("info", logFields{"tableId": tableID, "playerId": id}, "[game-state] insurance clock expired — declined")
+			playerInsurance(t, PlayerActionRequest{PlayerID: id, Action: "no_insurance"})
+		}
+	}))
+}
+
+// ── Dealer Rule ───────────────────────────────────────────────────────────────
+// How the dealer plays is a per-table strategy that dealer-ai applies; the
+// game loop draws exactly when the AI says "hit". Tables start with the
+// DEALER_HITS_SOFT17 default; a creator can pick a strategy per table.
+// The local 17 rule below is only used while dealer-ai is unreachable.
+
+var defaultDealerHitsSoft17 = getEnv("DEALER_HITS_SOFT17", "false") == "true"
+
+// dealerStrategies maps each strategy dealer-ai knows to whether it hits a
+// soft 17 — what the local fallback and the dealerHitsSoft17 flag need.
+// Spanish 21 dealers hit soft 17; the short deck is deck-service's concern.
+var dealerStrategies = map[string]bool{
+	"standard":    false,
+	"hit-soft-17": true,
+	"spanish":     true,
+}
+
+// strategyFor returns the plain strategy matching an H17/S17 rule.
+func strategyFor(hitsSoft17 bool) string {
+	if hitsSoft17 {
+		return "hit-soft-17"
+	}
+	return "standard"
+}
+
+// dealerHits decides whether the dealer draws another card: dealer-ai's
+// answer under the table strategy, or the local 17 rule if it has none.
+// A hand of 21 or more never draws, whatever the AI says.
+func dealerHits(rid string, s GameState, soft bool) bool {
+	if s.Dealer.HandValue >= 21 {
+		return false
+	}
+	switch action := callDealerAI(rid, s.Dealer.Hand, s.DealerStrategy); action {
+	case "hit":
+		return true
+	case "stand", "bust":
+		return false
+	default:
+		logf("warn", logFields{"requestId": rid}, "[dealer-ai] no decision (%q) — falling back to the 17 rule", action)
+		return dealerShouldHit(s.Dealer.HandValue, soft, s.DealerHitsSoft17)
+	}
+}
+
+// dealerShouldHit reports whether the dealer draws on this total. The dealer
+// always hits below 17 and stands on hard 17+; a soft 17 (e.g. A+6) is hit
+// only on H17 tables.
+func dealerShouldHit(value int, soft, hitsSoft17 bool) bool {
+	if value < 17 {
+		return true
+	}
+	return value == 17 && soft && hitsSoft17
+}
+
+// ── Betting Window ────────────────────────────────────────────────────────────
+// The waiting phase is bounded so one idle seat can't stall the table. The
+// hand is dealt as soon as every seated player has bet, or when the window
+// expires — whoever hasn't bet by then sits the hand out but keeps the seat.
+
+var defaultBetWindowSecs = getEnvInt("BET_WINDOW_SECONDS", 20)
+
+// armBetWindowLocked opens a fresh betting window and schedules its close.
+// Caller must hold t.mu.
+func (t *Table) armBetWindowLocked() {
+	t.betRound++
+	if t.state.BetWindowSecs <= 0 {
+		t.state.BetDeadline = ""
+		return
+	}
+	round := t.betRound
+	window := time.Duration(t.state.BetWindowSecs) * time.Second
+	t.state.BetDeadline = time.Now().Add(window).UTC().Format(time.RFC3339)
+	if t.betTimer != nil {
+		t.betTimer.Stop()
+	}
+	t.betTimer = time.AfterFunc(window, func() { t.serialized(func() { closeBetting(t, round) }) })
+}
+
+// closeBetting ends the betting window opened in the given round and deals
+// whoever has bet. A stale round (window already closed) is a no-op.
+func closeBetting(t *Table, round int) {
+	t.mu.Lock()
+	if round != t.betRound || t.state.Phase != "waiting" {
+		t.mu.Unlock()
+		return
+	}
+	t.betRound++
+	t.state.BetDeadline = ""
+
+	bettors := 0
+	for _, p := range t.state.Players {
+		if p.Status == "ready" {
+			bettors++
+		}
+	}
+	if bettors > 0 {
+		for i := range t.state.Players {
+			p := &t.state.Players[i]
+			if p.Status == "ready" {
+				p.Status = "betting"
+			} else {
+				p.Status = "sitting_out"
+				logf("info", logFields{"tableId": t.state.TableID, "playerId": p.ID}, "[game-state] sits out — no bet before window closed")
+			}
+		}
+		t.state.Phase = "betting"
+	}
+	t.state.HandledBy = hostname()
+	t.state.Timestamp = now()
+	t.state.Version++
+	snapshot := t.state
+	t.mu.Unlock()
+	t.Broadcast(snapshot)
+
+	// Nobody bet — stay in waiting; the next bet opens a new window
+	if bettors > 0 {
+		dealHand(t)
+	}
+}
+
+// Shoe configuration requested from deck-service at init. SHOE_MODE is
+// "shoe" (dealt down across hands) or "csm" (continuous shuffler — every
+// card returns to the machine after each hand).
+var (
+	shoeMode      = getEnv("SHOE_MODE", "shoe")
+	shoeDeckCount = getEnvInt("SHOE_DECKS", 6)
+)
+
+func initShoe(rid, tableID string) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"tableId":   tableID,
+		"deckCount": shoeDeckCount,
+		"mode":      shoeMode,
+	})
+	resp, err := sendUpstream(http.DefaultClient, rid, http.MethodPost, deckServiceURL+"/shoe", body)
+	if err != nil {
+		logf("error", logFields{"requestId": rid}, "[deck-service] initShoe error: %v", err)
+		return
+	}
+	resp.Body.Close()
+	// 201 = new shoe, 409 = table already has one — both fine
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusConflict {
+		logf("warn", logFields{"requestId": rid}, "[deck-service] initShoe status %d", resp.StatusCode)
+	}
+}
+
+// endShoeHand tells deck-service the hand is over so a CSM shoe can take its
+// cards back. Fire and forget — a missed call only delays the reshuffle.
+func endShoeHand(rid, tableID string) {
+	url := deckServiceURL + "/shoe/" + tableID + "/end-hand"
+	go func() {
+		resp, err := sendUpstream(http.DefaultClient, rid, http.MethodPost, url, nil)
+		if err != nil {
+			logf("error", logFields{"requestId": rid}, "[deck-service] end-hand error: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// ── Upstream Service Calls ─────────────────────────────────────────────────────
 
 var (
 	deckServiceURL     = getEnv("DECK_SERVICE_URL", "http://deck-service:3002")
@@ -937,11 +2489,143 @@ var (
 	bankServiceURL     = getEnv("BANK_SERVICE_URL", "http://bank-service:3005")
 )
 
+// ── Dependency Health ─────────────────────────────────────────────────────────
+// /health probes the upstreams game-state can't play a hand without. Results
+// are cached briefly so a load balancer polling every instance doesn't turn
+// into a probe storm against the shared services.
+
+var (
+	healthProbeTTL = time.Duration(getEnvInt("HEALTH_CACHE_SECONDS", 5)) * time.Second
+	healthClient   = &http.Client{Timeout: 2 * time.Second}
+)
+
+// healthDeps maps dependency name → base URL. Losing the bank makes the
+// instance unready (/ready 503); losing any other only degrades it —
+// dealer-ai in particular has a local fallback.
+var healthDeps = map[string]string{
+	"deck-service":   deckServiceURL,
+	"hand-evaluator": handEvaluatorURL,
+	"dealer-ai":      dealerAIURL,
+	"bank-service":   bankServiceURL,
+}
+
+type depHealth struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	results   map[string]string
+}
+
+var deps depHealth
+
+// check returns each dependency's status, probing again once the cached
+// results are older than healthProbeTTL. Probes run in parallel.
+func (d *depHealth) check() map[string]string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.results != nil && time.Since(d.checkedAt) < healthProbeTTL {
+		return d.results
+	}
+	results := make(map[string]string, len(healthDeps))
+	var wg sync.WaitGroup
+	var rmu sync.Mutex
+	for name, base := range healthDeps {
+		wg.Add(1)
+		go func(name, base string) {
+			defer wg.Done()
+			status := probeHealth(base + "/health")
+			rmu.Lock()
+			results[name] = status
+			rmu.Unlock()
+		}(name, base)
+	}
+	wg.Wait()
+	d.results, d.checkedAt = results, time.Now()
+	return results
+}
+
+func probeHealth(healthURL string) string {
+	resp, err := healthClient.Get(healthURL)
+	if err != nil {
+		return "unreachable"
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		return "healthy"
+	}
+	return fmt.Sprintf("degraded (%d)", resp.StatusCode)
+}
+
+// rollup turns per-dependency results into the overall status and the
+// HTTP code to answer with.
+func rollup(results map[string]string) (string, int) {
+	if results["bank-service"] != "healthy" {
+		return "unhealthy", http.StatusServiceUnavailable
+	}
+	for _, status := range results {
+		if status != "healthy" {
+			return "degraded", http.StatusOK
+		}
+	}
+	return "healthy", http.StatusOK
+}
+
+// requestIDHeader carries the correlation ID the gateway assigns to each
+// request. game-state forwards it on every upstream call it makes.
+const requestIDHeader = "X-Request-ID"
+
+// newRequestID makes a correlation ID for work no request started (demo
+// hands, or a call that reached game-state without going through the gateway).
+func newRequestID() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
+
+// requestID returns the inbound request's correlation ID, minting one if
+// the caller didn't send it.
+func requestID(r *http.Request) string {
+	if rid := r.Header.Get(requestIDHeader); rid != "" && len(rid) <= 64 {
+		return rid
+	}
+	return newRequestID()
+}
+
+// sendUpstream makes a JSON call to another service, tagged with rid.
+// A nil body sends no payload (used for GETs and bodyless POSTs).
+func sendUpstream(client *http.Client, rid, method, url string, body []byte) (*http.Response, error) {
+	var rdr io.Reader
+	if body != nil {
+		rdr = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, rdr)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if rid != "" {
+		req.Header.Set(requestIDHeader, rid)
+	}
+	return client.Do(req)
+}
+
 // reportEvent fires a non-blocking event report to the observability service.
 // Fire and forget — never blocks game logic.
-func reportEvent(callee, method, path string, status int, latencyMs int64) {
+func reportEvent(rid, callee, method, path string, status int, latencyMs int64) {
+	sendEvent(rid, callee, method, path, status, latencyMs, false)
+}
+
+// reportDegraded reports a failed call whose answer game-state worked out
+// for itself. The event is flagged degraded so the dashboard shows the game
+// carried on without the service rather than just an error.
+func reportDegraded(rid, callee, method, path string, status int, latencyMs int64) {
+	sendEvent(rid, callee, method, path, status, latencyMs, true)
+}
+
+func sendEvent(rid, callee, method, path string, status int, latencyMs int64, degraded bool) {
+	url := observabilityURL + "/event" // read now; the report may outlive a config swap
 	go func() {
-		body, _ := json.Marshal(map[string]interface{}{
+		event := map[string]interface{}{
 			"caller":      "game-state",
 			"callee":      callee,
 			"method":      method,
@@ -949,10 +2633,15 @@ func reportEvent(callee, method, path string, status int, latencyMs int64) {
 			"status_code": status,
 			"latency_ms":  latencyMs,
 			"protocol":    "http",
-		})
-		resp, err := http.Post(observabilityURL+"/event", "application/json", bytes.NewReader(body))
+			"request_id":  rid,
+		}
+		if degraded {
+			event["degraded"] = true
+		}
+		body, _ := json.Marshal(event)
+		resp, err := http.Post(url, "application/json", bytes.NewReader(body))
 		if err != nil {
-			log.Printf("[observability] report error: %v", err)
+			logf("warn", logFields{"requestId": rid}, "[observability] report error: %v", err)
 			return
 		}
 		resp.Body.Close()
@@ -963,22 +2652,18 @@ type DeckDealResponse struct {
 	Cards []Card `json:"cards"`
 }
 
-func callDeckService(tableID string, count int) []Card {
+func callDeckService(rid, tableID string, count int) []Card {
 	body, _ := json.Marshal(map[string]int{"count": count})
 	start := time.Now()
 	path := fmt.Sprintf("/shoe/%s/deal", tableID)
-	resp, err := http.Post(
-		fmt.Sprintf("%s%s", deckServiceURL, path),
-		"application/json",
-		bytes.NewReader(body),
-	)
+	resp, err := sendUpstream(http.DefaultClient, rid, http.MethodPost, deckServiceURL+path, body)
 	if err != nil {
-		log.Printf("[deck-service] error: %v", err)
-		reportEvent("deck-service", "POST", path, 503, time.Since(start).Milliseconds())
+		logf("error", logFields{"requestId": rid}, "[deck-service] error: %v", err)
+		reportEvent(rid, "deck-service", "POST", path, 503, time.Since(start).Milliseconds())
 		return nil
 	}
 	defer resp.Body.Close()
-	reportEvent("deck-service", "POST", path, resp.StatusCode, time.Since(start).Milliseconds())
+	reportEvent(rid, "deck-service", "POST", path, resp.StatusCode, time.Since(start).Milliseconds())
 	var result DeckDealResponse
 	json.NewDecoder(resp.Body).Decode(&result)
 	return result.Cards
@@ -991,44 +2676,125 @@ type HandResult struct {
 	IsBust     bool `json:"isBust"`
 }
 
-func callHandEvaluator(hand []Card) HandResult {
+// useRemoteEvaluator sends hands to hand-evaluator over HTTP. Off
+// (USE_REMOTE_EVALUATOR=false), every hand is scored in-process by
+// EvaluateHand — no network hop per card, but no hand-evaluator traffic on
+// the dashboard either.
+var useRemoteEvaluator = getEnv("USE_REMOTE_EVALUATOR", "true") != "false"
+
+// evaluatorMode names where hands are scored, for /rules and the startup log.
+func evaluatorMode() string {
+	if useRemoteEvaluator {
+		return "remote"
+	}
+	return "local"
+}
+
+// callHandEvaluator scores a hand via hand-evaluator. If the service is
+// down or answers badly the hand is scored locally instead — every field,
+// so a bust is still a bust — and the call is reported as degraded.
+func callHandEvaluator(rid string, hand []Card) HandResult {
+	if !useRemoteEvaluator {
+		return EvaluateHand(hand)
+	}
 	body, _ := json.Marshal(map[string]interface{}{"cards": hand})
 	start := time.Now()
-	resp, err := http.Post(
-		fmt.Sprintf("%s/evaluate", handEvaluatorURL),
-		"application/json",
-		bytes.NewReader(body),
-	)
+	resp, err := sendUpstream(http.DefaultClient, rid, http.MethodPost, handEvaluatorURL+"/evaluate", body)
 	if err != nil {
-		log.Printf("[hand-evaluator] error: %v", err)
-		reportEvent("hand-evaluator", "POST", "/evaluate", 503, time.Since(start).Milliseconds())
-		return HandResult{Value: estimateValue(hand)}
+		logf("error", logFields{"requestId": rid}, "[hand-evaluator] error: %v — evaluating locally", err)
+		reportDegraded(rid, "hand-evaluator", "POST", "/evaluate", 503, time.Since(start).Milliseconds())
+		return EvaluateHand(hand)
 	}
 	defer resp.Body.Close()
-	reportEvent("hand-evaluator", "POST", "/evaluate", resp.StatusCode, time.Since(start).Milliseconds())
 	var result HandResult
-	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("status %d", resp.StatusCode)
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(&result)
+	}
+	if err != nil {
+		logf("warn", logFields{"requestId": rid}, "[hand-evaluator] bad response: %v — evaluating locally", err)
+		reportDegraded(rid, "hand-evaluator", "POST", "/evaluate", resp.StatusCode, time.Since(start).Milliseconds())
+		return EvaluateHand(hand)
+	}
+	reportEvent(rid, "hand-evaluator", "POST", "/evaluate", resp.StatusCode, time.Since(start).Milliseconds())
 	return result
 }
 
-func callDealerAI(hand []Card) string {
-	body, _ := json.Marshal(map[string]interface{}{"hand": hand})
+// evalBatchMax matches hand-evaluator's per-request limit; bigger batches
+// are sent in chunks.
+const evalBatchMax = 64
+
+// callHandEvaluatorBatch scores several hands in one request to
+// hand-evaluator's /evaluate/batch, returning results in the same order.
+// Falls back to local scoring, reported as degraded, like callHandEvaluator.
+// Always returns len(hands) results.
+func callHandEvaluatorBatch(rid string, hands [][]Card) []HandResult {
+	if len(hands) == 0 {
+		return nil
+	}
+	if len(hands) > evalBatchMax {
+		return append(callHandEvaluatorBatch(rid, hands[:evalBatchMax]), callHandEvaluatorBatch(rid, hands[evalBatchMax:])...)
+	}
+	local := func() []HandResult {
+		results := make([]HandResult, len(hands))
+		for i, hand := range hands {
+			results[i] = EvaluateHand(hand)
+		}
+		return results
+	}
+	if !useRemoteEvaluator {
+		return local()
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"hands": hands})
+	start := time.Now()
+	resp, err := sendUpstream(http.DefaultClient, rid, http.MethodPost, handEvaluatorURL+"/evaluate/batch", body)
+	if err != nil {
+		logf("error", logFields{"requestId": rid}, "[hand-evaluator] batch error: %v — evaluating locally", err)
+		reportDegraded(rid, "hand-evaluator", "POST", "/evaluate/batch", 503, time.Since(start).Milliseconds())
+		return local()
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Results []HandResult `json:"results"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("status %d", resp.StatusCode)
+	} else if err = json.NewDecoder(resp.Body).Decode(&out); err == nil && len(out.Results) != len(hands) {
+		err = fmt.Errorf("%d results for %d hands", len(out.Results), len(hands))
+	}
+	if err != nil {
+		logf("warn", logFields{"requestId": rid}, "[hand-evaluator] bad batch response: %v — evaluating locally", err)
+		reportDegraded(rid, "hand-evaluator", "POST", "/evaluate/batch", resp.StatusCode, time.Since(start).Milliseconds())
+		return local()
+	}
+	reportEvent(rid, "hand-evaluator", "POST", "/evaluate/batch", resp.StatusCode, time.Since(start).Milliseconds())
+	return out.Results
+}
+
+// callDealerAI asks dealer-ai what the dealer does with hand under the given
+// strategy: "hit", "stand" or "bust". Returns "" when the AI can't answer.
+func callDealerAI(rid string, hand []Card, strategy string) string {
+	body, _ := json.Marshal(map[string]interface{}{"hand": hand, "strategy": strategy})
 	start := time.Now()
-	resp, err := http.Post(
-		fmt.Sprintf("%s/decide", dealerAIURL),
-		"application/json",
-		bytes.NewReader(body),
-	)
+	resp, err := sendUpstream(http.DefaultClient, rid, http.MethodPost, dealerAIURL+"/decide", body)
 	if err != nil {
-		log.Printf("[dealer-ai] error: %v", err)
-		reportEvent("dealer-ai", "POST", "/decide", 503, time.Since(start).Milliseconds())
-		return "stand"
+		logf("error", logFields{"requestId": rid}, "[dealer-ai] error: %v", err)
+		reportEvent(rid, "dealer-ai", "POST", "/decide", 503, time.Since(start).Milliseconds())
+		return ""
 	}
 	defer resp.Body.Close()
-	reportEvent("dealer-ai", "POST", "/decide", resp.StatusCode, time.Since(start).Milliseconds())
-	var result map[string]string
+	reportEvent(rid, "dealer-ai", "POST", "/decide", resp.StatusCode, time.Since(start).Milliseconds())
+	if resp.StatusCode != http.StatusOK {
+		logf("warn", logFields{"requestId": rid}, "[dealer-ai] /decide returned %d", resp.StatusCode)
+		return ""
+	}
+	var result struct {
+		Action string `json:"action"`
+	}
 	json.NewDecoder(resp.Body).Decode(&result)
-	return result["action"]
+	return result.Action
 }
 
 // ── Bank Service Calls ────────────────────────────────────────────────────────
@@ -1042,25 +2808,87 @@ type PayoutResponse struct {
 	NewBalance string `json:"newBalance"` // bank returns string e.g. "975.00"
 }
 
+// bankClient bounds every bank call so a hung bank can't stall a hand.
+var bankClient = &http.Client{Timeout: 5 * time.Second}
+
+// betRetryBackoff is the pause before the single bet retry.
+const betRetryBackoff = 250 * time.Millisecond
+
+// isDialError reports whether err happened while connecting — the request
+// demonstrably never reached the bank, so retrying cannot double-debit.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// maxBalanceConflicts bounds retries after the bank reports a lost balance
+// race (409 balance_conflict). Nothing moved, so a retry is always safe.
+const maxBalanceConflicts = 3
+
+// isBalanceConflict reports whether a bank 409 is a lost optimistic-lock race
+// rather than a business rejection. It consumes the response body.
+func isBalanceConflict(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusConflict {
+		return false
+	}
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return body.Error.Code == "balance_conflict"
+}
+
 // callBankBet deducts the bet from the player's bank balance.
 // Returns transaction_id to be held until payout, and new balance.
-func callBankBet(playerID string, amount int) (string, int) {
-	start := time.Now()
-	body, _ := json.Marshal(map[string]string{
+// A connection failure is retried once and a balance conflict up to
+// maxBalanceConflicts times; any other response from the bank
+// (including 409 insufficient funds) is final.
+func callBankBet(rid, playerID string, amount int) (string, int) {
+	return callBankBetLinked(rid, playerID, amount, "")
+}
+
+// callBankBetLinked places a bet tied to an open parent bet — a double-down
+// or insurance on the base hand — so the bank records them as one wager.
+func callBankBetLinked(rid, playerID string, amount int, parentTxID string) (string, int) {
+	req := map[string]string{
 		"playerId": playerID,
 		"amount":   fmt.Sprintf("%d.00", amount),
-	})
-	resp, err := http.Post(bankServiceURL+"/bet", "application/json", bytes.NewReader(body))
-	if err != nil {
-		log.Printf("[bank-service] bet error: %v", err)
-		reportEvent("bank-service", "POST", "/bet", 503, time.Since(start).Milliseconds())
-		return "", -1
+	}
+	if parentTxID != "" {
+		req["parentTransactionId"] = parentTxID
+	}
+	body, _ := json.Marshal(req)
+	var resp *http.Response
+	dialRetried, conflicts := false, 0
+	for {
+		start := time.Now()
+		var err error
+		resp, err = sendUpstream(bankClient, rid, http.MethodPost, bankServiceURL+"/bet", body)
+		if err == nil {
+			reportEvent(rid, "bank-service", "POST", "/bet", resp.StatusCode, time.Since(start).Milliseconds())
+			if conflicts < maxBalanceConflicts && isBalanceConflict(resp) {
+				resp.Body.Close()
+				conflicts++
+				logf("warn", logFields{"requestId": rid, "playerId": playerID}, "[bank-service] bet lost a balance race — retrying (%d/%d)", conflicts, maxBalanceConflicts)
+				continue
+			}
+			break
+		}
+		reportEvent(rid, "bank-service", "POST", "/bet", 503, time.Since(start).Milliseconds())
+		if dialRetried || !isDialError(err) {
+			logf("error", logFields{"requestId": rid, "playerId": playerID}, "[bank-service] bet error: %v", err)
+			return "", -1
+		}
+		logf("warn", logFields{"requestId": rid, "playerId": playerID}, "[bank-service] bet did not reach bank (%v) — retrying once", err)
+		dialRetried = true
+		time.Sleep(betRetryBackoff)
 	}
 	defer resp.Body.Close()
-	reportEvent("bank-service", "POST", "/bet", resp.StatusCode, time.Since(start).Milliseconds())
 
 	if resp.StatusCode != 200 {
-		log.Printf("[bank-service] bet rejected: status=%d", resp.StatusCode)
+		logf("warn", logFields{"requestId": rid, "playerId": playerID}, "[bank-service] bet rejected: status=%d", resp.StatusCode)
 		return "", -1
 	}
 
@@ -1074,69 +2902,294 @@ func callBankBet(playerID string, amount int) (string, int) {
 // callBankPayout settles a bet transaction.
 // result must be "win", "loss", or "push".
 // Returns new balance after settlement.
-func callBankPayout(txID string, result string) int {
-	start := time.Now()
-	body, _ := json.Marshal(map[string]string{
+func callBankPayout(rid, txID, result string) int {
+	return callBankPayoutHand(rid, txID, result, nil)
+}
+
+// HandEvidence is the final hand sent with a payout so the bank can
+// cross-check the claimed result (see bank-service guard.go).
+type HandEvidence struct {
+	PlayerValue int `json:"playerValue"`
+	PlayerCards int `json:"playerCards"`
+	DealerValue int `json:"dealerValue"`
+	DealerCards int `json:"dealerCards"`
+}
+
+// handEvidence captures seat i's final hand against the dealer's.
+func handEvidence(s GameState, i int) *HandEvidence {
+	if i < 0 || i >= len(s.Players) {
+		return nil
+	}
+	return &HandEvidence{
+		PlayerValue: s.Players[i].HandValue,
+		PlayerCards: len(s.Players[i].Hand),
+		DealerValue: s.Dealer.HandValue,
+		DealerCards: len(s.Dealer.Hand),
+	}
+}
+
+// callBankPayoutHand settles a bet, attaching the hand as evidence when given.
+// A balance conflict (a concurrent bet or deposit won the race) is retried.
+func callBankPayoutHand(rid, txID, result string, hand *HandEvidence) int {
+	body, _ := json.Marshal(map[string]interface{}{
 		"transactionId": txID,
 		"result":        result,
+		"hand":          hand,
 	})
-	resp, err := http.Post(bankServiceURL+"/payout", "application/json", bytes.NewReader(body))
+	var resp *http.Response
+	for conflicts := 0; ; conflicts++ {
+		start := time.Now()
+		var err error
+		resp, err = sendUpstream(bankClient, rid, http.MethodPost, bankServiceURL+"/payout", body)
+		if err != nil {
+			logf("error", logFields{"requestId": rid, "txId": txID}, "[bank-service] payout error: %v", err)
+			reportEvent(rid, "bank-service", "POST", "/payout", 503, time.Since(start).Milliseconds())
+			return -1
+		}
+		reportEvent(rid, "bank-service", "POST", "/payout", resp.StatusCode, time.Since(start).Milliseconds())
+		if conflicts >= maxBalanceConflicts || !isBalanceConflict(resp) {
+			break
+		}
+		resp.Body.Close()
+		logf("warn", logFields{"requestId": rid, "txId": txID}, "[bank-service] payout lost a balance race — retrying (%d/%d)", conflicts+1, maxBalanceConflicts)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		logf("warn", logFields{"requestId": rid, "txId": txID}, "[bank-service] payout rejected: status=%d", resp.StatusCode)
+		return -1
+	}
+
+	var pr PayoutResponse
+	json.NewDecoder(resp.Body).Decode(&pr)
+	var bal float64
+	fmt.Sscanf(pr.NewBalance, "%f", &bal)
+	return int(bal)
+}
+
+// callBankCancel reverses a bet whose hand never played. Returns the new
+// balance, or -1 if the bank refused (e.g. already settled) or was down.
+func callBankCancel(rid, txID string) int {
+	start := time.Now()
+	body, _ := json.Marshal(map[string]string{"transactionId": txID})
+	resp, err := sendUpstream(bankClient, rid, http.MethodPost, bankServiceURL+"/bet/cancel", body)
+	if err != nil {
+		logf("error", logFields{"requestId": rid}, "[bank-service] cancel error: %v", err)
+		reportEvent(rid, "bank-service", "POST", "/bet/cancel", 503, time.Since(start).Milliseconds())
+		return -1
+	}
+	defer resp.Body.Close()
+	reportEvent(rid, "bank-service", "POST", "/bet/cancel", resp.StatusCode, time.Since(start).Milliseconds())
+
+	if resp.StatusCode != 200 {
+		logf("warn", logFields{"requestId": rid, "txId": txID}, "[bank-service] cancel rejected: status=%d", resp.StatusCode)
+		return -1
+	}
+
+	var pr PayoutResponse
+	json.NewDecoder(resp.Body).Decode(&pr)
+	var bal float64
+	fmt.Sscanf(pr.NewBalance, "%f", &bal)
+	return int(bal)
+}
+
+// callBankBalance fetches current balance for display on startup/reconnect.
+func callBankBalance(rid, playerID string) int {
+	start := time.Now()
+	resp, err := sendUpstream(bankClient, rid, http.MethodGet, fmt.Sprintf("%s/balance?playerId=%s", bankServiceURL, playerID), nil)
+	if err != nil {
+		reportEvent(rid, "bank-service", "GET", "/balance", 503, time.Since(start).Milliseconds())
+		return -1
+	}
+	defer resp.Body.Close()
+	reportEvent(rid, "bank-service", "GET", "/balance", resp.StatusCode, time.Since(start).Milliseconds())
+
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if b, ok := result["balance"].(float64); ok {
+		return int(b)
+	}
+	return -1
+}
+
+// OpenBet is an unsettled bet as reported by the bank.
+type OpenBet struct {
+	TransactionID string `json:"transactionId"`
+	Amount        string `json:"amount"`
+	AgeSeconds    int64  `json:"ageSeconds"`
+}
+
+// callBankOpenBets lists the player's unsettled bets. A bank outage returns
+// none — reconciliation simply waits for the next table.
+func callBankOpenBets(rid, playerID string) []OpenBet {
+	start := time.Now()
+	resp, err := sendUpstream(bankClient, rid, http.MethodGet, fmt.Sprintf("%s/open-bets?playerId=%s", bankServiceURL, playerID), nil)
 	if err != nil {
-		log.Printf("[bank-service] payout error: %v", err)
-		reportEvent("bank-service", "POST", "/payout", 503, time.Since(start).Milliseconds())
-		return -1
+		reportEvent(rid, "bank-service", "GET", "/open-bets", 503, time.Since(start).Milliseconds())
+		return nil
 	}
 	defer resp.Body.Close()
-	reportEvent("bank-service", "POST", "/payout", resp.StatusCode, time.Since(start).Milliseconds())
-
+	reportEvent(rid, "bank-service", "GET", "/open-bets", resp.StatusCode, time.Since(start).Milliseconds())
 	if resp.StatusCode != 200 {
-		log.Printf("[bank-service] payout rejected: status=%d", resp.StatusCode)
-		return -1
+		return nil
 	}
 
-	var pr PayoutResponse
-	json.NewDecoder(resp.Body).Decode(&pr)
-	var bal float64
-	fmt.Sscanf(pr.NewBalance, "%f", &bal)
-	return int(bal)
+	var result struct {
+		OpenBets []OpenBet `json:"openBets"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return result.OpenBets
 }
 
-// callBankBalance fetches current balance for display on startup/reconnect.
-func callBankBalance(playerID string) int {
+// callBankExclusion returns the player's self-exclusion end (RFC3339), or ""
+// if they may bet. A bank outage does not block play — the bank still
+// enforces the freeze on /bet.
+func callBankExclusion(rid, playerID string) string {
 	start := time.Now()
-	resp, err := http.Get(fmt.Sprintf("%s/balance?playerId=%s", bankServiceURL, playerID))
+	resp, err := sendUpstream(bankClient, rid, http.MethodGet, fmt.Sprintf("%s/self-exclude?playerId=%s", bankServiceURL, playerID), nil)
 	if err != nil {
-		reportEvent("bank-service", "GET", "/balance", 503, time.Since(start).Milliseconds())
-		return -1
+		reportEvent(rid, "bank-service", "GET", "/self-exclude", 503, time.Since(start).Milliseconds())
+		return ""
 	}
 	defer resp.Body.Close()
-	reportEvent("bank-service", "GET", "/balance", resp.StatusCode, time.Since(start).Milliseconds())
+	reportEvent(rid, "bank-service", "GET", "/self-exclude", resp.StatusCode, time.Since(start).Milliseconds())
 
-	var result map[string]interface{}
+	var result struct {
+		ExcludedUntil *string `json:"excludedUntil"`
+	}
 	json.NewDecoder(resp.Body).Decode(&result)
-	if b, ok := result["balance"].(float64); ok {
-		return int(b)
+	if result.ExcludedUntil == nil {
+		return ""
 	}
-	return -1
+	return *result.ExcludedUntil
+}
+
+// ── Logging ───────────────────────────────────────────────────────────────────
+// One JSON object per line unless LOG_FORMAT=text. Plain log.Printf output
+// (startup, the demo loop) comes out at info; everything that touches a table
+// goes through logf with its level and tableId/playerId/requestId fields.
+
+var logJSON = getEnv("LOG_FORMAT", "json") != "text"
+
+// logFields are the structured fields of one log line.
+type logFields map[string]any
+
+var logMu sync.Mutex
+
+func setupLogging() {
+	if logJSON {
+		log.SetFlags(0)
+		log.SetOutput(jsonLogWriter{})
+	}
+}
+
+// jsonLogWriter turns each line the standard logger writes into a JSON line.
+type jsonLogWriter struct{}
+
+func (jsonLogWriter) Write(p []byte) (int, error) {
+	writeLogLine("info", string(bytes.TrimRight(p, "\n")), nil)
+	return len(p), nil
+}
+
+func writeLogLine(level, msg string, fields logFields) {
+	line := make(map[string]any, len(fields)+4)
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		line[k] = v
+	}
+	line["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level
+	line["service"] = "game-state"
+	line["msg"] = msg
+	b, err := json.Marshal(line)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"level": level, "service": "game-state", "msg": msg})
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+	os.Stderr.Write(append(b, '\n'))
+}
+
+// logf logs at level ("info", "warn" or "error") with fields. In text mode
+// the fields are appended as key=value pairs.
+func logf(level string, fields logFields, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if logJSON {
+		writeLogLine(level, msg, fields)
+		return
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg += fmt.Sprintf(" %s=%v", k, fields[k])
+	}
+	log.Print(msg)
 }
 
 // ── HTTP Handlers ─────────────────────────────────────────────────────────────
 
 func main() {
+	setupLogging()
 	registry := NewRegistry()
 
-	// Create and start demo table
-	demoTableID := "demo-table-00000000-0000-0000-0000-000000000001"
-	demoTable := registry.GetOrCreate(demoTableID)
-	go runDemoLoop(demoTable)
+	// Create and start the demo tables
+	if demoTableCount < 1 {
+		demoTableCount = 1
+	}
+	demoCtx, stopDemo := context.WithCancel(context.Background())
+	demoDone := registry.startDemoTables(demoCtx, demoTableCount)
+	go registry.sweepIdleTables(time.Minute)
 
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		upstreams := deps.check()
+		status, _ := rollup(upstreams)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    status,
+			"service":   "game-state",
+			"tables":    registry.Count(),
+			"upstreams": upstreams,
+			"sse": map[string]int{
+				"connections": registry.SSEConnections(),
+				"maxPerTable": maxSubscribers,
+			},
+		})
+	})
+
+	// GET /ready — 503 until the bank is reachable. /health always answers
+	// 200 so a liveness probe doesn't restart us over someone else's outage.
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		upstreams := deps.check()
+		_, code := rollup(upstreams)
+		status := "ready"
+		if code != http.StatusOK {
+			status = "not_ready"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":    status,
+			"service":   "game-state",
+			"upstreams": upstreams,
+		})
+	})
+
+	// GET /rules — table rules summary
+	mux.HandleFunc("/rules", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "healthy",
-			"service": "game-state",
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"shoeMode":         shoeMode,
+			"deckCount":        shoeDeckCount,
+			"dealerHitsSoft17": defaultDealerHitsSoft17,
+			"dealerStrategy":   strategyFor(defaultDealerHitsSoft17),
+			"handEvaluator":    evaluatorMode(),
 		})
 	})
 
@@ -1148,34 +3201,7 @@ func main() {
 	// POST /tables/create — create a player-owned table
 	// Reached via gateway rewrite: /api/game/create → /tables/create
 	mux.HandleFunc("/tables/create", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		if r.Method != http.MethodPost {
-			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
-			return
-		}
-		var req struct {
-			PlayerID   string `json:"playerId"`
-			PlayerName string `json:"playerName"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
-			http.Error(w, `{"error":"playerId required"}`, http.StatusBadRequest)
-			return
-		}
-		if req.PlayerName == "" {
-			req.PlayerName = "Player"
-		}
-		table := registry.CreatePlayerTable(req.PlayerID, req.PlayerName)
-		s := table.GetState()
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"tableId":  s.TableID,
-			"phase":    s.Phase,
-			"playerId": req.PlayerID,
-		})
+		createTableHandler(w, r, registry)
 	})
 
 	// GET /tables/{id} - state snapshot
@@ -1201,9 +3227,74 @@ func main() {
 		// /tables/{id}/join
 		if len(path) > 8 && path[len(path)-5:] == "/join" {
 			tableID := path[8 : len(path)-5]
-			table := registry.GetOrCreate(tableID)
 			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(table.GetState())
+			if r.Method != http.MethodPost {
+				if table := registry.GetOrCreate(tableID); table != nil {
+					json.NewEncoder(w).Encode(table.GetState())
+					return
+				}
+				http.NotFound(w, r)
+				return
+			}
+			var req struct {
+				PlayerID   string `json:"playerId"`
+				PlayerName string `json:"playerName"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if caller := r.Header.Get("X-Player-ID"); caller != "" {
+				req.PlayerID = caller
+			}
+			if req.PlayerID == "" {
+				http.Error(w, `{"error":"playerId required"}`, http.StatusBadRequest)
+				return
+			}
+			if req.PlayerName == "" {
+				req.PlayerName = "Player"
+			}
+			table, err := registry.Join(requestID(r), tableID, req.PlayerID, req.PlayerName)
+			switch {
+			case errors.Is(err, errTableNotFound):
+				http.Error(w, `{"error":"table not found"}`, http.StatusNotFound)
+			case errors.Is(err, errTableFull):
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": "table full", "maxSeats": maxSeats})
+			default:
+				json.NewEncoder(w).Encode(table.GetState())
+			}
+			return
+		}
+
+		// /tables/{id}/history
+		if len(path) > 8 && path[len(path)-8:] == "/history" {
+			tableID := path[8 : len(path)-8]
+			w.Header().Set("Content-Type", "application/json")
+			table, ok := registry.Get(tableID)
+			if !ok {
+				http.Error(w, `{"error":"table not found"}`, http.StatusNotFound)
+				return
+			}
+			limit := 0
+			if v := r.URL.Query().Get("limit"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 {
+					http.Error(w, `{"error":"limit must be a positive integer"}`, http.StatusBadRequest)
+					return
+				}
+				limit = n
+			}
+			hands := table.History(limit)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"tableId": tableID,
+				"hands":   hands,
+				"count":   len(hands),
+			})
+			return
+		}
+
+		// /tables/{id}/leave
+		if len(path) > 8 && path[len(path)-6:] == "/leave" {
+			tableID := path[8 : len(path)-6]
+			leaveHandler(w, r, registry, tableID)
 			return
 		}
 
@@ -1222,7 +3313,59 @@ func main() {
 		json.NewEncoder(w).Encode(table.GetState())
 	})
 
+	// GET /players/{id}/tables — a player's live and recently closed tables
+	// Reached via gateway rewrite: /api/players/ → /players/ (session scope required)
+	mux.HandleFunc("/players/", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if r.Method != http.MethodGet || len(path) <= 16 || path[len(path)-7:] != "/tables" {
+			http.NotFound(w, r)
+			return
+		}
+		playerID := path[9 : len(path)-7]
+		w.Header().Set("Content-Type", "application/json")
+		// Owner check — the gateway injects X-Player-ID from the session token
+		if caller := r.Header.Get("X-Player-ID"); caller == "" || caller != playerID {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "not your tables"})
+			return
+		}
+		active, recent := registry.PlayerTables(playerID)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"playerId": playerID,
+			"active":   active,
+			"recent":   recent,
+		})
+	})
+
 	// POST /demo/pause — toggle demo loop on/off
+	// POST /demo/speed {"multiplier": 2} — demo pacing, clamped to 0.25–4;
+	// GET reads it
+	mux.HandleFunc("/demo/speed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req struct {
+				Multiplier *float64 `json:"multiplier"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Multiplier == nil ||
+				math.IsNaN(*req.Multiplier) || *req.Multiplier <= 0 {
+				http.Error(w, `{"error":"multiplier must be a positive number"}`, http.StatusBadRequest)
+				return
+			}
+			speed := setDemoSpeed(*req.Multiplier)
+			log.Printf("[demo] speed set to %gx", speed)
+		default:
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"multiplier": currentDemoSpeed(),
+			"min":        minDemoSpeed,
+			"max":        maxDemoSpeed,
+		})
+	})
+
 	mux.HandleFunc("/demo/pause", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -1243,11 +3386,37 @@ func main() {
 
 	port := getEnv("PORT", "3001")
 	log.Printf("🃏 Game State service starting on :%s", port)
-	log.Printf("   Demo table: %s", demoTableID)
+	log.Printf("   Demo tables: %d (first %s)", demoTableCount, demoTableID(1))
+	log.Printf("   Hand evaluation: %s", evaluatorMode())
+	if !useRemoteEvaluator {
+		delete(healthDeps, "hand-evaluator") // not a dependency when scoring locally
+	}
+
+	srv := &http.Server{Addr: ":" + port, Handler: corsMiddleware(mux)}
+	// SSE streams never go idle on their own — end them so Shutdown can drain
+	srv.RegisterOnShutdown(registry.closeSubscribers)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-ctx.Done()
 
-	if err := http.ListenAndServe(":"+port, corsMiddleware(mux)); err != nil {
-		log.Fatal(err)
+	log.Printf("[game-state] shutting down — settling open hands (grace %s)", shutdownGrace)
+	atomic.StoreInt32(&draining, 1)
+	stopDemo()
+	registry.settleForShutdown(shutdownGrace, demoDone)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[game-state] shutdown: %v — closing remaining connections", err)
+		srv.Close()
 	}
+	log.Printf("[game-state] stopped")
 }
 
 func sseHandler(w http.ResponseWriter, r *http.Request, registry *Registry, tableID string) {
@@ -1267,11 +3436,20 @@ func sseHandler(w http.ResponseWriter, r *http.Request, registry *Registry, tabl
 		http.Error(w, "table not found", http.StatusNotFound)
 		return
 	}
-	ch := table.Subscribe()
+	ch, err := table.Subscribe()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"table %s already has %d live streams; try again later"}`, tableID, maxSubscribers),
+			http.StatusServiceUnavailable)
+		return
+	}
 	defer table.Unsubscribe(ch)
 
 	// Send current state immediately on connect
-	sendSSEEvent(w, flusher, "game_state", table.GetState())
+	sendSSEEvent(w, flusher, "game_state", guardState(table.GetState()))
+
+	// Keepalive comments only go out when the stream has been quiet
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
 
 	for {
 		select {
@@ -1280,19 +3458,181 @@ func sseHandler(w http.ResponseWriter, r *http.Request, registry *Registry, tabl
 				return
 			}
 			sendSSEEvent(w, flusher, "game_state", state)
+			keepalive.Reset(sseKeepalive)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
 		case <-r.Context().Done():
 			return
 		}
 	}
 }
 
+// sseKeepalive is how long an SSE stream may stay silent before a comment
+// line is sent to keep proxies from closing it (e.g. a long waiting phase).
+var sseKeepalive = time.Duration(getEnvInt("SSE_KEEPALIVE_SECONDS", 15)) * time.Second
+
+// sendSSEEvent writes one event. state is already trimmed — Broadcast runs
+// guardState once for every subscriber, so a trim is logged once.
 func sendSSEEvent(w http.ResponseWriter, flusher http.Flusher, eventType string, state GameState) {
 	evt := SSEEvent{Type: eventType, Data: state}
 	data, _ := json.Marshal(evt)
+	if len(data) > maxSSEPayloadBytes {
+		logf("warn", logFields{"tableId": state.TableID}, "[guard] %s payload is %d bytes (expected < %d)",
+			eventType, len(data), maxSSEPayloadBytes)
+	}
 	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
 	flusher.Flush()
 }
 
+// leaveHandler takes the session player off a table: POST /tables/{id}/leave.
+func leaveHandler(w http.ResponseWriter, r *http.Request, registry *Registry, tableID string) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	playerID := r.Header.Get("X-Player-ID")
+	if playerID == "" {
+		http.Error(w, `{"error":"sign in to leave a table"}`, http.StatusUnauthorized)
+		return
+	}
+	chips, closed, err := registry.Leave(requestID(r), tableID, playerID)
+	switch {
+	case errors.Is(err, errTableNotFound):
+		http.Error(w, `{"error":"table not found"}`, http.StatusNotFound)
+	case errors.Is(err, errNotSeated):
+		http.Error(w, `{"error":"not seated at this table"}`, http.StatusNotFound)
+	case errors.Is(err, errHandInProgress):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "hand in progress — finish it before leaving"})
+	case errors.Is(err, errRefundFailed):
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "bank unavailable — open bet could not be refunded"})
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"left":        true,
+			"tableId":     tableID,
+			"tableClosed": closed,
+			"chips":       chips,
+		})
+	}
+}
+
+// createTableHandler opens the caller's own table: POST /tables/create.
+func createTableHandler(w http.ResponseWriter, r *http.Request, registry *Registry) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		PlayerID         string `json:"playerId"`
+		PlayerName       string `json:"playerName"`
+		BetWindowSeconds int    `json:"betWindowSeconds"`
+		DealerHitsSoft17 *bool  `json:"dealerHitsSoft17"`
+		DealerStrategy   string `json:"dealerStrategy"`
+		MinBet           *int   `json:"minBet"`
+		MaxBet           *int   `json:"maxBet"`
+		StartingBalance  int    `json:"startingBalance"` // new accounts only; 0 = bank default
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if caller := r.Header.Get("X-Player-ID"); caller != "" {
+		req.PlayerID = caller
+	}
+	if err != nil || req.PlayerID == "" {
+		http.Error(w, `{"error":"playerId required"}`, http.StatusBadRequest)
+		return
+	}
+	if _, ok := dealerStrategies[req.DealerStrategy]; req.DealerStrategy != "" && !ok {
+		http.Error(w, `{"error":"dealerStrategy must be standard, hit-soft-17 or spanish"}`, http.StatusBadRequest)
+		return
+	}
+	minBet, maxBet := defaultMinBet, defaultMaxBet
+	if req.MinBet != nil {
+		minBet = *req.MinBet
+	}
+	if req.MaxBet != nil {
+		maxBet = *req.MaxBet
+	}
+	if minBet <= 0 || minBet > maxBet {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "minBet must be positive and no greater than maxBet",
+			"minBet": minBet,
+			"maxBet": maxBet,
+		})
+		return
+	}
+	if req.StartingBalance < 0 || req.StartingBalance > maxStartingBalance {
+		http.Error(w, fmt.Sprintf(`{"error":"startingBalance must be between 1 and %d"}`, maxStartingBalance),
+			http.StatusBadRequest)
+		return
+	}
+	if req.PlayerName == "" {
+		req.PlayerName = "Player"
+	}
+	table, created := registry.CreatePlayerTable(requestID(r), req.PlayerID, req.PlayerName, req.StartingBalance)
+	if !created && seatIndex(table.GetState(), req.PlayerID) < 0 {
+		// The owner left a table others are still playing at — sit back down
+		_, err := registry.Join(requestID(r), table.GetState().TableID, req.PlayerID, req.PlayerName)
+		switch {
+		case errors.Is(err, errTableFull):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "table full", "maxSeats": maxSeats})
+			return
+		case err != nil:
+			http.Error(w, `{"error":"table closed — try again"}`, http.StatusConflict)
+			return
+		}
+	}
+	// Rules are fixed when the table opens; an existing table, maybe mid-hand, keeps its own
+	if created {
+		if req.BetWindowSeconds > 0 {
+			table.mu.Lock()
+			table.state.BetWindowSecs = req.BetWindowSeconds
+			table.mu.Unlock()
+		}
+		// A strategy wins over the bare H17 flag; the two are kept in step
+		switch {
+		case req.DealerStrategy != "":
+			table.mu.Lock()
+			table.state.DealerStrategy = req.DealerStrategy
+			table.state.DealerHitsSoft17 = dealerStrategies[req.DealerStrategy]
+			table.mu.Unlock()
+		case req.DealerHitsSoft17 != nil:
+			table.mu.Lock()
+			table.state.DealerHitsSoft17 = *req.DealerHitsSoft17
+			table.state.DealerStrategy = strategyFor(*req.DealerHitsSoft17)
+			table.mu.Unlock()
+		}
+		if req.MinBet != nil || req.MaxBet != nil {
+			table.mu.Lock()
+			table.state.MinBet, table.state.MaxBet = minBet, maxBet
+			table.mu.Unlock()
+		}
+	}
+	s := table.GetState()
+	chips := 0
+	if i := seatIndex(s, req.PlayerID); i >= 0 {
+		chips = s.Players[i].Chips
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tableId":        s.TableID,
+		"phase":          s.Phase,
+		"playerId":       req.PlayerID,
+		"minBet":         s.MinBet,
+		"maxBet":         s.MaxBet,
+		"dealerStrategy": s.DealerStrategy,
+		"chips":          chips,
+	})
+}
+
 func actionHandler(w http.ResponseWriter, r *http.Request, registry *Registry, tableID string) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -1322,15 +3662,46 @@ func actionHandler(w http.ResponseWriter, r *http.Request, registry *Registry, t
 		return
 	}
 
-	// Validate action is legal for current phase
+	rid := requestID(r)
+	w.Header().Set("X-Request-ID", rid)
+
+	// Route the action to a seat. The gateway injects X-Player-ID from the
+	// session token; without it nobody can say whose seat this is, and the
+	// body's playerId is never trusted.
+	action.PlayerID = r.Header.Get("X-Player-ID")
+	if action.PlayerID == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": false,
+			"code":     "session_required",
+			"message":  "sign in to act at this table",
+		})
+		return
+	}
 	s := table.GetState()
+	seat := seatIndex(s, action.PlayerID)
+	if seat < 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": false,
+			"message":  "not seated at this table",
+		})
+		return
+	}
+
+	// Validate action is legal for current phase
 	valid := false
 	switch s.Phase {
 	case "waiting":
 		valid = action.Action == "bet"
+	case "insurance":
+		valid = action.Action == "insurance" || action.Action == "no_insurance"
 	case "player_turn":
 		valid = action.Action == "hit" || action.Action == "stand" ||
-			action.Action == "double" || action.Action == "split"
+			action.Action == "double" || action.Action == "split" ||
+			action.Action == "surrender"
 	}
 	if !valid {
 		w.Header().Set("Content-Type", "application/json")
@@ -1341,13 +3712,208 @@ func actionHandler(w http.ResponseWriter, r *http.Request, registry *Registry, t
 		})
 		return
 	}
+	if s.Phase == "player_turn" && (s.ActivePlayerID == nil || *s.ActivePlayerID != action.PlayerID) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": false,
+			"message":  "not your turn",
+		})
+		return
+	}
+	if deadline, err := time.Parse(time.RFC3339, s.TurnExpiresAt); err == nil && time.Now().After(deadline) &&
+		(s.Phase == "player_turn" || s.Phase == "insurance") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": false,
+			"code":     "turn_expired",
+			"message":  "decision clock ran out",
+		})
+		return
+	}
+
+	// Bet amounts are checked here, not clamped later — a bad amount is the
+	// client's error, not something for the bank to catch
+	if action.Action == "bet" {
+		if msg := validateBetAmount(s, action.Amount); msg != "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"accepted": false,
+				"code":     "invalid_amount",
+				"message":  msg,
+				"minBet":   s.MinBet,
+				"maxBet":   s.MaxBet,
+			})
+			return
+		}
+	}
+
+	// No new money goes on the table once shutdown has begun
+	if action.Action == "bet" && atomic.LoadInt32(&draining) == 1 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": false,
+			"code":     "shutting_down",
+			"message":  "table service is restarting — try again shortly",
+		})
+		return
+	}
+
+	// Self-excluded players cannot bet until the cooldown lifts
+	if action.Action == "bet" {
+		until := callBankExclusion(rid, action.PlayerID)
+		table.mu.Lock()
+		i := seatIndex(table.state, action.PlayerID)
+		changed := i >= 0 && table.state.Players[i].ExcludedUntil != until
+		if changed {
+			table.state.Players[i].ExcludedUntil = until
+			table.state.Version++
+		}
+		snapshot := table.state
+		table.mu.Unlock()
+		if changed {
+			// Table shows "excluded until" (or clears it once the cooldown lifted)
+			table.Broadcast(snapshot)
+		}
+		if until != "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"accepted":      false,
+				"code":          "self_excluded",
+				"message":       "betting is paused for this account until " + until,
+				"excludedUntil": until,
+			})
+			return
+		}
+	}
+
+	// Insufficient funds — tell the client instead of silently clamping or dropping
+	if action.Action == "bet" {
+		if msg, chips := checkFunds(rid, table, action); msg != "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"accepted": false,
+				"code":     "insufficient_funds",
+				"message":  msg,
+				"balance":  chips,
+				"minBet":   s.MinBet,
+				"next":     "deposit",
+			})
+			return
+		}
+	}
+
+	// Insurance is at most half the main bet; no amount asks for the full half
+	if action.Action == "insurance" {
+		p := s.Players[seat]
+		maxStake := p.CurrentBet / 2
+		stake := action.Amount
+		if stake == 0 {
+			stake = maxStake
+		}
+		switch {
+		case stake <= 0 || stake > maxStake:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"accepted":     false,
+				"code":         "invalid_amount",
+				"message":      fmt.Sprintf("insurance must be between 1 and %d — half the bet", maxStake),
+				"maxInsurance": maxStake,
+			})
+			return
+		case stake > p.Chips:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"accepted":     false,
+				"code":         "insufficient_funds",
+				"message":      fmt.Sprintf("insurance of %d needs more than your %d chips", stake, p.Chips),
+				"balance":      p.Chips,
+				"maxInsurance": maxStake,
+			})
+			return
+		}
+	}
+
+	// One action per player at a time; the rest of the table queues behind it
+	if !table.beginAction(action.PlayerID) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": false,
+			"code":     "action_in_progress",
+			"message":  "your previous action is still being processed",
+		})
+		return
+	}
 
 	// Respond 202 immediately, process async
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{"accepted": true, "message": "processing"})
 
-	go processPlayerAction(table, action)
+	go func() {
+		defer table.endAction(action.PlayerID)
+		table.serialized(func() {
+			table.setRequestID(rid)
+			processPlayerAction(table, action)
+		})
+	}()
+}
+
+// validateBetAmount returns a message when amount is not a positive bet
+// within the table limits, or "" when it is acceptable. Whether the player
+// can cover it is checkFunds' job.
+func validateBetAmount(s GameState, amount int) string {
+	switch {
+	case amount <= 0:
+		return "bet amount must be a positive number of chips"
+	case amount < s.MinBet:
+		return fmt.Sprintf("bet of %d is below the table minimum of %d", amount, s.MinBet)
+	case amount > s.MaxBet:
+		return fmt.Sprintf("bet of %d is above the table maximum of %d", amount, s.MaxBet)
+	}
+	return ""
+}
+
+// checkFunds returns a message and the player's balance when they cannot
+// cover the bet. Table chips may be stale (e.g. a deposit since the last
+// hand), so the bank is asked before rejecting.
+func checkFunds(rid string, table *Table, action PlayerActionRequest) (string, int) {
+	s := table.GetState()
+	i := seatIndex(s, action.PlayerID)
+	if i < 0 {
+		return "not seated at this table", 0
+	}
+	p := s.Players[i]
+	need := action.Amount
+	if need < s.MinBet {
+		need = s.MinBet
+	}
+	if p.Chips >= need {
+		return "", p.Chips
+	}
+	if balance := callBankBalance(rid, p.ID); balance >= 0 {
+		table.mu.Lock()
+		if j := seatIndex(table.state, p.ID); j >= 0 {
+			table.state.Players[j].Chips = balance
+		}
+		table.mu.Unlock()
+		if balance >= need {
+			return "", balance
+		}
+		p.Chips = balance
+	}
+	if p.Chips < s.MinBet {
+		return fmt.Sprintf("balance %d is below the table minimum of %d — deposit to keep playing", p.Chips, s.MinBet), p.Chips
+	}
+	return fmt.Sprintf("bet of %d exceeds balance %d", action.Amount, p.Chips), p.Chips
 }
 
 // ── Helpers ───────────────────────────────────────────────────────────────────
@@ -1384,10 +3950,18 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
-func defaultCards() []Card {
+func getEnvInt(key string, fallback int) int {
+	v := 0
+	if _, err := fmt.Sscanf(os.Getenv(key), "%d", &v); err != nil || v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+func randomCards(n int) []Card {
 	suits := []string{"hearts", "diamonds", "clubs", "spades"}
 	ranks := []string{"A", "2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K"}
-	cards := make([]Card, 4)
+	cards := make([]Card, n)
 	for i := range cards {
 		cards[i] = Card{
 			Suit: suits[rand.Intn(len(suits))],
@@ -1410,7 +3984,13 @@ func cardValue(c Card) int {
 	}
 }
 
-func estimateValue(hand []Card) int {
+// EvaluateHand scores a hand in-process, with the same rules as
+// hand-evaluator's evaluateHand: aces count 11 and drop to 1 one at a time
+// while the hand is over 21; soft means an ace is still counting 11;
+// blackjack is 21 on exactly two cards. Unknown ranks (a hidden card) count
+// 0. The two must agree — change both or neither; both are tested against
+// the hand table in contracts/testdata/hands.json.
+func EvaluateHand(hand []Card) HandResult {
 	total := 0
 	aces := 0
 	for _, c := range hand {
@@ -1430,5 +4010,10 @@ func estimateValue(hand []Card) int {
 		total -= 10
 		aces--
 	}
-	return total
+	return HandResult{
+		Value:       total,
+		IsSoft:      aces > 0 && total <= 21,
+		IsBlackjack: total == 21 && len(hand) == 2,
+		IsBust:      total > 21,
+	}
 }
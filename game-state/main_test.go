@@ -0,0 +1,1002 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServices is one httptest server standing in for every upstream
+// game-state calls: bank-service, deck-service, dealer-ai and observability.
+// It deals fives, counts dealt cards, and can hold a deal open (gate) so a
+// test can keep an action in flight.
+type fakeServices struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	dealt         int
+	balance       int
+	excludedUntil string
+	txSeq         int
+	betParents    []string      // parentTransactionId sent with each /bet
+	gate          chan struct{} // when set, /deal waits for it to close
+	deckDown      bool          // when set, /deal answers 503
+	deck          []Card        // dealt first, in order; 5♥ once it runs out
+	cancelled     []string      // transactionId of each /bet/cancel
+	payouts       []string      // "transactionId result" of each /payout
+}
+
+func newFakeServices(t *testing.T) *fakeServices {
+	t.Helper()
+	f := &fakeServices{balance: 1000}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+
+	saved := []string{bankServiceURL, deckServiceURL, handEvaluatorURL, dealerAIURL, observabilityURL}
+	savedRemote := useRemoteEvaluator
+	bankServiceURL, deckServiceURL, handEvaluatorURL, dealerAIURL, observabilityURL = f.URL, f.URL, f.URL, f.URL, f.URL
+	useRemoteEvaluator = false
+	t.Cleanup(func() {
+		bankServiceURL, deckServiceURL, handEvaluatorURL, dealerAIURL, observabilityURL =
+			saved[0], saved[1], saved[2], saved[3], saved[4]
+		useRemoteEvaluator = savedRemote
+		f.Close()
+	})
+	return f
+}
+
+func (f *fakeServices) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	path := r.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/deal"):
+		f.mu.Lock()
+		gate := f.gate
+		f.mu.Unlock()
+		if gate != nil {
+			<-gate
+		}
+		f.mu.Lock()
+		down := f.deckDown
+		f.mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		n := 1
+		if c, ok := body["count"].(float64); ok {
+			n = int(c)
+		}
+		f.mu.Lock()
+		f.dealt += n
+		cards := make([]Card, n)
+		for i := range cards {
+			cards[i] = Card{Suit: "hearts", Rank: "5"}
+			if len(f.deck) > 0 {
+				cards[i], f.deck = f.deck[0], f.deck[1:]
+			}
+		}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"cards": cards})
+	case path == "/shoe":
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	case path == "/self-exclude":
+		f.mu.Lock()
+		var until any
+		if f.excludedUntil != "" {
+			until = f.excludedUntil
+		}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"excludedUntil": until})
+	case path == "/balance":
+		f.mu.Lock()
+		json.NewEncoder(w).Encode(map[string]any{"balance": f.balance})
+		f.mu.Unlock()
+	case path == "/bet":
+		f.mu.Lock()
+		f.txSeq++
+		amount := 0
+		fmt.Sscanf(fmt.Sprint(body["amount"]), "%d", &amount)
+		f.balance -= amount
+		parent, _ := body["parentTransactionId"].(string)
+		f.betParents = append(f.betParents, parent)
+		resp := map[string]string{"transactionId": fmt.Sprintf("tx-%d", f.txSeq), "newBalance": fmt.Sprintf("%d.00", f.balance)}
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(resp)
+	case path == "/payout", path == "/bet/cancel":
+		f.mu.Lock()
+		txID, _ := body["transactionId"].(string)
+		if path == "/bet/cancel" {
+			f.cancelled = append(f.cancelled, txID)
+		} else {
+			f.payouts = append(f.payouts, fmt.Sprintf("%s %v", txID, body["result"]))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"newBalance": fmt.Sprintf("%d.00", f.balance)})
+		f.mu.Unlock()
+	case path == "/open-bets":
+		w.Write([]byte(`{"openBets":[]}`))
+	case path == "/decide":
+		w.Write([]byte(`{"action":"stand"}`))
+	default:
+		w.Write([]byte(`{}`))
+	}
+}
+
+func (f *fakeServices) cardsDealt() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.dealt
+}
+
+// newTestTable creates a player table for playerID against the fakes.
+func newTestTable(t *testing.T, playerID string) (*Registry, *Table) {
+	t.Helper()
+	registry := NewRegistry()
+	table, _ := registry.CreatePlayerTable("test", playerID, "Tester", 0)
+	t.Cleanup(func() {
+		// Let a running action finish, then stop the clocks before the
+		// fakes and their URLs go away
+		table.actionMu.Lock()
+		defer table.actionMu.Unlock()
+		table.mu.Lock()
+		table.retireTimersLocked()
+		table.mu.Unlock()
+	})
+	return registry, table
+}
+
+// postAction sends an action through actionHandler as the gateway would.
+func postAction(registry *Registry, tableID, playerID string, action map[string]any) (int, map[string]any) {
+	body, _ := json.Marshal(action)
+	req := httptest.NewRequest(http.MethodPost, "/tables/"+tableID+"/action", bytes.NewReader(body))
+	req.Header.Set("X-Player-ID", playerID)
+	rec := httptest.NewRecorder()
+	actionHandler(rec, req, registry, tableID)
+	var out map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+// waitForPhase polls the table until it reaches phase or the test times out.
+func waitForPhase(t *testing.T, table *Table, phase string) GameState {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if s := table.GetState(); s.Phase == phase {
+			return s
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("table never reached phase %q (at %q)", phase, table.GetState().Phase)
+	return GameState{}
+}
+
+func TestDeckFailureCancelsTheHand(t *testing.T) {
+	f := newFakeServices(t)
+	f.mu.Lock()
+	f.deckDown = true
+	f.mu.Unlock()
+	registry, table := newTestTable(t, "p-nodeck")
+	tableID := table.GetState().TableID
+
+	if code, out := postAction(registry, tableID, "p-nodeck", map[string]any{"action": "bet", "amount": 50}); code != http.StatusAccepted {
+		t.Fatalf("bet: %d %v", code, out)
+	}
+	// No made-up cards: the stake goes back and betting reopens
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		f.mu.Lock()
+		cancelled := append([]string(nil), f.cancelled...)
+		f.mu.Unlock()
+		if len(cancelled) > 0 {
+			if len(cancelled) != 1 || cancelled[0] != "tx-1" {
+				t.Errorf("cancelled %v, want the one bet tx-1", cancelled)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bet never cancelled (phase %q)", table.GetState().Phase)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	s := waitFor(t, table, "betting reopened", func(s GameState) bool {
+		return s.Phase == "waiting" && s.Players[0].Status == "waiting"
+	})
+	if p := s.Players[0]; p.BankTxID != "" || len(p.Hand) != 0 || p.CurrentBet != 0 || len(s.Dealer.Hand) != 0 {
+		t.Errorf("hand dealt without a shoe: player %+v dealer %+v", p, s.Dealer)
+	}
+	waitFor(t, table, "action finished", func(GameState) bool {
+		table.mu.RLock()
+		defer table.mu.RUnlock()
+		return !table.inFlight["p-nodeck"]
+	})
+	f.mu.Lock()
+	f.deckDown = false
+	f.mu.Unlock()
+
+	// The deck is back — the next bet deals
+	if code, out := postAction(registry, tableID, "p-nodeck", map[string]any{"action": "bet", "amount": 50}); code != http.StatusAccepted {
+		t.Fatalf("bet after recovery: %d %v", code, out)
+	}
+	waitForPhase(t, table, "player_turn")
+}
+
+// ── Self-exclusion ───────────────────────────────────────────────────────────
+
+func TestBetRejectedWhileSelfExcluded(t *testing.T) {
+	f := newFakeServices(t)
+	registry, table := newTestTable(t, "p-excluded")
+	until := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	f.mu.Lock()
+	f.excludedUntil = until
+	f.mu.Unlock()
+
+	code, out := postAction(registry, table.GetState().TableID, "p-excluded", map[string]any{"action": "bet", "amount": 50})
+	if code != http.StatusForbidden || out["code"] != "self_excluded" {
+		t.Fatalf("bet while excluded: %d %v, want 403 self_excluded", code, out)
+	}
+	if out["excludedUntil"] != until {
+		t.Errorf("excludedUntil = %v, want %s", out["excludedUntil"], until)
+	}
+	s := table.GetState()
+	if s.Phase != "waiting" || s.Players[0].ExcludedUntil != until {
+		t.Errorf("table after rejected bet: phase %q excludedUntil %q", s.Phase, s.Players[0].ExcludedUntil)
+	}
+}
+
+func TestBetAllowedAfterSelfExclusionExpires(t *testing.T) {
+	f := newFakeServices(t)
+	registry, table := newTestTable(t, "p-returning")
+	tableID := table.GetState().TableID
+
+	f.mu.Lock()
+	f.excludedUntil = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	f.mu.Unlock()
+	if code, _ := postAction(registry, tableID, "p-returning", map[string]any{"action": "bet", "amount": 50}); code != http.StatusForbidden {
+		t.Fatalf("bet while excluded: status %d, want 403", code)
+	}
+
+	// The bank lifts the freeze at expiry and stops reporting it
+	f.mu.Lock()
+	f.excludedUntil = ""
+	f.mu.Unlock()
+	code, out := postAction(registry, tableID, "p-returning", map[string]any{"action": "bet", "amount": 50})
+	if code != http.StatusAccepted {
+		t.Fatalf("bet after expiry: %d %v, want 202", code, out)
+	}
+	if s := table.GetState(); s.Players[0].ExcludedUntil != "" {
+		t.Errorf("excludedUntil still shown after expiry: %q", s.Players[0].ExcludedUntil)
+	}
+	waitForPhase(t, table, "player_turn")
+}
+
+// ── Dealer 17 rule ───────────────────────────────────────────────────────────
+
+// hand builds cards from ranks; suits don't matter to the dealer.
+func hand(ranks ...string) []Card {
+	cards := make([]Card, len(ranks))
+	for i, r := range ranks {
+		cards[i] = Card{Suit: "spades", Rank: r}
+	}
+	return cards
+}
+
+func TestDealerShouldHitSoft17(t *testing.T) {
+	cases := []struct {
+		name     string
+		cards    []Card
+		hitOnH17 bool
+		hitOnS17 bool
+	}{
+		{"soft 17 A+6", hand("A", "6"), true, false},
+		{"soft 17 A+A+5", hand("A", "A", "5"), true, false},
+		{"soft 17 6+A", hand("6", "A"), true, false},
+		{"hard 17 10+7", hand("10", "7"), false, false},
+		{"hard 17 A+6+K", hand("A", "6", "K"), false, false},
+		{"hard 16", hand("10", "6"), true, true},
+		{"soft 16 A+5", hand("A", "5"), true, true},
+		{"soft 18 A+7", hand("A", "7"), false, false},
+		{"hard 18", hand("10", "8"), false, false},
+	}
+	for _, c := range cases {
+		r := EvaluateHand(c.cards)
+		if got := dealerShouldHit(r.Value, r.IsSoft, true); got != c.hitOnH17 {
+			t.Errorf("%s (value %d soft %v) on H17: hit = %v, want %v", c.name, r.Value, r.IsSoft, got, c.hitOnH17)
+		}
+		if got := dealerShouldHit(r.Value, r.IsSoft, false); got != c.hitOnS17 {
+			t.Errorf("%s (value %d soft %v) on S17: hit = %v, want %v", c.name, r.Value, r.IsSoft, got, c.hitOnS17)
+		}
+	}
+}
+
+// ── Bet validation ───────────────────────────────────────────────────────────
+
+func TestValidateBetAmount(t *testing.T) {
+	s := GameState{MinBet: 10, MaxBet: 500}
+	cases := []struct {
+		amount int
+		want   string // substring of the message; empty: accepted
+	}{
+		{0, "positive"},
+		{-5, "positive"},
+		{9, "below the table minimum of 10"},
+		{10, ""},
+		{250, ""},
+		{500, ""},
+		{501, "above the table maximum of 500"},
+	}
+	for _, c := range cases {
+		got := validateBetAmount(s, c.amount)
+		if (c.want == "") != (got == "") || !strings.Contains(got, c.want) {
+			t.Errorf("validateBetAmount(%d) = %q, want %q", c.amount, got, c.want)
+		}
+	}
+}
+
+// setChips sets the seat's chips and what the fake bank reports.
+func setChips(f *fakeServices, table *Table, playerID string, chips, bank int) {
+	table.mu.Lock()
+	table.state.Players[seatIndex(table.state, playerID)].Chips = chips
+	table.mu.Unlock()
+	f.mu.Lock()
+	f.balance = bank
+	f.mu.Unlock()
+}
+
+func TestCheckFunds(t *testing.T) {
+	f := newFakeServices(t)
+	_, table := newTestTable(t, "p-funds")
+
+	cases := []struct {
+		name      string
+		chips     int
+		bank      int
+		amount    int
+		want      string // substring of the message; empty: accepted
+		wantChips int
+	}{
+		{"covered", 100, 100, 50, "", 100},
+		{"exactly covered", 100, 100, 100, "", 100},
+		{"over chips", 20, 20, 50, "bet of 50 exceeds balance 20", 20},
+		{"stale chips, bank covers", 20, 300, 50, "", 300},
+		{"below table minimum", 5, 5, 10, "below the table minimum of 10", 5},
+		{"zero balance", 0, 0, 10, "below the table minimum of 10", 0},
+	}
+	for _, c := range cases {
+		setChips(f, table, "p-funds", c.chips, c.bank)
+		got, chips := checkFunds("test", table, PlayerActionRequest{PlayerID: "p-funds", Action: "bet", Amount: c.amount})
+		if (c.want == "") != (got == "") || !strings.Contains(got, c.want) {
+			t.Errorf("%s: checkFunds = %q, want %q", c.name, got, c.want)
+		}
+		if chips != c.wantChips {
+			t.Errorf("%s: balance = %d, want %d", c.name, chips, c.wantChips)
+		}
+		// A fresher bank balance is written back to the seat
+		if seat := table.GetState().Players[0].Chips; seat != c.bank {
+			t.Errorf("%s: seat chips = %d, want the bank's %d", c.name, seat, c.bank)
+		}
+	}
+
+	if msg, _ := checkFunds("test", table, PlayerActionRequest{PlayerID: "someone-else", Action: "bet", Amount: 10}); msg == "" {
+		t.Error("checkFunds for an unseated player accepted the bet")
+	}
+}
+
+func TestBetAmountRejectedByActionHandler(t *testing.T) {
+	f := newFakeServices(t)
+	registry, table := newTestTable(t, "p-bets")
+	tableID := table.GetState().TableID
+	setChips(f, table, "p-bets", 40, 40)
+
+	cases := []struct {
+		name   string
+		amount int
+		status int
+		code   string
+	}{
+		{"zero", 0, http.StatusBadRequest, "invalid_amount"},
+		{"negative", -10, http.StatusBadRequest, "invalid_amount"},
+		{"over max", 501, http.StatusBadRequest, "invalid_amount"},
+		{"over chips", 50, http.StatusConflict, "insufficient_funds"},
+	}
+	for _, c := range cases {
+		code, out := postAction(registry, tableID, "p-bets", map[string]any{"action": "bet", "amount": c.amount})
+		if code != c.status || out["code"] != c.code {
+			t.Errorf("%s bet (%d): %d %v, want %d %s", c.name, c.amount, code, out, c.status, c.code)
+		}
+	}
+	if s := table.GetState(); s.Phase != "waiting" || s.Players[0].CurrentBet != 0 {
+		t.Errorf("rejected bets changed the table: phase %q bet %d", s.Phase, s.Players[0].CurrentBet)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.txSeq != 0 {
+		t.Errorf("%d bets reached the bank, want none", f.txSeq)
+	}
+}
+
+// ── In-flight actions ────────────────────────────────────────────────────────
+
+func TestSimultaneousHitsDrawOneCard(t *testing.T) {
+	f := newFakeServices(t)
+	registry, table := newTestTable(t, "p-double-click")
+	tableID := table.GetState().TableID
+
+	if code, out := postAction(registry, tableID, "p-double-click", map[string]any{"action": "bet", "amount": 50}); code != http.StatusAccepted {
+		t.Fatalf("bet: %d %v", code, out)
+	}
+	waitForPhase(t, table, "player_turn")
+	before := f.cardsDealt()
+
+	// Hold the deck so the first hit is still in flight when the second lands
+	gate := make(chan struct{})
+	release := sync.OnceFunc(func() { close(gate) })
+	t.Cleanup(release) // never leave a deal hanging on a failed test
+	f.mu.Lock()
+	f.gate = gate
+	f.mu.Unlock()
+
+	type reply struct {
+		code int
+		out  map[string]any
+	}
+	replies := make(chan reply, 2)
+	start := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			<-start
+			code, out := postAction(registry, tableID, "p-double-click", map[string]any{"action": "hit"})
+			replies <- reply{code, out}
+		}()
+	}
+	close(start)
+	got := map[int]int{}
+	for i := 0; i < 2; i++ {
+		r := <-replies
+		got[r.code]++
+		if r.code == http.StatusConflict && r.out["code"] != "action_in_progress" {
+			t.Errorf("rejected hit: code %v, want action_in_progress", r.out["code"])
+		}
+	}
+	if got[http.StatusAccepted] != 1 || got[http.StatusConflict] != 1 {
+		t.Fatalf("replies %v, want one 202 and one 409", got)
+	}
+
+	release()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(table.GetState().Players[0].Hand) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Give a wrongly queued second hit time to land before counting
+	time.Sleep(300 * time.Millisecond)
+	if n := f.cardsDealt() - before; n != 1 {
+		t.Errorf("two simultaneous hits drew %d cards, want 1", n)
+	}
+	if hand := table.GetState().Players[0].Hand; len(hand) != 3 {
+		t.Errorf("player hand has %d cards, want 3", len(hand))
+	}
+}
+
+// ── Hand evaluation ──────────────────────────────────────────────────────────
+
+// handFixture is one row of contracts/testdata/hands.json, the table
+// hand-evaluator's own tests score too.
+type handFixture struct {
+	Name      string   `json:"name"`
+	Cards     []string `json:"cards"`
+	Value     int      `json:"value"`
+	Soft      bool     `json:"soft"`
+	Blackjack bool     `json:"blackjack"`
+	Bust      bool     `json:"bust"`
+}
+
+func (h handFixture) want() HandResult {
+	return HandResult{Value: h.Value, IsSoft: h.Soft, IsBlackjack: h.Blackjack, IsBust: h.Bust}
+}
+
+func loadHandFixtures(t *testing.T) []handFixture {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("..", "contracts", "testdata", "hands.json"))
+	if err != nil {
+		t.Fatalf("read shared hand table: %v", err)
+	}
+	var fixture struct {
+		Hands []handFixture `json:"hands"`
+	}
+	if err := json.Unmarshal(raw, &fixture); err != nil {
+		t.Fatalf("parse shared hand table: %v", err)
+	}
+	if len(fixture.Hands) == 0 {
+		t.Fatal("shared hand table is empty")
+	}
+	return fixture.Hands
+}
+
+func TestEvaluateHandMatchesSharedTable(t *testing.T) {
+	for _, h := range loadHandFixtures(t) {
+		if got := EvaluateHand(hand(h.Cards...)); got != h.want() {
+			t.Errorf("%s %v: EvaluateHand = %+v, want %+v", h.Name, h.Cards, got, h.want())
+		}
+	}
+}
+
+func TestEvaluatorFallbackMatchesSharedTable(t *testing.T) {
+	fixtures := loadHandFixtures(t)
+	hands := make([][]Card, len(fixtures))
+	for i, h := range fixtures {
+		hands[i] = hand(h.Cards...)
+	}
+
+	// Observability counts the degraded hand-evaluator events
+	var mu sync.Mutex
+	degraded := 0
+	obs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		json.NewDecoder(r.Body).Decode(&event)
+		if event["callee"] == "hand-evaluator" && event["degraded"] == true {
+			mu.Lock()
+			degraded++
+			mu.Unlock()
+		}
+	}))
+	defer obs.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"down"}`, http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	garbled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"value":`))
+	}))
+	defer garbled.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	savedURL, savedObs, savedRemote := handEvaluatorURL, observabilityURL, useRemoteEvaluator
+	defer func() { handEvaluatorURL, observabilityURL, useRemoteEvaluator = savedURL, savedObs, savedRemote }()
+	useRemoteEvaluator = true
+	observabilityURL = obs.URL
+
+	outages := []struct{ name, url string }{
+		{"unreachable", unreachable.URL},
+		{"500", failing.URL},
+		{"bad body", garbled.URL},
+	}
+	for _, o := range outages {
+		handEvaluatorURL = o.url
+		for i, h := range fixtures {
+			if got := callHandEvaluator("test", hands[i]); got != h.want() {
+				t.Errorf("%s: %s %v: fallback = %+v, want %+v", o.name, h.Name, h.Cards, got, h.want())
+			}
+		}
+		results := callHandEvaluatorBatch("test", hands)
+		if len(results) != len(fixtures) {
+			t.Fatalf("%s: batch fallback returned %d results for %d hands", o.name, len(results), len(fixtures))
+		}
+		for i, h := range fixtures {
+			if results[i] != h.want() {
+				t.Errorf("%s: batch %s %v: fallback = %+v, want %+v", o.name, h.Name, h.Cards, results[i], h.want())
+			}
+		}
+	}
+
+	// One degraded event per call: every single hand plus one batch, per outage
+	want := len(outages) * (len(fixtures) + 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := degraded
+		mu.Unlock()
+		if got >= want || time.Now().After(deadline) {
+			if got != want {
+				t.Errorf("%d degraded events reported, want %d", got, want)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// ── Shared tables ────────────────────────────────────────────────────────────
+
+// createTable sends POST /tables/create as playerID through the gateway.
+func createTable(registry *Registry, playerID string, body map[string]any) (int, map[string]any) {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/tables/create", bytes.NewReader(data))
+	req.Header.Set("X-Player-ID", playerID)
+	rec := httptest.NewRecorder()
+	createTableHandler(rec, req, registry)
+	var out map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+// waitFor polls the table until cond holds or the test times out.
+func waitFor(t *testing.T, table *Table, what string, cond func(GameState) bool) GameState {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if s := table.GetState(); cond(s) {
+			return s
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("table never reached: %s (phase %q)", what, table.GetState().Phase)
+	return GameState{}
+}
+
+func TestActionRequiresSessionPlayer(t *testing.T) {
+	newFakeServices(t)
+	registry, table := newTestTable(t, "p-owner")
+	tableID := table.GetState().TableID
+
+	// No X-Player-ID: the body's playerId names a real seat but isn't believed
+	body, _ := json.Marshal(map[string]any{"action": "bet", "amount": 50, "playerId": "p-owner"})
+	rec := httptest.NewRecorder()
+	actionHandler(rec, httptest.NewRequest(http.MethodPost, "/tables/"+tableID+"/action", bytes.NewReader(body)), registry, tableID)
+	var out map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	if rec.Code != http.StatusUnauthorized || out["code"] != "session_required" {
+		t.Errorf("action without X-Player-ID: %d %v, want 401 session_required", rec.Code, out)
+	}
+	if s := table.GetState(); s.Players[0].Status != "waiting" || s.Players[0].CurrentBet != 0 {
+		t.Errorf("unauthenticated bet changed the seat: %+v", s.Players[0])
+	}
+
+	if code, _ := postAction(registry, tableID, "p-stranger", map[string]any{"action": "bet", "amount": 50}); code != http.StatusForbidden {
+		t.Errorf("action by an unseated player: status %d, want 403", code)
+	}
+}
+
+func TestLeaveRequiresSessionPlayer(t *testing.T) {
+	newFakeServices(t)
+	registry, table := newTestTable(t, "p-owner")
+	tableID := table.GetState().TableID
+	if _, err := registry.Join("test", tableID, "p-friend", "Friend"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	leave := func(playerID string, body map[string]any) (int, map[string]any) {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/tables/"+tableID+"/leave", bytes.NewReader(data))
+		if playerID != "" {
+			req.Header.Set("X-Player-ID", playerID)
+		}
+		rec := httptest.NewRecorder()
+		leaveHandler(rec, req, registry, tableID)
+		var out map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &out)
+		return rec.Code, out
+	}
+
+	// The body can't name someone else's seat
+	if code, _ := leave("", map[string]any{"playerId": "p-owner"}); code != http.StatusUnauthorized {
+		t.Errorf("leave without X-Player-ID: status %d, want 401", code)
+	}
+	code, out := leave("p-friend", map[string]any{"playerId": "p-owner"})
+	if code != http.StatusOK || out["tableClosed"] != false {
+		t.Fatalf("friend leaves: %d %v", code, out)
+	}
+	if s := table.GetState(); len(s.Players) != 1 || s.Players[0].ID != "p-owner" {
+		t.Errorf("seats after the friend left: %+v, want only p-owner", s.Players)
+	}
+}
+
+func TestLastLeaveRacingJoinKeepsTheJoiner(t *testing.T) {
+	newFakeServices(t)
+	for i := 0; i < 50; i++ {
+		registry, table := newTestTable(t, "p-owner")
+		tableID := table.GetState().TableID
+
+		var wg sync.WaitGroup
+		var joinErr error
+		var closed bool
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, closed, _ = registry.Leave("test", tableID, "p-owner")
+		}()
+		go func() {
+			defer wg.Done()
+			_, joinErr = registry.Join("test", tableID, "p-joiner", "Joiner")
+		}()
+		wg.Wait()
+
+		// Either the joiner sat down first and kept the table, or was turned
+		// away — never seated at a table that's gone
+		_, open := registry.Get(tableID)
+		switch {
+		case joinErr == nil && (closed || !open):
+			t.Fatalf("round %d: joiner seated but table closed=%v open=%v", i, closed, open)
+		case joinErr != nil && !errors.Is(joinErr, errTableNotFound):
+			t.Fatalf("round %d: join: %v", i, joinErr)
+		case joinErr != nil && open:
+			t.Fatalf("round %d: join refused but the table is still open", i)
+		}
+	}
+}
+
+func TestSharedTableTurnsFollowSeats(t *testing.T) {
+	newFakeServices(t)
+	registry, table := newTestTable(t, "p-first")
+	tableID := table.GetState().TableID
+	if _, err := registry.Join("test", tableID, "p-second", "Second"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+
+	// The deal waits for every seat to bet
+	if code, out := postAction(registry, tableID, "p-first", map[string]any{"action": "bet", "amount": 20}); code != http.StatusAccepted {
+		t.Fatalf("first bet: %d %v", code, out)
+	}
+	waitFor(t, table, "first seat ready", func(s GameState) bool { return s.Players[0].Status == "ready" })
+	if s := table.GetState(); s.Phase != "waiting" {
+		t.Fatalf("dealt with one of two seats bet: phase %q", s.Phase)
+	}
+	if code, out := postAction(registry, tableID, "p-second", map[string]any{"action": "bet", "amount": 30}); code != http.StatusAccepted {
+		t.Fatalf("second bet: %d %v", code, out)
+	}
+	s := waitForPhase(t, table, "player_turn")
+	for _, p := range s.Players {
+		if len(p.Hand) != 2 {
+			t.Errorf("%s dealt %d cards, want 2", p.ID, len(p.Hand))
+		}
+	}
+	if s.ActivePlayerID == nil || *s.ActivePlayerID != "p-first" {
+		t.Fatalf("first turn: %v, want p-first", s.ActivePlayerID)
+	}
+
+	// Out of turn is refused; the turn passes in seat order
+	if code, out := postAction(registry, tableID, "p-second", map[string]any{"action": "hit"}); code != http.StatusConflict {
+		t.Errorf("out-of-turn hit: %d %v, want 409", code, out)
+	}
+	if code, out := postAction(registry, tableID, "p-first", map[string]any{"action": "stand"}); code != http.StatusAccepted {
+		t.Fatalf("first stand: %d %v", code, out)
+	}
+	waitFor(t, table, "second seat's turn", func(s GameState) bool {
+		return s.ActivePlayerID != nil && *s.ActivePlayerID == "p-second"
+	})
+	if code, out := postAction(registry, tableID, "p-second", map[string]any{"action": "stand"}); code != http.StatusAccepted {
+		t.Fatalf("second stand: %d %v", code, out)
+	}
+
+	// Each seat settles its own bet: 5+5 against a dealer the fake dealer-ai
+	// stands on 5+5
+	s = waitFor(t, table, "hand settled", func(s GameState) bool { return s.LastResult != nil })
+	want := map[string]int{"p-first": 20, "p-second": 30}
+	if len(s.LastResult.Results) != 2 {
+		t.Fatalf("results %+v, want one per seat", s.LastResult.Results)
+	}
+	for _, r := range s.LastResult.Results {
+		if r.Outcome != "push" || r.BetAmount != want[r.PlayerID] {
+			t.Errorf("%s settled %s on %d, want push on %d", r.PlayerID, r.Outcome, r.BetAmount, want[r.PlayerID])
+		}
+	}
+}
+
+func TestCreateExistingTableKeepsItsRules(t *testing.T) {
+	f := newFakeServices(t)
+	registry := NewRegistry()
+
+	code, out := createTable(registry, "p-creator", map[string]any{"betWindowSeconds": 30})
+	if code != http.StatusCreated {
+		t.Fatalf("create: %d %v", code, out)
+	}
+	table, _ := registry.Get(out["tableId"].(string))
+
+	// Asking again returns the table as it is, with the caller's own chips
+	f.mu.Lock()
+	f.balance = 1234
+	f.mu.Unlock()
+	code, out = createTable(registry, "p-creator", map[string]any{"betWindowSeconds": 5})
+	if code != http.StatusCreated || out["chips"] != 1234.0 {
+		t.Errorf("create again: %d %v, want the creator's own 1234 chips", code, out)
+	}
+	if s := table.GetState(); s.BetWindowSecs != 30 {
+		t.Errorf("existing table changed: bet window %d, want 30", s.BetWindowSecs)
+	}
+
+	// The creator leaves a table a friend is still at, then comes back
+	tableID := table.GetState().TableID
+	if _, err := registry.Join("test", tableID, "p-friend", "Friend"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	table.mu.Lock()
+	table.state.Players[seatIndex(table.state, "p-friend")].Chips = 777
+	table.mu.Unlock()
+	if _, _, err := registry.Leave("test", tableID, "p-creator"); err != nil {
+		t.Fatalf("leave: %v", err)
+	}
+	code, out = createTable(registry, "p-creator", map[string]any{})
+	if code != http.StatusCreated || out["chips"] != 1234.0 {
+		t.Errorf("create after leaving: %d %v, want the creator's own 1234 chips", code, out)
+	}
+	if s := table.GetState(); seatIndex(s, "p-creator") < 0 || len(s.Players) != 2 {
+		t.Errorf("creator not seated again: %+v", s.Players)
+	}
+}
+
+// ── Insurance ────────────────────────────────────────────────────────────────
+
+// stackAceUp has the fake shoe deal the seat 5-5 against a dealer Ace, with
+// hole as the card the peek draws.
+func stackAceUp(f *fakeServices, hole Card) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	five := Card{Suit: "hearts", Rank: "5"}
+	f.deck = []Card{five, {Suit: "spades", Rank: "A"}, five, five, hole}
+}
+
+// payoutsSoFar returns the fake bank's settled payouts.
+func (f *fakeServices) payoutsSoFar() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.payouts...)
+}
+
+func TestInsuranceRejectsBadAmounts(t *testing.T) {
+	f := newFakeServices(t)
+	stackAceUp(f, Card{Suit: "clubs", Rank: "9"})
+	registry, table := newTestTable(t, "p-ins")
+	tableID := table.GetState().TableID
+
+	if code, out := postAction(registry, tableID, "p-ins", map[string]any{"action": "bet", "amount": 50}); code != http.StatusAccepted {
+		t.Fatalf("bet: %d %v", code, out)
+	}
+	waitForPhase(t, table, "insurance")
+
+	// Over half the bet is refused, not cut down to 25
+	code, out := postAction(registry, tableID, "p-ins", map[string]any{"action": "insurance", "amount": 30})
+	if code != http.StatusBadRequest || out["code"] != "invalid_amount" || out["maxInsurance"] != 25.0 {
+		t.Errorf("insurance of 30 on 50: %d %v, want 400 invalid_amount with maxInsurance 25", code, out)
+	}
+	// More than the chips left is refused, not dropped
+	table.mu.Lock()
+	table.state.Players[0].Chips = 10
+	table.mu.Unlock()
+	code, out = postAction(registry, tableID, "p-ins", map[string]any{"action": "insurance"})
+	if code != http.StatusConflict || out["code"] != "insufficient_funds" || out["balance"] != 10.0 {
+		t.Errorf("insurance of 25 with 10 chips: %d %v, want 409 insufficient_funds", code, out)
+	}
+	if p := table.GetState().Players[0]; p.InsuranceDone || p.InsuranceBet != 0 {
+		t.Errorf("refused insurance was recorded: %+v", p)
+	}
+
+	// A stake the seat can cover goes through
+	code, out = postAction(registry, tableID, "p-ins", map[string]any{"action": "insurance", "amount": 10})
+	if code != http.StatusAccepted {
+		t.Fatalf("insurance of 10: %d %v", code, out)
+	}
+	s := waitForPhase(t, table, "player_turn")
+	if p := s.Players[0]; p.InsuranceBet != 10 {
+		t.Errorf("insurance bet %d, want 10", p.InsuranceBet)
+	}
+}
+
+func TestInsuranceSettlesThroughBank(t *testing.T) {
+	cases := []struct {
+		name  string
+		hole  Card
+		want  []string // payouts, in order
+		phase string   // where the hand goes after insurance
+	}{
+		// Dealer natural: insurance pays, the main bet loses, the hand ends
+		{"dealer blackjack", Card{Suit: "spades", Rank: "K"}, []string{"tx-2 insurance", "tx-1 loss"}, "waiting"},
+		// No natural: insurance is lost and the seat plays on
+		{"no blackjack", Card{Suit: "clubs", Rank: "9"}, []string{"tx-2 loss"}, "player_turn"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeServices(t)
+			stackAceUp(f, c.hole)
+			registry, table := newTestTable(t, "p-settle")
+			tableID := table.GetState().TableID
+
+			if code, out := postAction(registry, tableID, "p-settle", map[string]any{"action": "bet", "amount": 50}); code != http.StatusAccepted {
+				t.Fatalf("bet: %d %v", code, out)
+			}
+			waitForPhase(t, table, "insurance")
+			if code, out := postAction(registry, tableID, "p-settle", map[string]any{"action": "insurance"}); code != http.StatusAccepted {
+				t.Fatalf("insurance: %d %v", code, out)
+			}
+
+			deadline := time.Now().Add(15 * time.Second)
+			for len(f.payoutsSoFar()) < len(c.want) && time.Now().Before(deadline) {
+				time.Sleep(20 * time.Millisecond)
+			}
+			if got := f.payoutsSoFar(); strings.Join(got, ",") != strings.Join(c.want, ",") {
+				t.Errorf("payouts %v, want %v", got, c.want)
+			}
+			s := waitForPhase(t, table, c.phase)
+			if p := s.Players[0]; p.InsuranceTxID != "" {
+				t.Errorf("insurance still open after settling: %+v", p)
+			}
+			// The insurance bet rides on the main bet at the bank
+			f.mu.Lock()
+			parents := strings.Join(f.betParents, ",")
+			f.mu.Unlock()
+			if parents != ",tx-1" {
+				t.Errorf("bet parents %q, want the insurance bet linked to tx-1", parents)
+			}
+		})
+	}
+}
+
+// ── Surrender ────────────────────────────────────────────────────────────────
+
+func TestSurrenderSettlesAtOnce(t *testing.T) {
+	f := newFakeServices(t)
+	registry, table := newTestTable(t, "p-surr")
+	tableID := table.GetState().TableID
+
+	// 5-5 against a dealer 5: no peek, straight to the seat's turn
+	if code, out := postAction(registry, tableID, "p-surr", map[string]any{"action": "bet", "amount": 40}); code != http.StatusAccepted {
+		t.Fatalf("bet: %d %v", code, out)
+	}
+	waitForPhase(t, table, "player_turn")
+	if code, out := postAction(registry, tableID, "p-surr", map[string]any{"action": "surrender"}); code != http.StatusAccepted {
+		t.Fatalf("surrender: %d %v", code, out)
+	}
+
+	s := waitFor(t, table, "hand settled", func(s GameState) bool { return s.LastResult != nil })
+	if len(s.LastResult.Results) != 1 {
+		t.Fatalf("results %+v, want the one seat", s.LastResult.Results)
+	}
+	if r := s.LastResult.Results[0]; r.Outcome != "surrender" || r.BetAmount != 40 {
+		t.Errorf("settled %s on %d, want surrender on 40", r.Outcome, r.BetAmount)
+	}
+	// Settled once, by the surrender — the dealer's turn doesn't pay it again
+	if got := f.payoutsSoFar(); strings.Join(got, ",") != "tx-1 surrender" {
+		t.Errorf("payouts %v, want [tx-1 surrender]", got)
+	}
+}
+
+// ── Peek ─────────────────────────────────────────────────────────────────────
+
+func TestTenUpPeeksForDealerBlackjack(t *testing.T) {
+	cases := []struct {
+		name  string
+		hole  Card
+		want  []string // payouts before the seat could act
+		phase string
+	}{
+		// Dealer natural: the hand ends before the seat plays it
+		{"dealer blackjack", Card{Suit: "spades", Rank: "A"}, []string{"tx-1 loss"}, "waiting"},
+		// No natural: the seat plays as usual
+		{"no blackjack", Card{Suit: "clubs", Rank: "9"}, nil, "player_turn"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeServices(t)
+			five := Card{Suit: "hearts", Rank: "5"}
+			f.mu.Lock()
+			f.deck = []Card{five, {Suit: "spades", Rank: "K"}, five, five, c.hole}
+			f.mu.Unlock()
+			registry, table := newTestTable(t, "p-peek")
+			tableID := table.GetState().TableID
+
+			if code, out := postAction(registry, tableID, "p-peek", map[string]any{"action": "bet", "amount": 50}); code != http.StatusAccepted {
+				t.Fatalf("bet: %d %v", code, out)
+			}
+			deadline := time.Now().Add(15 * time.Second)
+			for len(f.payoutsSoFar()) < len(c.want) && time.Now().Before(deadline) {
+				time.Sleep(20 * time.Millisecond)
+			}
+			s := waitForPhase(t, table, c.phase)
+			if c.want != nil && (s.LastResult == nil || s.LastResult.Results[0].Outcome != "loss") {
+				t.Errorf("result %+v, want the seat's loss to a dealer natural", s.LastResult)
+			}
+			if got := f.payoutsSoFar(); strings.Join(got, ",") != strings.Join(c.want, ",") {
+				t.Errorf("payouts %v, want %v", got, c.want)
+			}
+		})
+	}
+}
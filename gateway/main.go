@@ -1,18 +1,35 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"math/big"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -29,12 +46,26 @@ type ObservabilityEvent struct {
 	StatusCode int    `json:"statusCode"`
 	LatencyMs  int64  `json:"latencyMs"`
 	Protocol   string `json:"protocol"`
+	RequestID  string `json:"requestId,omitempty"`
+	Seq        int64  `json:"-"`                  // bus sequence number — the SSE event id
+	Attempt    int    `json:"attempt,omitempty"`  // set on upstream retries: 2 = first retry
+	Degraded   bool   `json:"degraded,omitempty"` // caller fell back to a local answer
 }
 
-// ObservabilityBus fans out events to all connected dashboard clients
+// observabilityHistory is how many recent events the bus keeps for replay.
+const observabilityHistory = 500
+
+// ObservabilityBus fans out events to all connected dashboard clients and
+// keeps a ring of recent events so a reconnecting client can catch up.
 type ObservabilityBus struct {
 	mu      sync.RWMutex
 	clients map[chan ObservabilityEvent]struct{}
+	dropped atomic.Int64 // events not delivered to a full client channel
+
+	seq     int64 // last sequence number assigned
+	history [observabilityHistory]ObservabilityEvent
+	next    int
+	count   int
 }
 
 func NewObservabilityBus() *ObservabilityBus {
@@ -43,31 +74,112 @@ func NewObservabilityBus() *ObservabilityBus {
 	}
 }
 
-func (b *ObservabilityBus) Subscribe() chan ObservabilityEvent {
+// SubscribeSince subscribes and returns the buffered events newer than
+// lastSeq, oldest first. Both happen under one lock, so nothing published in
+// between is missed or delivered twice. lastSeq < 0 skips replay; a lastSeq
+// ahead of the bus (the gateway restarted) replays everything buffered.
+func (b *ObservabilityBus) SubscribeSince(lastSeq int64) (chan ObservabilityEvent, []ObservabilityEvent) {
 	ch := make(chan ObservabilityEvent, 32)
 	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.clients[ch] = struct{}{}
-	b.mu.Unlock()
-	return ch
+	if lastSeq < 0 {
+		return ch, nil
+	}
+	if lastSeq > b.seq {
+		lastSeq = 0
+	}
+	var backlog []ObservabilityEvent
+	start := (b.next - b.count + len(b.history)) % len(b.history)
+	for i := 0; i < b.count; i++ {
+		evt := b.history[(start+i)%len(b.history)]
+		if evt.Seq > lastSeq {
+			backlog = append(backlog, evt)
+		}
+	}
+	return ch, backlog
 }
 
+// Unsubscribe removes and closes a client channel. A no-op once Close has
+// already closed it.
 func (b *ObservabilityBus) Unsubscribe(ch chan ObservabilityEvent) {
 	b.mu.Lock()
-	delete(b.clients, ch)
-	b.mu.Unlock()
-	close(ch)
+	defer b.mu.Unlock()
+	if _, ok := b.clients[ch]; ok {
+		delete(b.clients, ch)
+		close(ch)
+	}
+}
+
+// Close ends every client stream — called on shutdown.
+func (b *ObservabilityBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		delete(b.clients, ch)
+		close(ch)
+	}
 }
 
 func (b *ObservabilityBus) Publish(evt ObservabilityEvent) {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	evt.Seq = b.seq
+	b.history[b.next] = evt
+	b.next = (b.next + 1) % len(b.history)
+	if b.count < len(b.history) {
+		b.count++
+	}
 	for ch := range b.clients {
 		select {
 		case ch <- evt:
 		default:
 			// slow client — drop rather than block
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// LagTracker keeps a rolling window of Redis delivery lag samples — the time
+// between an event's embedded timestamp and when the gateway fans it out.
+// Fixed-size ring, so recording is O(1) and memory is bounded.
+type LagTracker struct {
+	mu      sync.Mutex
+	samples [256]int64
+	next    int
+	count   int
+}
+
+func (l *LagTracker) Record(lagMs int64) {
+	if lagMs < 0 {
+		lagMs = 0 // clock skew between containers
+	}
+	l.mu.Lock()
+	l.samples[l.next] = lagMs
+	l.next = (l.next + 1) % len(l.samples)
+	if l.count < len(l.samples) {
+		l.count++
+	}
+	l.mu.Unlock()
+}
+
+// Snapshot returns the sample count, average and max lag over the window.
+func (l *LagTracker) Snapshot() (int, int64, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.count == 0 {
+		return 0, 0, 0
+	}
+	var sum, max int64
+	for i := 0; i < l.count; i++ {
+		v := l.samples[i]
+		sum += v
+		if v > max {
+			max = v
 		}
 	}
+	return l.count, sum / int64(l.count), max
 }
 
 // BalanceBus fans out balance updates to subscribed SSE clients
@@ -95,9 +207,21 @@ func (b *BalanceBus) Subscribe() chan BalanceEvent {
 
 func (b *BalanceBus) Unsubscribe(ch chan BalanceEvent) {
 	b.mu.Lock()
-	delete(b.clients, ch)
-	b.mu.Unlock()
-	close(ch)
+	defer b.mu.Unlock()
+	if _, ok := b.clients[ch]; ok {
+		delete(b.clients, ch)
+		close(ch)
+	}
+}
+
+// Close ends every client stream — called on shutdown.
+func (b *BalanceBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		delete(b.clients, ch)
+		close(ch)
+	}
 }
 
 func (b *BalanceBus) Publish(evt BalanceEvent) {
@@ -111,9 +235,61 @@ func (b *BalanceBus) Publish(evt BalanceEvent) {
 	}
 }
 
+// AlertBus fans out observability alerts to subscribed SSE clients. Alerts
+// are passed through as the JSON observability-service published.
+type AlertBus struct {
+	mu      sync.RWMutex
+	clients map[chan json.RawMessage]struct{}
+}
+
+func NewAlertBus() *AlertBus {
+	return &AlertBus{clients: make(map[chan json.RawMessage]struct{})}
+}
+
+func (b *AlertBus) Subscribe() chan json.RawMessage {
+	ch := make(chan json.RawMessage, 8)
+	b.mu.Lock()
+	b.clients[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *AlertBus) Unsubscribe(ch chan json.RawMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clients[ch]; ok {
+		delete(b.clients, ch)
+		close(ch)
+	}
+}
+
+// Close ends every client stream — called on shutdown.
+func (b *AlertBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		delete(b.clients, ch)
+		close(ch)
+	}
+}
+
+func (b *AlertBus) Publish(alert json.RawMessage) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.clients {
+		select {
+		case ch <- alert:
+		default:
+		}
+	}
+}
+
 var (
 	bus        = NewObservabilityBus()
 	balanceBus = NewBalanceBus()
+	alertBus   = NewAlertBus()
+	redisLag   = &LagTracker{}
+	metrics    = NewMetrics()
 
 	serviceURLs = map[string]string{
 		"game-state": getEnv("GAME_STATE_URL", "http://game-state:3001"),
@@ -127,6 +303,10 @@ var (
 	}
 )
 
+// shutdownTimeout bounds how long in-flight requests get to finish after
+// SIGTERM (SHUTDOWN_TIMEOUT_SECONDS). Kept under Docker's 10s stop timeout.
+var shutdownTimeout = 8 * time.Second
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -135,23 +315,54 @@ func getEnv(key, fallback string) string {
 }
 
 func main() {
+	setupLogging()
+	if secs, err := strconv.Atoi(getEnv("SSE_KEEPALIVE_SECONDS", "15")); err == nil && secs > 0 {
+		sseKeepalive = time.Duration(secs) * time.Second
+	}
+	jwtVerifier = loadJWTVerifier()
+	perMin := 10
+	if v, err := strconv.Atoi(getEnv("AUTH_RATE_LIMIT_PER_MIN", "")); err == nil && v > 0 {
+		perMin = v
+	}
+	burst := perMin
+	if v, err := strconv.Atoi(getEnv("AUTH_RATE_LIMIT_BURST", "")); err == nil && v > 0 {
+		burst = v
+	}
+	authLimiter = NewRateLimiter(perMin, burst)
+	trustedProxies = parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if v, err := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT_SECONDS", "")); err == nil && v > 0 {
+		shutdownTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(getEnv("UPSTREAM_RETRIES", "")); err == nil && v >= 0 {
+		upstreamRetries = v
+	}
+	if v, err := strconv.Atoi(getEnv("UPSTREAM_RETRY_BASE_MS", "")); err == nil && v > 0 {
+		upstreamRetryBase = time.Duration(v) * time.Millisecond
+	}
+
 	mux := http.NewServeMux()
 
-	// Health
+	// Health (liveness) and readiness
 	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/ready", readyHandler)
 
 	// Observability SSE feed (no auth — dashboard is internal)
 	mux.HandleFunc("/events", observabilitySSEHandler)
+	mux.HandleFunc("/alerts", alertsSSEHandler)
 
-	// Demo control — pause/resume the demo loop
+	// Demo control — pause/resume the demo loop, and its pacing
 	mux.HandleFunc("/api/game/demo/pause", instrumentedProxyWithRewrite("game-state", serviceURLs["game-state"], "/api/game/demo/", "/demo/"))
+	mux.HandleFunc("/api/game/demo/speed", instrumentedProxyWithRewrite("game-state", serviceURLs["game-state"], "/api/game/demo/", "/demo/"))
 
 	// Game routes — SSE stream and table listing are public (EventSource can't send headers)
-	// Actions are open for now — will require session scope once player join flow is wired
-	mux.HandleFunc("/api/game/", instrumentedProxyWithRewrite("game-state", serviceURLs["game-state"], "/api/game/", "/tables/"))
+	// A session token, when present, identifies the seat for joins and actions on shared tables
+	mux.HandleFunc("/api/game/", withSessionPlayer(instrumentedProxyWithRewrite("game-state", serviceURLs["game-state"], "/api/game/", "/tables/")))
 
-	// Auth routes → auth service (/api/auth/* → /*)
-	mux.HandleFunc("/api/auth/", instrumentedProxyWithRewrite("auth", serviceURLs["auth"], "/api/auth/", "/"))
+	// Player lobby routes → game-state (/api/players/* → /players/*) — session scope required
+	mux.HandleFunc("/api/players/", requireSessionScope(instrumentedProxyWithRewrite("game-state", serviceURLs["game-state"], "/api/players/", "/players/")))
+
+	// Auth routes → auth service (/api/auth/* → /*) — submissions rate limited per IP
+	mux.HandleFunc("/api/auth/", rateLimitAuth(instrumentedProxyWithRewrite("auth", serviceURLs["auth"], "/api/auth/", "/")))
 
 	// Email verification link
 	// /verify?token=... → auth-service /verify-token?token=... (returns redirect to UI)
@@ -169,7 +380,8 @@ func main() {
 	mux.HandleFunc("/api/bank/", requireSessionScope(instrumentedProxyWithRewrite("bank", serviceURLs["bank"], "/api/bank/", "/")))
 
 	// Chat routes → chat service (/api/chat/* → /*)
-	mux.HandleFunc("/api/chat/", instrumentedProxyWithRewrite("chat", serviceURLs["chat"], "/api/chat/", "/"))
+	mux.HandleFunc("/api/chat/", websocketProxy("chat", serviceURLs["chat"], "/api/chat/", "/",
+		instrumentedProxyWithRewrite("chat", serviceURLs["chat"], "/api/chat/", "/")))
 
 	// Email routes → email service (/api/email/* → /*)
 	mux.HandleFunc("/api/email/", instrumentedProxyWithRewrite("email", serviceURLs["email"], "/api/email/", "/"))
@@ -178,6 +390,12 @@ func main() {
 	mux.HandleFunc("/dev/reset", devResetHandler)
 	mux.HandleFunc("/dev/demo-token", instrumentedProxyWithRewrite("auth", serviceURLs["auth"], "/dev/demo-token", "/dev/demo-token"))
 	mux.HandleFunc("/api/bank/balance/stream", balanceSSEHandler)
+	// Per-player balance stream — session only, filtered to the token's sub.
+	// EventSource can't send headers, so the token may ride in ?token=
+	mux.HandleFunc("/balance/stream", sseQueryToken(requireSessionScope(playerBalanceSSEHandler)))
+
+	// Prometheus scrape endpoint — registered explicitly so the UI catch-all never shadows it
+	mux.HandleFunc("/metrics", metricsHandler)
 
 	// UI catch-all — must be last. Proxies everything else to the UI container.
 	// In production this would be a CDN or static file server.
@@ -206,13 +424,37 @@ func main() {
 		}
 		if rdb != nil {
 			defer rdb.Close()
+			go subscribeRedisAlerts(rdb)
 			subscribeRedisBalance(rdb)
 		}
 	}()
 
-	if err := http.ListenAndServe(":"+port, corsMiddleware(mux)); err != nil {
-		log.Fatal(err)
+	srv := &http.Server{Addr: ":" + port, Handler: corsMiddleware(requestIDMiddleware(mux))}
+	// Dashboard, balance and alert streams never go idle on their own — end them so
+	// Shutdown can drain. Proxied game streams end when game-state stops.
+	srv.RegisterOnShutdown(bus.Close)
+	srv.RegisterOnShutdown(balanceBus.Close)
+	srv.RegisterOnShutdown(alertBus.Close)
+	// Hijacked WebSockets are invisible to Shutdown — close them explicitly
+	srv.RegisterOnShutdown(wsConns.closeAll)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+
+	log.Printf("[gateway] shutting down — draining connections (timeout %s)", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[gateway] shutdown: %v — closing remaining connections", err)
+		srv.Close()
 	}
+	log.Printf("[gateway] stopped")
 }
 
 // instrumentedProxyWithRewrite proxies with prefix rewriting e.g. /api/game/ → /tables/
@@ -224,6 +466,7 @@ func instrumentedProxyWithRewrite(callee, targetURL, stripPrefix, addPrefix stri
 
 	proxy := httputil.NewSingleHostReverseProxy(target)
 	proxy.FlushInterval = -1 // flush immediately — required for SSE pass-through
+	proxy.Transport = &retryTransport{callee: callee, next: http.DefaultTransport}
 	proxy.Director = func(req *http.Request) {
 		req.URL.Scheme = target.Scheme
 		req.URL.Host = target.Host
@@ -237,7 +480,7 @@ func instrumentedProxyWithRewrite(callee, targetURL, stripPrefix, addPrefix stri
 		}
 	}
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf("proxy error [%s]: %v", callee, err)
+		logf("error", logFields{"requestId": r.Header.Get(requestIDHeader)}, "proxy error [%s]: %v", callee, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadGateway)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -250,7 +493,14 @@ func instrumentedProxyWithRewrite(callee, targetURL, stripPrefix, addPrefix stri
 		start := time.Now()
 		isSSE := r.Header.Get("Accept") == "text/event-stream"
 		rw := &statusRecorder{ResponseWriter: w, status: 200}
-		proxy.ServeHTTP(rw, r)
+		if isSSE {
+			done := metrics.SSEConnected()
+			proxy.ServeHTTP(rw, r)
+			done()
+		} else {
+			proxy.ServeHTTP(rw, r)
+		}
+		metrics.ObserveRequest(callee, r.Method, rw.status, time.Since(start), isSSE)
 		latency := time.Since(start).Milliseconds()
 		bus.Publish(ObservabilityEvent{
 			ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
@@ -262,8 +512,10 @@ func instrumentedProxyWithRewrite(callee, targetURL, stripPrefix, addPrefix stri
 			Protocol:  protocolFor(isSSE, r),
 			StatusCode: rw.status,
 			LatencyMs:  latency,
+			RequestID:  r.Header.Get(requestIDHeader),
 		})
-		log.Printf("[gateway→%s] %s %s %d (%dms)", callee, r.Method, r.URL.Path, rw.status, latency)
+		logf(statusLevel(rw.status), logFields{"requestId": r.Header.Get(requestIDHeader)},
+			"[gateway→%s] %s %s %d (%dms)", callee, r.Method, r.URL.Path, rw.status, latency)
 	}
 }
 
@@ -276,8 +528,9 @@ func instrumentedProxy(callee, targetURL string) http.HandlerFunc {
 
 	proxy := httputil.NewSingleHostReverseProxy(target)
 	proxy.FlushInterval = -1 // flush immediately — required for SSE pass-through
+	proxy.Transport = &retryTransport{callee: callee, next: http.DefaultTransport}
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf("proxy error [%s]: %v", callee, err)
+		logf("error", logFields{"requestId": r.Header.Get(requestIDHeader)}, "proxy error [%s]: %v", callee, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadGateway)
 		json.NewEncoder(w).Encode(map[string]string{
@@ -301,21 +554,353 @@ func instrumentedProxy(callee, targetURL string) http.HandlerFunc {
 			Method:    r.Method,
 			Path:      r.URL.Path,
 			Protocol:  protocolFor(isSSE, r),
+			RequestID: r.Header.Get(requestIDHeader),
 		}
 
 		// Track response status
 		rw := &statusRecorder{ResponseWriter: w, status: 200}
-		proxy.ServeHTTP(rw, r)
+		if isSSE {
+			done := metrics.SSEConnected()
+			proxy.ServeHTTP(rw, r)
+			done()
+		} else {
+			proxy.ServeHTTP(rw, r)
+		}
+		metrics.ObserveRequest(callee, r.Method, rw.status, time.Since(start), isSSE)
 
 		latency := time.Since(start).Milliseconds()
 		reqEvt.StatusCode = rw.status
 		reqEvt.LatencyMs = latency
 		bus.Publish(reqEvt)
 
-		log.Printf("[gateway→%s] %s %s %d (%dms)", callee, r.Method, r.URL.Path, rw.status, latency)
+		logf(statusLevel(rw.status), logFields{"requestId": reqEvt.RequestID},
+			"[gateway→%s] %s %s %d (%dms)", callee, r.Method, r.URL.Path, rw.status, latency)
+	}
+}
+
+// ── Logging ───────────────────────────────────────────────────────────────────
+// JSON lines for the log aggregator, or plain text with LOG_FORMAT=text.
+// log.Printf output is logged at info; logf sets the level and fields.
+
+var logJSON = getEnv("LOG_FORMAT", "json") != "text"
+
+// logFields are the structured fields of one log line.
+type logFields map[string]any
+
+var logMu sync.Mutex
+
+func setupLogging() {
+	if logJSON {
+		log.SetFlags(0)
+		log.SetOutput(jsonLogWriter{})
+	}
+}
+
+// jsonLogWriter turns each line the standard logger writes into a JSON line.
+type jsonLogWriter struct{}
+
+func (jsonLogWriter) Write(p []byte) (int, error) {
+	writeLogLine("info", string(bytes.TrimRight(p, "\n")), nil)
+	return len(p), nil
+}
+
+func writeLogLine(level, msg string, fields logFields) {
+	line := make(map[string]any, len(fields)+4)
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		line[k] = v
+	}
+	line["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level
+	line["service"] = "gateway"
+	line["msg"] = msg
+	b, err := json.Marshal(line)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"level": level, "service": "gateway", "msg": msg})
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+	os.Stderr.Write(append(b, '\n'))
+}
+
+// statusLevel is the log level for a proxied response with this status.
+func statusLevel(status int) string {
+	switch {
+	case status >= 500:
+		return "error"
+	case status >= 400:
+		return "warn"
+	}
+	return "info"
+}
+
+// logf logs at level ("info", "warn" or "error") with fields. In text mode
+// the fields are appended as key=value pairs.
+func logf(level string, fields logFields, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if logJSON {
+		writeLogLine(level, msg, fields)
+		return
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg += fmt.Sprintf(" %s=%v", k, fields[k])
+	}
+	log.Print(msg)
+}
+
+// ── WebSocket proxy ───────────────────────────────────────────────────────────
+// The reverse proxy can't switch protocols through statusRecorder, so
+// upgrade requests take a separate path: dial the upstream, replay the
+// handshake, and once it answers 101 hijack the client connection and copy
+// bytes both ways. When either side closes, both connections are closed so
+// neither copy goroutine outlives the session. An observability event is
+// published on connect and on close.
+
+// wsDialTimeout bounds the upstream dial and handshake.
+const wsDialTimeout = 10 * time.Second
+
+// wsTracker holds open WebSocket connection pairs so shutdown can end them.
+type wsTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+var wsConns = &wsTracker{conns: make(map[net.Conn]struct{})}
+
+func (t *wsTracker) add(c net.Conn) {
+	t.mu.Lock()
+	t.conns[c] = struct{}{}
+	t.mu.Unlock()
+}
+
+func (t *wsTracker) remove(c net.Conn) {
+	t.mu.Lock()
+	delete(t.conns, c)
+	t.mu.Unlock()
+}
+
+func (t *wsTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+func (t *wsTracker) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for c := range t.conns {
+		c.Close()
+	}
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// websocketProxy serves WebSocket upgrades itself and hands every other
+// request to next.
+func websocketProxy(callee, targetURL, stripPrefix, addPrefix string, next http.HandlerFunc) http.HandlerFunc {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		log.Fatalf("invalid upstream URL for %s: %v", callee, err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isWebSocketUpgrade(r) {
+			next(w, r)
+			return
+		}
+		start := time.Now()
+		rid := r.Header.Get(requestIDHeader)
+		publish := func(status int, latency time.Duration) {
+			bus.Publish(ObservabilityEvent{
+				ID:         fmt.Sprintf("%d", time.Now().UnixNano()),
+				Timestamp:  time.Now().UTC().Format(time.RFC3339),
+				Caller:     "gateway",
+				Callee:     callee,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Protocol:   "websocket",
+				StatusCode: status,
+				LatencyMs:  latency.Milliseconds(),
+				RequestID:  rid,
+			})
+		}
+
+		upstream, err := net.DialTimeout("tcp", target.Host, wsDialTimeout)
+		if err != nil {
+			logf("error", logFields{"requestId": rid}, "proxy error [%s]: websocket dial: %v", callee, err)
+			scopeError(w, http.StatusBadGateway, "upstream_error", fmt.Sprintf("%s service unavailable", callee))
+			publish(http.StatusBadGateway, time.Since(start))
+			return
+		}
+
+		// Replay the handshake with the path rewritten for the upstream
+		out := r.Clone(r.Context())
+		out.URL.Scheme, out.URL.Host, out.Host = "", "", target.Host
+		out.URL.Path = addPrefix + strings.TrimPrefix(r.URL.Path, stripPrefix)
+		out.URL.RawPath = ""
+		out.Header.Set("X-Forwarded-For", clientIP(r))
+		upstream.SetDeadline(time.Now().Add(wsDialTimeout))
+		if err := out.Write(upstream); err != nil {
+			upstream.Close()
+			logf("error", logFields{"requestId": rid}, "proxy error [%s]: websocket handshake: %v", callee, err)
+			scopeError(w, http.StatusBadGateway, "upstream_error", fmt.Sprintf("%s service unavailable", callee))
+			publish(http.StatusBadGateway, time.Since(start))
+			return
+		}
+		upstreamBuf := bufio.NewReader(upstream)
+		resp, err := http.ReadResponse(upstreamBuf, out)
+		if err != nil {
+			upstream.Close()
+			logf("error", logFields{"requestId": rid}, "proxy error [%s]: websocket handshake: %v", callee, err)
+			scopeError(w, http.StatusBadGateway, "upstream_error", fmt.Sprintf("%s service unavailable", callee))
+			publish(http.StatusBadGateway, time.Since(start))
+			return
+		}
+		upstream.SetDeadline(time.Time{})
+
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			// Upstream refused the upgrade — relay its answer as plain HTTP
+			defer upstream.Close()
+			defer resp.Body.Close()
+			for k, vv := range resp.Header {
+				for _, v := range vv {
+					w.Header().Add(k, v)
+				}
+			}
+			w.WriteHeader(resp.StatusCode)
+			io.Copy(w, resp.Body)
+			publish(resp.StatusCode, time.Since(start))
+			return
+		}
+
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			upstream.Close()
+			scopeError(w, http.StatusInternalServerError, "websocket_unsupported", "connection cannot be upgraded")
+			return
+		}
+		client, clientBuf, err := hj.Hijack()
+		if err != nil {
+			upstream.Close()
+			logf("error", logFields{"requestId": rid}, "proxy error [%s]: websocket hijack: %v", callee, err)
+			return
+		}
+		if err := resp.Write(client); err != nil {
+			client.Close()
+			upstream.Close()
+			return
+		}
+
+		wsConns.add(client)
+		wsConns.add(upstream)
+		metrics.ObserveRequest(callee, r.Method, http.StatusSwitchingProtocols, time.Since(start), true)
+		publish(http.StatusSwitchingProtocols, time.Since(start))
+		logf("info", logFields{"requestId": rid}, "[gateway→%s] websocket open %s", callee, r.URL.Path)
+
+		// Bytes already read past the handshake on either side go first
+		done := make(chan struct{}, 2)
+		go func() {
+			io.Copy(upstream, io.MultiReader(io.LimitReader(clientBuf, int64(clientBuf.Reader.Buffered())), client))
+			done <- struct{}{}
+		}()
+		go func() {
+			io.Copy(client, upstreamBuf)
+			done <- struct{}{}
+		}()
+		<-done
+		// One side is gone — closing both unblocks the other copy
+		client.Close()
+		upstream.Close()
+		<-done
+
+		wsConns.remove(client)
+		wsConns.remove(upstream)
+		lifetime := time.Since(start)
+		publish(http.StatusSwitchingProtocols, lifetime)
+		logf("info", logFields{"requestId": rid}, "[gateway→%s] websocket closed %s after %s", callee, r.URL.Path, lifetime.Round(time.Millisecond))
 	}
 }
 
+// ── Upstream retries ──────────────────────────────────────────────────────────
+// Safe methods (GET, HEAD) are retried on dial errors and 502/503/504 with
+// exponential backoff plus jitter. Anything else — a POST /bet above all —
+// goes upstream exactly once so a retry can never double-charge.
+
+var (
+	upstreamRetries   = 2                      // UPSTREAM_RETRIES: retries after the first attempt
+	upstreamRetryBase = 100 * time.Millisecond // UPSTREAM_RETRY_BASE_MS: first backoff delay
+)
+
+type retryTransport struct {
+	callee string
+	next   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if (req.Method != http.MethodGet && req.Method != http.MethodHead) || req.Header.Get("Upgrade") != "" {
+		return t.next.RoundTrip(req)
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt > upstreamRetries || !retryable(resp, err) {
+			return resp, err
+		}
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		// Full jitter: sleep a random duration up to base * 2^(attempt-1)
+		backoff := upstreamRetryBase << (attempt - 1)
+		delay := time.Duration(rand.Int63n(int64(backoff) + 1))
+		logf("warn", logFields{"requestId": req.Header.Get(requestIDHeader)},
+			"[gateway→%s] retry %d/%d %s %s after status=%d err=%v (backoff %s)",
+			t.callee, attempt, upstreamRetries, req.Method, req.URL.Path, status, err, delay.Round(time.Millisecond))
+		bus.Publish(ObservabilityEvent{
+			ID:         fmt.Sprintf("%d", time.Now().UnixNano()),
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+			Caller:     "gateway",
+			Callee:     t.callee,
+			Method:     req.Method,
+			Path:       req.URL.Path,
+			Protocol:   "http",
+			StatusCode: status,
+			RequestID:  req.Header.Get(requestIDHeader),
+			Attempt:    attempt + 1,
+		})
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retryable reports whether a safe request is worth another attempt:
+// the upstream couldn't be dialed, or answered 502/503/504.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
 func protocolFor(isSSE bool, r *http.Request) string {
 	if isSSE {
 		return "sse"
@@ -326,28 +911,423 @@ func protocolFor(isSSE bool, r *http.Request) string {
 	return "http"
 }
 
-// extractJWTClaims decodes JWT payload without signature verification.
-// Scope decisions are routing-only — auth-service still fully verifies on every call.
-func extractJWTClaims(r *http.Request) map[string]interface{} {
+// ── JWT verification ─────────────────────────────────────────────────────────
+// Scope decisions at the gateway are routing-only — auth-service still fully
+// verifies on every call. As defense in depth the gateway can also verify
+// signatures: HS256 with JWT_SECRET (shared with auth-service) and/or RS256
+// against the keys published at JWT_JWKS_URL. With neither configured, or
+// JWT_VERIFY=false for local dev, tokens are decoded without verification.
+
+var (
+	errNoToken        = errors.New("no bearer token")
+	errMalformedToken = errors.New("malformed token")
+	errBadSignature   = errors.New("invalid token signature")
+	errTokenExpired   = errors.New("token expired")
+	errTokenNotYet    = errors.New("token not yet valid")
+)
+
+// jwtClockSkew tolerates clock drift between auth-service and the gateway
+// when checking exp and nbf.
+const jwtClockSkew = 5 * time.Second
+
+// jwtVerifier checks token signatures. nil means decode-only mode.
+var jwtVerifier *JWTVerifier
+
+// jwksRefreshInterval rate-limits JWKS refetches triggered by unknown kids.
+const jwksRefreshInterval = time.Minute
+
+type JWTVerifier struct {
+	secret  []byte // HS256 key; empty disables HS256
+	jwksURL string // RS256 key set; empty disables RS256
+
+	mu      sync.RWMutex
+	keys    map[string]*rsa.PublicKey // kid → key
+	fetched time.Time
+}
+
+// loadJWTVerifier builds the verifier from the environment, or returns nil
+// for decode-only mode.
+func loadJWTVerifier() *JWTVerifier {
+	if strings.EqualFold(getEnv("JWT_VERIFY", "true"), "false") {
+		log.Printf("[gateway] JWT_VERIFY=false — tokens are decoded without signature checks (dev only)")
+		return nil
+	}
+	v := &JWTVerifier{
+		secret:  []byte(getEnv("JWT_SECRET", "")),
+		jwksURL: getEnv("JWT_JWKS_URL", ""),
+		keys:    make(map[string]*rsa.PublicKey),
+	}
+	if len(v.secret) == 0 && v.jwksURL == "" {
+		log.Printf("[gateway] no JWT_SECRET or JWT_JWKS_URL — tokens are decoded without signature checks")
+		return nil
+	}
+	log.Printf("[gateway] JWT verification on (hs256=%v rs256=%v)", len(v.secret) > 0, v.jwksURL != "")
+	return v
+}
+
+// Verify checks the signature over header.payload for the token's alg.
+// Only HS256 and RS256 are accepted — never "none".
+func (v *JWTVerifier) Verify(alg, kid, signingInput string, sig []byte) error {
+	switch alg {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return errBadSignature
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errBadSignature
+		}
+		return nil
+	case "RS256":
+		key := v.rsaKey(kid)
+		if key == nil {
+			return errBadSignature
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return errBadSignature
+		}
+		return nil
+	default:
+		return errBadSignature
+	}
+}
+
+// rsaKey returns the JWKS key for kid, refetching the set (at most once per
+// jwksRefreshInterval) when the kid is unknown — keys rotate.
+func (v *JWTVerifier) rsaKey(kid string) *rsa.PublicKey {
+	if v.jwksURL == "" {
+		return nil
+	}
+	v.mu.RLock()
+	key, stale := v.keys[kid], time.Since(v.fetched) > jwksRefreshInterval
+	v.mu.RUnlock()
+	if key != nil || !stale {
+		return key
+	}
+
+	keys, err := fetchJWKS(v.jwksURL)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.fetched = time.Now()
+	if err != nil {
+		log.Printf("[gateway] JWKS fetch: %v", err)
+		return v.keys[kid]
+	}
+	v.keys = keys
+	return keys[kid]
+}
+
+// fetchJWKS loads the RSA keys from a JWKS document.
+func fetchJWKS(url string) (map[string]*rsa.PublicKey, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS %s returned %d", url, resp.StatusCode)
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("JWKS decode: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		exp := 0
+		for _, b := range e {
+			exp = exp<<8 | int(b)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}
+	}
+	return keys, nil
+}
+
+// parseJWT decodes the bearer token's claims. With a verifier configured the
+// signature must be valid and the token unexpired.
+func parseJWT(r *http.Request) (map[string]interface{}, error) {
 	auth := r.Header.Get("Authorization")
 	if !strings.HasPrefix(auth, "Bearer ") {
-		return nil
+		return nil, errNoToken
 	}
 	parts := strings.Split(strings.TrimPrefix(auth, "Bearer "), ".")
 	if len(parts) != 3 {
-		return nil
+		return nil, errMalformedToken
 	}
 	decoded, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil
+		return nil, errMalformedToken
 	}
 	var claims map[string]interface{}
 	if err := json.Unmarshal(decoded, &claims); err != nil {
+		return nil, errMalformedToken
+	}
+	// Lifetime is enforced even in decode-only mode
+	if err := checkTokenTimes(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	if jwtVerifier == nil {
+		return claims, nil
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, errMalformedToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errBadSignature
+	}
+	if err := jwtVerifier.Verify(header.Alg, header.Kid, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkTokenTimes enforces the exp and nbf claims (NumericDate seconds) with
+// jwtClockSkew of leeway. A token without exp is accepted — auth-service
+// always sets one, and signature checks cover forged tokens.
+func checkTokenTimes(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := claims["exp"].(float64); ok {
+		if now.After(time.Unix(int64(exp), 0).Add(jwtClockSkew)) {
+			return errTokenExpired
+		}
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Before(time.Unix(int64(nbf), 0).Add(-jwtClockSkew)) {
+			return errTokenNotYet
+		}
+	}
+	return nil
+}
+
+// extractJWTClaims returns the token's claims, or nil if there is no usable
+// token. Used where a token is optional.
+func extractJWTClaims(r *http.Request) map[string]interface{} {
+	claims, err := parseJWT(r)
+	if err != nil {
 		return nil
 	}
 	return claims
 }
 
+// authenticate resolves the request's claims for a protected route, writing
+// the 401 itself when there are none.
+func authenticate(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	claims, err := parseJWT(r)
+	switch {
+	case err == nil:
+		return claims, true
+	case errors.Is(err, errTokenExpired):
+		scopeError(w, http.StatusUnauthorized, "token_expired", "token has expired")
+	case errors.Is(err, errTokenNotYet):
+		scopeError(w, http.StatusUnauthorized, "token_not_yet_valid", "token is not valid yet")
+	case errors.Is(err, errBadSignature):
+		scopeError(w, http.StatusUnauthorized, "invalid_token", "token signature is invalid")
+	default:
+		scopeError(w, http.StatusUnauthorized, "auth_required", "authentication required")
+	}
+	return nil, false
+}
+
+// ── Rate limiting ─────────────────────────────────────────────────────────────
+// Auth submissions are throttled per client IP with a token bucket: each IP
+// gets AUTH_RATE_LIMIT_PER_MIN requests a minute, with bursts up to
+// AUTH_RATE_LIMIT_BURST. Buckets that have refilled are dropped by a periodic
+// cleanup, and the table is capped so a flood of addresses can't grow it
+// without bound.
+
+// maxRateLimitKeys caps how many client buckets are tracked at once.
+const maxRateLimitKeys = 10000
+
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	l := &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			l.cleanup()
+		}
+	}()
+	return l
+}
+
+// Allow takes a token for key. When the bucket is empty it reports how long
+// until the next token.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitKeys {
+			l.evictOldestLocked()
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup drops buckets that have refilled — they carry no state.
+func (l *RateLimiter) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// evictOldestLocked drops the least recently used bucket. Caller holds l.mu.
+func (l *RateLimiter) evictOldestLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, b := range l.buckets {
+		if oldestKey == "" || b.last.Before(oldest) {
+			oldestKey, oldest = key, b.last
+		}
+	}
+	delete(l.buckets, oldestKey)
+}
+
+// trustedProxies are the peers allowed to say who the client is through
+// X-Forwarded-For. Empty (the default) means the gateway faces clients
+// directly and the header is ignored. Set in main from TRUSTED_PROXIES.
+var trustedProxies []*net.IPNet
+
+// parseTrustedProxies reads a comma-separated list of IPs and CIDRs.
+// Unparseable entries are logged and skipped.
+func parseTrustedProxies(v string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			logf("warn", nil, "[gateway] TRUSTED_PROXIES: ignoring %q: %v", entry, err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP identifies the caller: the connection's peer address. Only when
+// that peer is a trusted proxy is X-Forwarded-For read, right to left, and
+// the first address not itself a trusted proxy is the client — anything
+// further left was supplied by the client and can't be believed.
+func clientIP(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !isTrustedProxy(peer) {
+		return peer
+	}
+	parts := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(parts) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(parts[i])
+		if ip == "" || isTrustedProxy(ip) {
+			continue
+		}
+		return ip
+	}
+	return peer
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds for Retry-After.
+func retryAfterSeconds(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
+
+// authLimiter throttles POSTs to /api/auth/. Set in main.
+var authLimiter *RateLimiter
+
+// rateLimitAuth applies the per-IP auth limit to submissions; reads pass.
+func rateLimitAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if ok, wait := authLimiter.Allow(clientIP(r)); !ok {
+				logf("warn", logFields{"requestId": r.Header.Get(requestIDHeader)}, "[gateway] rate limited %s on %s", clientIP(r), r.URL.Path)
+				w.Header().Set("Retry-After", retryAfterSeconds(wait))
+				scopeError(w, http.StatusTooManyRequests, "rate_limited", "too many attempts — try again later")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
 func scopeError(w http.ResponseWriter, status int, code, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -355,12 +1335,11 @@ func scopeError(w http.ResponseWriter, status int, code, message string) {
 }
 
 // requireSessionScope enforces scope: "session" on protected routes.
-// 401 = no token. 403 = wrong token type (bootstrap token used on game route).
+// 401 = no valid token. 403 = wrong token type (bootstrap token used on game route).
 func requireSessionScope(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims := extractJWTClaims(r)
-		if claims == nil {
-			scopeError(w, http.StatusUnauthorized, "auth_required", "authentication required")
+		claims, ok := authenticate(w, r)
+		if !ok {
 			return
 		}
 		scope, _ := claims["scope"].(string)
@@ -370,7 +1349,8 @@ func requireSessionScope(next http.HandlerFunc) http.HandlerFunc {
 				"session token required — complete passkey enrollment first")
 			return
 		}
-		// Inject player ID for downstream services
+		// Inject player ID for downstream services — never trust a client's
+		r.Header.Del("X-Player-ID")
 		if sub, ok := claims["sub"].(string); ok {
 			r.Header.Set("X-Player-ID", sub)
 		}
@@ -378,12 +1358,28 @@ func requireSessionScope(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// withSessionPlayer injects X-Player-ID when the request carries a session
+// token, without requiring one. A client-supplied X-Player-ID is always
+// dropped so it can't be spoofed.
+func withSessionPlayer(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("X-Player-ID")
+		if claims := extractJWTClaims(r); claims != nil {
+			if scope, _ := claims["scope"].(string); scope == "session" {
+				if sub, ok := claims["sub"].(string); ok {
+					r.Header.Set("X-Player-ID", sub)
+				}
+			}
+		}
+		next(w, r)
+	}
+}
+
 // requireEnrollScope accepts enroll or session scope — used on passkey registration endpoints.
 func requireEnrollScope(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims := extractJWTClaims(r)
-		if claims == nil {
-			scopeError(w, http.StatusUnauthorized, "auth_required", "authentication required")
+		claims, ok := authenticate(w, r)
+		if !ok {
 			return
 		}
 		scope, _ := claims["scope"].(string)
@@ -402,7 +1398,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -411,6 +1408,32 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// ── Request IDs ───────────────────────────────────────────────────────────────
+// Every request entering the gateway carries an X-Request-ID. A well-formed
+// client-supplied ID is kept; anything else is replaced. The header rides
+// the proxied request upstream, where game-state forwards it on its own
+// calls, so one player action can be followed through every service.
+
+const requestIDHeader = "X-Request-ID"
+
+var reRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func newRequestID() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
+
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rid := r.Header.Get(requestIDHeader)
+		if !reRequestID.MatchString(rid) {
+			rid = newRequestID()
+			r.Header.Set(requestIDHeader, rid)
+		}
+		w.Header().Set(requestIDHeader, rid)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func observabilitySSEHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -423,31 +1446,104 @@ func observabilitySSEHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ch := bus.Subscribe()
+	// Browsers send Last-Event-ID on reconnect; replay what they missed
+	lastSeq := int64(-1)
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			lastSeq = n
+		}
+	}
+	ch, backlog := bus.SubscribeSince(lastSeq)
 	defer bus.Unsubscribe(ch)
+	defer metrics.SSEConnected()()
 
 	// Send connected event
 	fmt.Fprintf(w, "event: connected\ndata: {\"service\":\"gateway\"}\n\n")
+	for _, evt := range backlog {
+		writeServiceCall(w, evt)
+	}
 	flusher.Flush()
 
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+
 	for {
 		select {
 		case evt, ok := <-ch:
 			if !ok {
 				return
 			}
-			data, _ := json.Marshal(evt)
-			fmt.Fprintf(w, "event: service_call\ndata: %s\n\n", data)
+			writeServiceCall(w, evt)
 			flusher.Flush()
+			keepalive.Reset(sseKeepalive)
+		case <-keepalive.C:
+			writeSSEKeepalive(w, flusher)
 		case <-r.Context().Done():
 			return
 		}
 	}
 }
 
+// writeServiceCall writes one observability event as an SSE frame. The id
+// line carries the bus sequence number so reconnects can resume from it.
+func writeServiceCall(w http.ResponseWriter, evt ObservabilityEvent) {
+	data, _ := json.Marshal(evt)
+	fmt.Fprintf(w, "id: %d\nevent: service_call\ndata: %s\n\n", evt.Seq, data)
+}
+
+// sseKeepalive is how long an SSE stream may stay silent before a comment
+// line is sent to keep proxies from closing it. Set from SSE_KEEPALIVE_SECONDS.
+var sseKeepalive = 15 * time.Second
+
+// writeSSEKeepalive sends an SSE comment — ignored by EventSource, but it
+// counts as traffic for any proxy idle timeout in between.
+func writeSSEKeepalive(w http.ResponseWriter, flusher http.Flusher) {
+	fmt.Fprint(w, ": keepalive\n\n")
+	flusher.Flush()
+}
+
 // balanceSSEHandler streams balance updates to the UI.
 // No auth — demo player is public; production would scope per JWT.
 func balanceSSEHandler(w http.ResponseWriter, r *http.Request) {
+	streamBalances(w, r, "")
+}
+
+// playerBalanceSSEHandler streams only the signed-in player's balance:
+// GET /balance/stream?playerId=. Runs behind requireSessionScope, so
+// X-Player-ID is the token's sub; asking for anyone else is a 403.
+func playerBalanceSSEHandler(w http.ResponseWriter, r *http.Request) {
+	sub := r.Header.Get("X-Player-ID")
+	if sub == "" {
+		scopeError(w, http.StatusUnauthorized, "auth_required", "token has no subject")
+		return
+	}
+	if pid := r.URL.Query().Get("playerId"); pid != "" && pid != sub {
+		scopeError(w, http.StatusForbidden, "forbidden", "balance stream is limited to your own account")
+		return
+	}
+	streamBalances(w, r, sub)
+}
+
+// sseQueryToken lets EventSource clients authenticate with ?token= when no
+// Authorization header is present. The token is removed from the URL so it
+// never reaches logs or upstreams.
+func sseQueryToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if tok := q.Get("token"); tok != "" {
+			if r.Header.Get("Authorization") == "" {
+				r.Header.Set("Authorization", "Bearer "+tok)
+			}
+			q.Del("token")
+			r.URL.RawQuery = q.Encode()
+		}
+		next(w, r)
+	}
+}
+
+// streamBalances writes balance_update events until the client goes away.
+// A non-empty playerID drops every other player's updates.
+func streamBalances(w http.ResponseWriter, r *http.Request, playerID string) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "SSE not supported", http.StatusInternalServerError)
@@ -460,6 +1556,10 @@ func balanceSSEHandler(w http.ResponseWriter, r *http.Request) {
 
 	ch := balanceBus.Subscribe()
 	defer balanceBus.Unsubscribe(ch)
+	defer metrics.SSEConnected()()
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
 
 	for {
 		select {
@@ -467,9 +1567,15 @@ func balanceSSEHandler(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
+			if playerID != "" && evt.PlayerID != playerID {
+				continue
+			}
 			data, _ := json.Marshal(evt)
 			fmt.Fprintf(w, "event: balance_update\ndata: %s\n\n", data)
 			flusher.Flush()
+			keepalive.Reset(sseKeepalive)
+		case <-keepalive.C:
+			writeSSEKeepalive(w, flusher)
 		case <-r.Context().Done():
 			return
 		}
@@ -491,6 +1597,57 @@ func subscribeRedisBalance(rdb *redis.Client) {
 	}
 }
 
+// alertsSSEHandler streams error-rate alerts raised by observability-service.
+// No auth — like /events, the dashboard is internal.
+func alertsSSEHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "SSE not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	ch := alertBus.Subscribe()
+	defer alertBus.Unsubscribe(ch)
+	defer metrics.SSEConnected()()
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case alert, ok := <-ch:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: alert\ndata: %s\n\n", alert)
+			flusher.Flush()
+			keepalive.Reset(sseKeepalive)
+		case <-keepalive.C:
+			writeSSEKeepalive(w, flusher)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// subscribeRedisAlerts subscribes to swarm:alerts and fans out to alertBus.
+func subscribeRedisAlerts(rdb *redis.Client) {
+	sub := rdb.Subscribe(context.Background(), "swarm:alerts")
+	defer sub.Close()
+	log.Printf("[gateway] subscribed to Redis channel swarm:alerts")
+	for msg := range sub.Channel() {
+		if !json.Valid([]byte(msg.Payload)) {
+			log.Printf("[gateway] alert parse error: invalid JSON")
+			continue
+		}
+		alertBus.Publish(json.RawMessage(msg.Payload))
+	}
+}
+
 // subscribeRedis subscribes to the observability Redis channel and feeds
 // events into the local bus so SSE clients see internal service calls.
 func subscribeRedis() {
@@ -522,6 +1679,8 @@ func subscribeRedis() {
 	defer sub.Close()
 
 	log.Printf("[gateway] subscribed to Redis channel swarm:events")
+	redisSubscribed.Store(true)
+	defer redisSubscribed.Store(false)
 
 	ch := sub.Channel()
 	for msg := range ch {
@@ -530,6 +1689,9 @@ func subscribeRedis() {
 			log.Printf("[gateway] redis event parse error: %v", err)
 			continue
 		}
+		if ts, err := time.Parse(time.RFC3339Nano, evt.Timestamp); err == nil {
+			redisLag.Record(time.Since(ts).Milliseconds())
+		}
 		bus.Publish(evt)
 	}
 }
@@ -587,15 +1749,171 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		upstreams[name] = status
 	}
 
+	samples, avgLag, maxLag := redisLag.Snapshot()
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":   "healthy",
 		"service":  "gateway",
 		"version":  "0.1.0",
 		"upstream": upstreams,
+		"redis_lag": map[string]interface{}{
+			"samples": samples,
+			"avg_ms":  avgLag,
+			"max_ms":  maxLag,
+		},
+		"bus_dropped": bus.dropped.Load(),
+	})
+}
+
+// redisSubscribed is true while the swarm:events subscription is live.
+var redisSubscribed atomic.Bool
+
+// readyHandler holds traffic until the gateway's own dependencies are up.
+// Upstream services have their own /ready; only Redis, which feeds the
+// dashboard and balance streams, is checked here.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	status, code, redisStatus := "ready", http.StatusOK, "connected"
+	if !redisSubscribed.Load() {
+		status, code, redisStatus = "not_ready", http.StatusServiceUnavailable, "disconnected"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  status,
+		"service": "gateway",
+		"redis":   redisStatus,
 	})
 }
 
+// ── Metrics ───────────────────────────────────────────────────────────────────
+// Prometheus text exposition, hand-rolled to keep the gateway dependency-light.
+// The proxies already measure status and latency for the observability feed;
+// the same numbers are aggregated here for scraping.
+
+// latencyBuckets are the histogram upper bounds in seconds.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	callee string
+	method string
+	status int
+}
+
+type histogram struct {
+	counts []int64 // per bucket, non-cumulative
+	sum    float64
+	count  int64
+}
+
+type Metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]int64
+	latencies map[string]*histogram // by callee
+	sseActive atomic.Int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  make(map[requestKey]int64),
+		latencies: make(map[string]*histogram),
+	}
+}
+
+// ObserveRequest counts a proxied request. SSE streams are counted but kept
+// out of the latency histogram — their duration is the connection lifetime.
+func (m *Metrics) ObserveRequest(callee, method string, status int, d time.Duration, isSSE bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{callee, method, status}]++
+	if isSSE {
+		return
+	}
+	h, ok := m.latencies[callee]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(latencyBuckets))}
+		m.latencies[callee] = h
+	}
+	secs := d.Seconds()
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+// SSEConnected marks an SSE stream open; call the returned func when it closes.
+func (m *Metrics) SSEConnected() func() {
+	m.sseActive.Add(1)
+	return func() { m.sseActive.Add(-1) }
+}
+
+// Render writes every metric in Prometheus text format, sorted for stable output.
+func (m *Metrics) Render(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP gateway_requests_total Proxied requests by upstream, method and status.")
+	fmt.Fprintln(w, "# TYPE gateway_requests_total counter")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.callee != b.callee {
+			return a.callee < b.callee
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "gateway_requests_total{callee=%q,method=%q,status=\"%d\"} %d\n",
+			k.callee, k.method, k.status, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP gateway_request_duration_seconds Proxied request latency by upstream (SSE excluded).")
+	fmt.Fprintln(w, "# TYPE gateway_request_duration_seconds histogram")
+	callees := make([]string, 0, len(m.latencies))
+	for c := range m.latencies {
+		callees = append(callees, c)
+	}
+	sort.Strings(callees)
+	for _, c := range callees {
+		h := m.latencies[c]
+		var cumulative int64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "gateway_request_duration_seconds_bucket{callee=%q,le=\"%g\"} %d\n", c, le, cumulative)
+		}
+		fmt.Fprintf(w, "gateway_request_duration_seconds_bucket{callee=%q,le=\"+Inf\"} %d\n", c, h.count)
+		fmt.Fprintf(w, "gateway_request_duration_seconds_sum{callee=%q} %g\n", c, h.sum)
+		fmt.Fprintf(w, "gateway_request_duration_seconds_count{callee=%q} %d\n", c, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP gateway_sse_connections Open SSE streams (proxied and gateway-owned).")
+	fmt.Fprintln(w, "# TYPE gateway_sse_connections gauge")
+	fmt.Fprintf(w, "gateway_sse_connections %d\n", m.sseActive.Load())
+
+	fmt.Fprintln(w, "# HELP gateway_websocket_connections Open proxied WebSocket sessions.")
+	fmt.Fprintln(w, "# TYPE gateway_websocket_connections gauge")
+	fmt.Fprintf(w, "gateway_websocket_connections %d\n", wsConns.count()/2)
+
+	fmt.Fprintln(w, "# HELP gateway_bus_dropped_total Observability events dropped for slow dashboard clients.")
+	fmt.Fprintln(w, "# TYPE gateway_bus_dropped_total counter")
+	fmt.Fprintf(w, "gateway_bus_dropped_total %d\n", bus.dropped.Load())
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.Render(w)
+}
+
 func checkUpstream(healthURL string) string {
 	client := &http.Client{Timeout: 2 * time.Second}
 	resp, err := client.Get(healthURL)
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signHS256 builds a compact HS256 token over claims.
+func signHS256(t *testing.T, secret string, claims map[string]interface{}) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	body, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestCheckTokenTimes(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	at := func(d time.Duration) float64 { return float64(now.Add(d).Unix()) }
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		want   error
+	}{
+		{"valid", map[string]interface{}{"exp": at(time.Hour), "nbf": at(-time.Hour)}, nil},
+		{"missing exp", map[string]interface{}{"sub": "p1"}, nil},
+		{"missing exp, future nbf", map[string]interface{}{"nbf": at(time.Minute)}, errTokenNotYet},
+		{"expired", map[string]interface{}{"exp": at(-time.Minute)}, errTokenExpired},
+		{"expired within skew", map[string]interface{}{"exp": at(-jwtClockSkew + time.Second)}, nil},
+		{"expired at skew edge", map[string]interface{}{"exp": at(-jwtClockSkew)}, nil},
+		{"expired just past skew", map[string]interface{}{"exp": at(-jwtClockSkew - time.Second)}, errTokenExpired},
+		{"not yet valid", map[string]interface{}{"exp": at(time.Hour), "nbf": at(time.Minute)}, errTokenNotYet},
+		{"nbf within skew", map[string]interface{}{"exp": at(time.Hour), "nbf": at(jwtClockSkew - time.Second)}, nil},
+		{"nbf at skew edge", map[string]interface{}{"exp": at(time.Hour), "nbf": at(jwtClockSkew)}, nil},
+		{"nbf just past skew", map[string]interface{}{"exp": at(time.Hour), "nbf": at(jwtClockSkew + time.Second)}, errTokenNotYet},
+		// A non-numeric claim isn't a NumericDate and is ignored
+		{"string exp", map[string]interface{}{"exp": "yesterday"}, nil},
+	}
+	for _, c := range cases {
+		if got := checkTokenTimes(c.claims, now); got != c.want {
+			t.Errorf("%s: checkTokenTimes = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestAuthenticateTokenLifetime(t *testing.T) {
+	saved := jwtVerifier
+	jwtVerifier = &JWTVerifier{secret: []byte("test-secret")}
+	defer func() { jwtVerifier = saved }()
+
+	now := time.Now()
+	cases := []struct {
+		name     string
+		claims   map[string]interface{}
+		wantCode string // empty: accepted
+	}{
+		{"valid", map[string]interface{}{"sub": "p1", "exp": now.Add(time.Hour).Unix()}, ""},
+		{"missing exp", map[string]interface{}{"sub": "p1"}, ""},
+		{"expired", map[string]interface{}{"sub": "p1", "exp": now.Add(-time.Hour).Unix()}, "token_expired"},
+		{"expired within skew", map[string]interface{}{"sub": "p1", "exp": now.Add(-2 * time.Second).Unix()}, ""},
+		{"not yet valid", map[string]interface{}{"sub": "p1", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Hour).Unix()}, "token_not_yet_valid"},
+		{"nbf within skew", map[string]interface{}{"sub": "p1", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(2 * time.Second).Unix()}, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/tables", nil)
+		req.Header.Set("Authorization", "Bearer "+signHS256(t, "test-secret", c.claims))
+		rec := httptest.NewRecorder()
+		claims, ok := authenticate(rec, req)
+
+		if c.wantCode == "" {
+			if !ok || claims["sub"] != "p1" {
+				t.Errorf("%s: rejected (%d %s), want accepted", c.name, rec.Code, rec.Body.String())
+			}
+			continue
+		}
+		var body map[string]string
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if ok || rec.Code != http.StatusUnauthorized || body["code"] != c.wantCode {
+			t.Errorf("%s: got ok=%v %d %v, want 401 %s", c.name, ok, rec.Code, body, c.wantCode)
+		}
+	}
+
+	// Lifetime is checked before the signature, and in decode-only mode too
+	jwtVerifier = nil
+	req := httptest.NewRequest(http.MethodGet, "/api/tables", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, "other", map[string]interface{}{"exp": now.Add(-time.Hour).Unix()}))
+	if _, err := parseJWT(req); err != errTokenExpired {
+		t.Errorf("decode-only expired token: err = %v, want errTokenExpired", err)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	saved := trustedProxies
+	defer func() { trustedProxies = saved }()
+	trustedProxies = parseTrustedProxies("10.0.0.0/8, 192.168.1.5, not-an-ip")
+	if len(trustedProxies) != 2 {
+		t.Fatalf("parsed %d trusted proxies, want 2", len(trustedProxies))
+	}
+
+	cases := []struct {
+		name, remote, xff, want string
+	}{
+		{"direct client", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"direct client, spoofed header", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"via trusted proxy", "10.1.2.3:443", "198.51.100.1", "198.51.100.1"},
+		{"via trusted proxy, client-supplied entry ignored", "10.1.2.3:443", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"via two trusted proxies", "192.168.1.5:443", "198.51.100.1, 10.9.9.9", "198.51.100.1"},
+		{"trusted proxy, no header", "10.1.2.3:443", "", "10.1.2.3"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.RemoteAddr = c.remote
+		if c.xff != "" {
+			req.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if got := clientIP(req); got != c.want {
+			t.Errorf("%s: clientIP = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestAuthRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	savedLimiter, savedProxies := authLimiter, trustedProxies
+	defer func() { authLimiter, trustedProxies = savedLimiter, savedProxies }()
+	authLimiter = NewRateLimiter(1, 2)
+	trustedProxies = nil
+
+	h := rateLimitAuth(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	for i := 1; i <= 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i)) // a fresh address every time
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		want := http.StatusOK
+		if i == 3 {
+			want = http.StatusTooManyRequests
+		}
+		if rec.Code != want {
+			t.Errorf("attempt %d: status %d, want %d", i, rec.Code, want)
+		}
+	}
+}
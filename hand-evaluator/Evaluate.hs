@@ -0,0 +1,71 @@
+{-# LANGUAGE OverloadedStrings #-}
+{-# LANGUAGE DeriveGeneric #-}
+
+-- The hand-evaluation domain on its own, so the fixture test
+-- (test/HandFixtures.hs) can score hands without the HTTP layer.
+
+module Evaluate
+  ( Card (..)
+  , HandResult (..)
+  , evaluateHand
+  ) where
+
+import Data.Aeson (FromJSON, ToJSON)
+import GHC.Generics (Generic)
+import qualified Data.Text as T
+
+-- ── Types ────────────────────────────────────────────────────────────────────
+
+data Card = Card
+  { suit :: T.Text
+  , rank :: T.Text
+  } deriving (Show, Generic)
+
+instance FromJSON Card
+instance ToJSON Card
+
+data HandResult = HandResult
+  { value      :: Int
+  , isSoft     :: Bool
+  , isBlackjack :: Bool
+  , isBust     :: Bool
+  } deriving (Show, Generic)
+
+instance ToJSON HandResult
+
+-- ── Pure Evaluation Logic ─────────────────────────────────────────────────────
+-- This is the entire domain in pure functions. No IO, no state, no side effects.
+-- The HTTP layer is the only impure code in this service.
+
+rankValue :: T.Text -> Int
+rankValue "A"  = 11
+rankValue "K"  = 10
+rankValue "Q"  = 10
+rankValue "J"  = 10
+rankValue "10" = 10
+rankValue r    = case reads (T.unpack r) :: [(Int, String)] of
+                   [(n, "")] -> n
+                   _         -> 0
+
+evaluateHand :: [Card] -> HandResult
+evaluateHand hand =
+  let ranks'     = map rank hand
+      rawTotal   = sum (map rankValue ranks')
+      aceCount   = length (filter (== "A") ranks')
+      -- Reduce aces from 11 to 1 until we're not bust
+      (total, softnessUsed) = reduceAces rawTotal aceCount 0
+      soft       = softnessUsed < aceCount && total <= 21
+      bj         = total == 21 && length hand == 2
+      bust       = total > 21
+  in HandResult
+       { value       = total
+       , isSoft      = soft
+       , isBlackjack = bj
+       , isBust      = bust
+       }
+
+reduceAces :: Int -> Int -> Int -> (Int, Int)
+reduceAces total 0 used = (total, used)
+reduceAces total aces used
+  | total > 21 = reduceAces (total - 10) (aces - 1) (used + 1)
+  | otherwise  = (total, used)
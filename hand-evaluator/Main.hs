@@ -11,7 +11,7 @@
 
 module Main where
 
-import Data.Aeson (FromJSON, ToJSON, decode, encode, object, (.=), (.:), withObject)
+import Data.Aeson (FromJSON, decode, encode, object, (.=), (.:), withObject)
 import Data.Aeson.Types (Parser, parseJSON, toJSON, Value)
 import GHC.Generics (Generic)
 import Network.HTTP.Types (status200, status400, status405, hContentType, Status)
@@ -23,15 +23,9 @@ import qualified Data.Text as T
 import System.Environment (lookupEnv)
 import Data.Maybe (fromMaybe)
 
--- ── Types ────────────────────────────────────────────────────────────────────
-
-data Card = Card
-  { suit :: T.Text
-  , rank :: T.Text
-  } deriving (Show, Generic)
+import Evaluate (Card, evaluateHand)
 
-instance FromJSON Card
-instance ToJSON Card
+-- ── Types ────────────────────────────────────────────────────────────────────
 
 data EvaluateRequest = EvaluateRequest
   { cards :: [Card]
@@ -39,61 +33,28 @@ data EvaluateRequest = EvaluateRequest
 
 instance FromJSON EvaluateRequest
 
-data HandResult = HandResult
-  { value      :: Int
-  , isSoft     :: Bool
-  , isBlackjack :: Bool
-  , isBust     :: Bool
+-- Several hands scored in one round-trip — e.g. every intermediate hand of
+-- an initial deal. Results come back in request order.
+data BatchRequest = BatchRequest
+  { hands :: [[Card]]
   } deriving (Show, Generic)
 
-instance ToJSON HandResult
-
--- ── Pure Evaluation Logic ─────────────────────────────────────────────────────
--- This is the entire domain in pure functions. No IO, no state, no side effects.
--- The HTTP layer is the only impure code in this service.
-
-rankValue :: T.Text -> Int
-rankValue "A"  = 11
-rankValue "K"  = 10
-rankValue "Q"  = 10
-rankValue "J"  = 10
-rankValue "10" = 10
-rankValue r    = case reads (T.unpack r) :: [(Int, String)] of
-                   [(n, "")] -> n
-                   _         -> 0
-
-evaluateHand :: [Card] -> HandResult
-evaluateHand hand =
-  let ranks'     = map rank hand
-      rawTotal   = sum (map rankValue ranks')
-      aceCount   = length (filter (== "A") ranks')
-      -- Reduce aces from 11 to 1 until we're not bust
-      (total, softnessUsed) = reduceAces rawTotal aceCount 0
-      soft       = softnessUsed < aceCount && total <= 21
-      bj         = total == 21 && length hand == 2
-      bust       = total > 21
-  in HandResult
-       { value       = total
-       , isSoft      = soft
-       , isBlackjack = bj
-       , isBust      = bust
-       }
-
-reduceAces :: Int -> Int -> Int -> (Int, Int)
-reduceAces total 0 used = (total, used)
-reduceAces total aces used
-  | total > 21 = reduceAces (total - 10) (aces - 1) (used + 1)
-  | otherwise  = (total, used)
+instance FromJSON BatchRequest
+
+-- Upper bound on hands per batch request.
+maxBatch :: Int
+maxBatch = 64
 
 -- ── HTTP Application ──────────────────────────────────────────────────────────
 
 app :: Application
 app req respond =
   case (requestMethod req, pathInfo req) of
-    ("GET",  ["health"])   -> respond healthResponse
-    ("POST", ["evaluate"]) -> handleEvaluate req respond
-    ("OPTIONS", _)         -> respond corsResponse
-    _                      -> respond notFoundResponse
+    ("GET",  ["health"])            -> respond healthResponse
+    ("POST", ["evaluate"])          -> handleEvaluate req respond
+    ("POST", ["evaluate", "batch"]) -> handleBatch req respond
+    ("OPTIONS", _)                  -> respond corsResponse
+    _                               -> respond notFoundResponse
 
 handleEvaluate :: Request -> (Response -> IO ResponseReceived) -> IO ResponseReceived
 handleEvaluate req respond = do
@@ -105,6 +66,18 @@ handleEvaluate req respond = do
       let result = evaluateHand (cards evalReq)
       respond $ jsonResponse status200 (toJSON result)
 
+handleBatch :: Request -> (Response -> IO ResponseReceived) -> IO ResponseReceived
+handleBatch req respond = do
+  body <- requestBody req
+  let bodyLazy = BL.fromStrict body
+  case decode bodyLazy :: Maybe BatchRequest of
+    Nothing -> respond $ jsonResponse status400 (object ["error" .= ("invalid request body" :: T.Text)])
+    Just batchReq
+      | length (hands batchReq) > maxBatch ->
+          respond $ jsonResponse status400 (object ["error" .= ("too many hands" :: T.Text)])
+      | otherwise ->
+          respond $ jsonResponse status200 (object ["results" .= map evaluateHand (hands batchReq)])
+
 healthResponse :: Response
 healthResponse = jsonResponse status200 $ object
   [ "status"   .= ("healthy" :: T.Text)
@@ -0,0 +1,56 @@
+{-# LANGUAGE OverloadedStrings #-}
+
+-- Runs evaluateHand over the shared hand table in contracts/testdata. The
+-- same table drives game-state's EvaluateHand test, so the remote and local
+-- evaluators can't disagree without one of them failing. HANDS_FIXTURE
+-- overrides the path (default: relative to this package).
+
+module Main (main) where
+
+import Data.Aeson (FromJSON (..), eitherDecode, withObject, (.:))
+import qualified Data.ByteString.Lazy as BL
+import Data.Maybe (fromMaybe, mapMaybe)
+import qualified Data.Text as T
+import System.Environment (lookupEnv)
+import System.Exit (exitFailure)
+
+import Evaluate (Card (..), HandResult (..), evaluateHand)
+
+newtype Fixture = Fixture [Case]
+
+-- Expected is (value, soft, blackjack, bust).
+data Case = Case
+  { caseName     :: String
+  , caseRanks    :: [T.Text]
+  , caseExpected :: (Int, Bool, Bool, Bool)
+  }
+
+instance FromJSON Fixture where
+  parseJSON = withObject "fixture" $ \o -> Fixture <$> o .: "hands"
+
+instance FromJSON Case where
+  parseJSON = withObject "hand" $ \o -> do
+    name     <- o .: "name"
+    ranks    <- o .: "cards"
+    expected <- (,,,) <$> o .: "value" <*> o .: "soft" <*> o .: "blackjack" <*> o .: "bust"
+    return (Case name ranks expected)
+
+-- Suits don't affect the score; every card is a spade.
+check :: Case -> Maybe String
+check c
+  | got == caseExpected c = Nothing
+  | otherwise = Just (caseName c ++ ": got " ++ show got ++ ", want " ++ show (caseExpected c))
+  where
+    r   = evaluateHand (map (Card "spades") (caseRanks c))
+    got = (value r, isSoft r, isBlackjack r, isBust r)
+
+main :: IO ()
+main = do
+  path <- fromMaybe "../contracts/testdata/hands.json" <$> lookupEnv "HANDS_FIXTURE"
+  raw  <- BL.readFile path
+  Fixture cases <- either (\e -> fail ("bad fixture " ++ path ++ ": " ++ e)) return (eitherDecode raw)
+  let failures = mapMaybe check cases
+  mapM_ putStrLn failures
+  if null failures
+    then putStrLn (show (length cases) ++ " hands agree with " ++ path)
+    else exitFailure
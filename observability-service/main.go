@@ -21,7 +21,10 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -39,6 +42,8 @@ type InboundEvent struct {
 	StatusCode int    `json:"status_code"`
 	LatencyMs  int64  `json:"latency_ms"`
 	Protocol   string `json:"protocol"`
+	RequestID  string `json:"request_id"`
+	Degraded   bool   `json:"degraded"` // caller fell back to a local answer
 }
 
 // PublishedEvent is what we put on Redis (camelCase, matches frontend contract)
@@ -52,23 +57,100 @@ type PublishedEvent struct {
 	StatusCode int    `json:"statusCode"`
 	LatencyMs  int64  `json:"latencyMs"`
 	Protocol   string `json:"protocol"`
+	RequestID  string `json:"requestId,omitempty"`
+	Degraded   bool   `json:"degraded,omitempty"`
 }
 
 // ── Allowlists ────────────────────────────────────────────────────────────────
 
-var knownServices = map[string]bool{
-	"gateway":               true,
-	"game-state":            true,
-	"deck-service":          true,
-	"hand-evaluator":        true,
-	"dealer-ai":             true,
-	"bank-service":          true,
-	"auth-service":          true,
-	"chat-service":          true,
-	"email-service":         true,
-	"observability-service": true,
+// defaultServices seeds the service allowlist. Services can be added or
+// removed at runtime via PUT /rules/services; the live set is persisted to
+// Redis so it survives restarts.
+var defaultServices = []string{
+	"gateway",
+	"game-state",
+	"deck-service",
+	"hand-evaluator",
+	"dealer-ai",
+	"bank-service",
+	"auth-service",
+	"chat-service",
+	"email-service",
+	"document-service",
+	"observability-service",
+	"cobol", // bank-service's COBOL programs, reported as a pseudo-callee
 }
 
+// Service names are letters, digits and dashes
+var reServiceName = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+type ServiceAllowlist struct {
+	mu       sync.RWMutex
+	services map[string]bool
+}
+
+func NewServiceAllowlist(names []string) *ServiceAllowlist {
+	a := &ServiceAllowlist{services: make(map[string]bool, len(names))}
+	for _, n := range names {
+		a.services[n] = true
+	}
+	return a
+}
+
+func (a *ServiceAllowlist) Allowed(name string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.services[name]
+}
+
+// List returns the allowed services, sorted.
+func (a *ServiceAllowlist) List() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.services))
+	for n := range a.services {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// With returns the set that applying add and remove would produce, without
+// changing the live list.
+func (a *ServiceAllowlist) With(add, remove []string) []string {
+	a.mu.RLock()
+	next := make(map[string]bool, len(a.services)+len(add))
+	for n := range a.services {
+		next[n] = true
+	}
+	a.mu.RUnlock()
+	for _, n := range add {
+		next[n] = true
+	}
+	for _, n := range remove {
+		delete(next, n)
+	}
+	names := make([]string, 0, len(next))
+	for n := range next {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Replace swaps in a new set of services.
+func (a *ServiceAllowlist) Replace(names []string) {
+	next := make(map[string]bool, len(names))
+	for _, n := range names {
+		next[n] = true
+	}
+	a.mu.Lock()
+	a.services = next
+	a.mu.Unlock()
+}
+
+var knownServices = NewServiceAllowlist(defaultServices)
+
 var knownMethods = map[string]bool{
 	"GET": true, "POST": true, "PUT": true,
 	"DELETE": true, "PATCH": true, "HEAD": true,
@@ -76,6 +158,7 @@ var knownMethods = map[string]bool{
 
 var knownProtocols = map[string]bool{
 	"http": true, "sse": true, "websocket": true, "mtls": true,
+	"exec": true, "sql": true, "postgres": true, // subprocess and database hops
 }
 
 // ── Sanitization patterns ─────────────────────────────────────────────────────
@@ -86,17 +169,109 @@ var (
 	reUUID  = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
 	reJWT   = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
 	reQuery = regexp.MustCompile(`([?&][^=&]+)=[^&]*`)
+
+	// Emails, including a percent-encoded @
+	reEmail = regexp.MustCompile(`[A-Za-z0-9._%+-]+(?:@|%40)[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Card-number-like runs: 13–19 digits, optionally grouped by spaces
+	// (raw or %20) or dashes. Shorter numeric IDs are left alone.
+	reNumber = regexp.MustCompile(`\b\d(?:(?:[ -]|%20)?\d){12,18}\b`)
+
+	// Request IDs are opaque correlation tokens; anything else is dropped
+	reRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
 )
 
+// pathTemplates are route prefixes whose next segment is an identifier of
+// any format (e.g. "demo-table-..." or numeric IDs the regexes miss). The
+// segment collapses to {id}, giving low-cardinality paths for aggregation.
+// Override with PATH_TEMPLATES (comma-separated prefixes).
+var pathTemplates = []string{"/shoe/", "/tables/", "/players/"}
+
+func loadPathTemplates(raw string) []string {
+	templates := []string{}
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		if !strings.HasSuffix(p, "/") {
+			p += "/"
+		}
+		templates = append(templates, p)
+	}
+	return templates
+}
+
+func templatePath(path string) string {
+	for _, prefix := range pathTemplates {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		rest := path[len(prefix):]
+		tail := ""
+		if i := strings.IndexAny(rest, "/?"); i >= 0 {
+			rest, tail = rest[:i], rest[i:]
+		}
+		if rest == "" {
+			return path
+		}
+		return prefix + "{id}" + tail
+	}
+	return path
+}
+
+// sanitizePath applies the redactions in order. Emails go before IPs so a
+// dotted domain is never half-redacted, and UUIDs before long digit runs so
+// an all-digit UUID group can't be mistaken for a card number.
 func sanitizePath(path string) string {
+	path = templatePath(path)
 	path = reJWT.ReplaceAllString(path, "[token]")
+	path = reEmail.ReplaceAllString(path, "[email]")
 	path = reIPv4.ReplaceAllString(path, "[ip]")
 	path = reIPv6.ReplaceAllString(path, "[ip]")
 	path = reUUID.ReplaceAllString(path, "[id]")
+	path = reNumber.ReplaceAllString(path, "[number]")
 	path = reQuery.ReplaceAllString(path, "$1=[redacted]")
 	return path
 }
 
+// sanitizeBoundaryPath trims the path of a non-HTTP event before the usual
+// sanitizing. An exec path keeps only the program — arguments can carry
+// amounts or IDs. A sql path keeps only the statement verb and its table,
+// so query text and literals never reach the dashboard:
+//
+//	/CALC-PAYOUT BET=10          →  /CALC-PAYOUT
+//	SELECT * FROM accounts WHERE →  /SELECT accounts
+func sanitizeBoundaryPath(protocol, path string) string {
+	switch protocol {
+	case "exec":
+		if f := strings.Fields(path); len(f) > 0 {
+			return f[0]
+		}
+		return path
+	case "sql", "postgres":
+		f := strings.Fields(strings.TrimPrefix(path, "/"))
+		if len(f) == 0 {
+			return path
+		}
+		verb := strings.ToUpper(f[0])
+		for i := 0; i < len(f)-1; i++ {
+			switch strings.ToUpper(f[i]) {
+			case "FROM", "INTO", "UPDATE", "TABLE":
+				if reTableName.MatchString(f[i+1]) {
+					return "/" + verb + " " + f[i+1]
+				}
+			}
+		}
+		return "/" + verb
+	}
+	return path
+}
+
+var reTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]{0,62}$`)
+
 // ── Counters ──────────────────────────────────────────────────────────────────
 
 var (
@@ -105,9 +280,293 @@ var (
 	eventsDropped   atomic.Int64
 )
 
+// ── Recent events ─────────────────────────────────────────────────────────────
+// Redis pub/sub is fire-and-forget: an event nobody is listening for is gone.
+// The last EVENT_RETENTION sanitized events are also kept in a ring buffer so
+// GET /events/recent can answer "what happened in the last 5 minutes?" and
+// the dashboard can backfill on load.
+
+type recentEvent struct {
+	at  time.Time
+	evt PublishedEvent
+}
+
+type EventRing struct {
+	mu   sync.RWMutex
+	buf  []recentEvent
+	next int
+	full bool
+}
+
+func NewEventRing(size int) *EventRing {
+	return &EventRing{buf: make([]recentEvent, size)}
+}
+
+func (r *EventRing) Add(at time.Time, evt PublishedEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf[r.next] = recentEvent{at: at, evt: evt}
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// EventFilter selects events from the ring. Zero fields match everything.
+type EventFilter struct {
+	Since     time.Time
+	Callee    string
+	MinStatus int
+	MaxStatus int
+	Limit     int
+}
+
+func (f EventFilter) match(e recentEvent) bool {
+	switch {
+	case !f.Since.IsZero() && e.at.Before(f.Since):
+		return false
+	case f.Callee != "" && e.evt.Callee != f.Callee:
+		return false
+	case f.MinStatus > 0 && e.evt.StatusCode < f.MinStatus:
+		return false
+	case f.MaxStatus > 0 && e.evt.StatusCode > f.MaxStatus:
+		return false
+	}
+	return true
+}
+
+// Query returns the newest f.Limit matching events, oldest first — the
+// order the live feed would have delivered them.
+func (r *EventRing) Query(f EventFilter) []PublishedEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := r.next
+	if r.full {
+		n = len(r.buf)
+	}
+	out := []PublishedEvent{}
+	// Walk newest → oldest, then reverse
+	for i := 0; i < n && len(out) < f.Limit; i++ {
+		e := r.buf[(r.next-1-i+len(r.buf))%len(r.buf)]
+		if f.match(e) {
+			out = append(out, e.evt)
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+var recent = NewEventRing(1000)
+
+// ── Stats ─────────────────────────────────────────────────────────────────────
+// Per-callee health over a sliding window: request count, error rate and
+// latency percentiles. Each callee keeps a ring of its last statsSamples
+// events; samples older than statsWindow are ignored when a snapshot is
+// taken, so a quiet service's numbers age out instead of freezing.
+
+type sample struct {
+	at        time.Time
+	latencyMs int64
+	status    int
+}
+
+type sampleRing struct {
+	buf  []sample
+	next int
+	full bool
+}
+
+type StatsTracker struct {
+	mu      sync.Mutex
+	size    int
+	window  time.Duration
+	callees map[string]*sampleRing
+}
+
+func NewStatsTracker(size int, window time.Duration) *StatsTracker {
+	return &StatsTracker{size: size, window: window, callees: make(map[string]*sampleRing)}
+}
+
+func (t *StatsTracker) Record(callee string, at time.Time, latencyMs int64, status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ring, ok := t.callees[callee]
+	if !ok {
+		ring = &sampleRing{buf: make([]sample, t.size)}
+		t.callees[callee] = ring
+	}
+	ring.buf[ring.next] = sample{at: at, latencyMs: latencyMs, status: status}
+	ring.next = (ring.next + 1) % len(ring.buf)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// CalleeStats summarises one callee's window.
+type CalleeStats struct {
+	Count        int     `json:"count"`
+	ClientErrors int     `json:"client_errors"`
+	ServerErrors int     `json:"server_errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	P50          int64   `json:"p50_ms"`
+	P95          int64   `json:"p95_ms"`
+	P99          int64   `json:"p99_ms"`
+}
+
+// Snapshot computes stats for every callee with samples inside the window.
+func (t *StatsTracker) Snapshot(now time.Time) map[string]CalleeStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]CalleeStats, len(t.callees))
+	cutoff := now.Add(-t.window)
+	for callee, ring := range t.callees {
+		n := ring.next
+		if ring.full {
+			n = len(ring.buf)
+		}
+		var st CalleeStats
+		latencies := make([]int64, 0, n)
+		for _, smp := range ring.buf[:n] {
+			if smp.at.Before(cutoff) {
+				continue
+			}
+			latencies = append(latencies, smp.latencyMs)
+			switch {
+			case smp.status >= 500:
+				st.ServerErrors++
+			case smp.status >= 400:
+				st.ClientErrors++
+			}
+		}
+		if len(latencies) == 0 {
+			continue
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		st.Count = len(latencies)
+		st.ErrorRate = float64(st.ClientErrors+st.ServerErrors) / float64(st.Count)
+		st.P50 = percentile(latencies, 50)
+		st.P95 = percentile(latencies, 95)
+		st.P99 = percentile(latencies, 99)
+		out[callee] = st
+	}
+	return out
+}
+
+// ServerErrors counts a callee's samples and 5xx responses since cutoff —
+// the cheap subset of Snapshot the alerter needs on every event.
+func (t *StatsTracker) ServerErrors(callee string, cutoff time.Time) (count, errors int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ring, ok := t.callees[callee]
+	if !ok {
+		return 0, 0
+	}
+	n := ring.next
+	if ring.full {
+		n = len(ring.buf)
+	}
+	for _, smp := range ring.buf[:n] {
+		if smp.at.Before(cutoff) {
+			continue
+		}
+		count++
+		if smp.status >= 500 {
+			errors++
+		}
+	}
+	return count, errors
+}
+
+// percentile is the nearest-rank percentile of sorted values.
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+var stats = NewStatsTracker(1000, 5*time.Minute)
+
+// ── Alerts ────────────────────────────────────────────────────────────────────
+// When a callee's 5xx rate over ALERT_WINDOW reaches ALERT_5XX_RATE (with at
+// least ALERT_MIN_REQUESTS samples, so one failed call isn't an outage), an
+// alert is logged and published to swarm:alerts. Each service then stays
+// quiet for ALERT_COOLDOWN, so a sustained outage raises one alert per
+// cooldown rather than one per failing request.
+
+// Alert is what we put on swarm:alerts (camelCase, like PublishedEvent).
+type Alert struct {
+	ID            string  `json:"id"`
+	Timestamp     string  `json:"timestamp"`
+	Type          string  `json:"type"` // always "alert"
+	Service       string  `json:"service"`
+	ErrorRate     float64 `json:"errorRate"`
+	Requests      int     `json:"requests"`
+	ServerErrors  int     `json:"serverErrors"`
+	Threshold     float64 `json:"threshold"`
+	WindowSeconds int     `json:"windowSeconds"`
+}
+
+type Alerter struct {
+	threshold   float64
+	minRequests int
+	window      time.Duration
+	cooldown    time.Duration
+
+	mu        sync.Mutex
+	lastFired map[string]time.Time
+}
+
+// Check evaluates callee after a new sample and returns an alert to raise,
+// or nil when the rate is healthy or the service is cooling down.
+func (a *Alerter) Check(callee string, now time.Time) *Alert {
+	count, errs := stats.ServerErrors(callee, now.Add(-a.window))
+	if count < a.minRequests {
+		return nil
+	}
+	rate := float64(errs) / float64(count)
+	if rate < a.threshold {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if last, ok := a.lastFired[callee]; ok && now.Sub(last) < a.cooldown {
+		return nil
+	}
+	a.lastFired[callee] = now
+	return &Alert{
+		ID:            fmt.Sprintf("%d", now.UnixNano()),
+		Timestamp:     now.UTC().Format(time.RFC3339),
+		Type:          "alert",
+		Service:       callee,
+		ErrorRate:     rate,
+		Requests:      count,
+		ServerErrors:  errs,
+		Threshold:     a.threshold,
+		WindowSeconds: int(a.window.Seconds()),
+	}
+}
+
+var alerter = &Alerter{
+	threshold:   0.5,
+	minRequests: 10,
+	window:      time.Minute,
+	cooldown:    5 * time.Minute,
+	lastFired:   make(map[string]time.Time),
+}
+
+var alertsRaised atomic.Int64
+
 // ── Redis ─────────────────────────────────────────────────────────────────────
 
-const redisChannel = "swarm:events"
+const (
+	redisChannel     = "swarm:events"
+	redisAlerts      = "swarm:alerts"
+	redisServicesKey = "swarm:observability:services"
+)
 
 var rdb *redis.Client
 
@@ -118,6 +577,53 @@ func initRedis(addr string) error {
 	return rdb.Ping(ctx).Err()
 }
 
+func publishAlert(alert Alert) {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("marshal error: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := rdb.Publish(ctx, redisAlerts, data).Err(); err != nil {
+		log.Printf("redis alert publish error: %v", err)
+	}
+}
+
+// loadServices replaces the default allowlist with the set persisted in
+// Redis, if one was ever saved.
+func loadServices() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	names, err := rdb.SMembers(ctx, redisServicesKey).Result()
+	if err != nil {
+		log.Printf("[observability-service] load service allowlist: %v — using defaults", err)
+		return
+	}
+	if len(names) == 0 {
+		return
+	}
+	knownServices.Replace(names)
+	log.Printf("[observability-service] loaded %d allowed services from Redis", len(names))
+}
+
+// saveServices persists the full allowlist, replacing what was stored.
+func saveServices(names []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	members := make([]interface{}, len(names))
+	for i, n := range names {
+		members[i] = n
+	}
+	pipe := rdb.TxPipeline()
+	pipe.Del(ctx, redisServicesKey)
+	if len(members) > 0 {
+		pipe.SAdd(ctx, redisServicesKey, members...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func publish(evt PublishedEvent) {
 	data, err := json.Marshal(evt)
 	if err != nil {
@@ -152,7 +658,7 @@ func eventHandler(w http.ResponseWriter, r *http.Request) {
 	eventsReceived.Add(1)
 
 	// ── Validate allowlists ───────────────────────────────────────────────────
-	if !knownServices[inbound.Caller] || !knownServices[inbound.Callee] {
+	if !knownServices.Allowed(inbound.Caller) || !knownServices.Allowed(inbound.Callee) {
 		log.Printf("DROP unknown service: caller=%q callee=%q", inbound.Caller, inbound.Callee)
 		eventsDropped.Add(1)
 		w.WriteHeader(http.StatusAccepted)
@@ -181,16 +687,32 @@ func eventHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// ── Sanitize ──────────────────────────────────────────────────────────────
+	now := time.Now()
 	cleaned := PublishedEvent{
-		ID:         fmt.Sprintf("%d", time.Now().UnixNano()),
-		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		ID:         fmt.Sprintf("%d", now.UnixNano()),
+		Timestamp:  now.UTC().Format(time.RFC3339),
 		Caller:     inbound.Caller,
 		Callee:     inbound.Callee,
 		Method:     strings.ToUpper(inbound.Method),
-		Path:       sanitizePath(inbound.Path),
+		Path:       sanitizePath(sanitizeBoundaryPath(strings.ToLower(inbound.Protocol), inbound.Path)),
 		StatusCode: inbound.StatusCode,
 		LatencyMs:  inbound.LatencyMs,
 		Protocol:   strings.ToLower(inbound.Protocol),
+		Degraded:   inbound.Degraded,
+	}
+	if reRequestID.MatchString(inbound.RequestID) {
+		cleaned.RequestID = inbound.RequestID
+	}
+
+	recent.Add(now, cleaned)
+	stats.Record(cleaned.Callee, now, cleaned.LatencyMs, cleaned.StatusCode)
+	if cleaned.StatusCode >= 500 {
+		if alert := alerter.Check(cleaned.Callee, now); alert != nil {
+			alertsRaised.Add(1)
+			log.Printf("ALERT %s: %.0f%% 5xx (%d/%d) over %ds", alert.Service,
+				alert.ErrorRate*100, alert.ServerErrors, alert.Requests, alert.WindowSeconds)
+			go publishAlert(*alert)
+		}
 	}
 
 	// Publish non-blocking
@@ -199,6 +721,72 @@ func eventHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// recentEventsHandler returns retained events as JSON:
+//
+//	GET /events/recent?limit=&since=&callee=&minStatus=&maxStatus=
+//
+// since is an RFC3339 time or a duration back from now ("5m").
+func recentEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	f := EventFilter{Callee: q.Get("callee"), Limit: 100}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		f.Limit = n
+	}
+	if v := q.Get("since"); v != "" {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			f.Since = t
+		} else if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			f.Since = time.Now().Add(-d)
+		} else {
+			http.Error(w, "since must be RFC3339 or a duration like 5m", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"minStatus", &f.MinStatus}, {"maxStatus", &f.MaxStatus}} {
+		if v := q.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 100 || n > 599 {
+				http.Error(w, p.name+" must be an HTTP status code", http.StatusBadRequest)
+				return
+			}
+			*p.dst = n
+		}
+	}
+
+	events := recent.Query(f)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// statsHandler returns per-callee request count, error rate and latency
+// percentiles over the sliding window.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"window_seconds": int(stats.window.Seconds()),
+		"services":       stats.Snapshot(time.Now()),
+	})
+}
+
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	redisStatus := "connected"
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -218,14 +806,31 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 		"events_received":   eventsReceived.Load(),
 		"events_published":  eventsPublished.Load(),
 		"events_dropped":    eventsDropped.Load(),
+		"alerts_raised":     alertsRaised.Load(),
 	})
 }
 
-func rulesHandler(w http.ResponseWriter, r *http.Request) {
-	services := make([]string, 0, len(knownServices))
-	for s := range knownServices {
-		services = append(services, s)
+// readyHandler answers 503 until Redis is reachable — without it events
+// are accepted but go nowhere, so traffic should wait.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	status, code, redisStatus := "ready", http.StatusOK, "connected"
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		status, code, redisStatus = "not_ready", http.StatusServiceUnavailable, "disconnected"
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  status,
+		"service": "observability-service",
+		"redis":   redisStatus,
+	})
+}
+
+func rulesHandler(w http.ResponseWriter, r *http.Request) {
+	services := knownServices.List()
 	methods := make([]string, 0, len(knownMethods))
 	for m := range knownMethods {
 		methods = append(methods, m)
@@ -240,16 +845,69 @@ func rulesHandler(w http.ResponseWriter, r *http.Request) {
 		"service_allowlist":  services,
 		"method_allowlist":   methods,
 		"protocol_allowlist": protocols,
+		"path_templates":     pathTemplates,
 		"path_sanitization": []map[string]string{
 			{"pattern": "JWT tokens",     "replacement": "[token]"},
 			{"pattern": "IPv4 addresses", "replacement": "[ip]"},
 			{"pattern": "IPv6 addresses", "replacement": "[ip]"},
+			{"pattern": "email addresses", "replacement": "[email]"},
 			{"pattern": "UUIDs",          "replacement": "[id]"},
+			{"pattern": "card-like digit runs", "replacement": "[number]"},
 			{"pattern": "query values",   "replacement": "[redacted]"},
+			{"pattern": "exec arguments", "replacement": "program only"},
+			{"pattern": "SQL text", "replacement": "verb and table only"},
 		},
 	})
 }
 
+var servicesEdit sync.Mutex
+
+// servicesRuleHandler edits the service allowlist at runtime:
+//
+//	PUT /rules/services  {"add": ["document-service"], "remove": ["chat-service"]}
+//
+// The change is persisted to Redis before it takes effect, so a restart
+// keeps it. Returns the live list.
+func servicesRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	for _, list := range [][]string{req.Add, req.Remove} {
+		for _, name := range list {
+			if !reServiceName.MatchString(name) {
+				http.Error(w, fmt.Sprintf("invalid service name %q: use letters, digits and dashes", name), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	// Serialize edits so concurrent PUTs can't drop each other's changes
+	servicesEdit.Lock()
+	defer servicesEdit.Unlock()
+	next := knownServices.With(req.Add, req.Remove)
+	if err := saveServices(next); err != nil {
+		log.Printf("[observability-service] save service allowlist: %v", err)
+		http.Error(w, "could not persist allowlist", http.StatusServiceUnavailable)
+		return
+	}
+	knownServices.Replace(next)
+	log.Printf("[observability-service] service allowlist updated: +%v -%v", req.Add, req.Remove)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"service_allowlist": next,
+	})
+}
+
 // ── Main ──────────────────────────────────────────────────────────────────────
 
 func getEnv(key, fallback string) string {
@@ -262,6 +920,33 @@ func getEnv(key, fallback string) string {
 func main() {
 	redisAddr := getEnv("REDIS_URL", "redis:6379")
 	port := getEnv("PORT", "3009")
+	if raw := getEnv("PATH_TEMPLATES", ""); raw != "" {
+		pathTemplates = loadPathTemplates(raw)
+	}
+	if n, err := strconv.Atoi(getEnv("EVENT_RETENTION", "1000")); err == nil && n > 0 {
+		recent = NewEventRing(n)
+	}
+	statsSamples, err := strconv.Atoi(getEnv("STATS_SAMPLES", "1000"))
+	if err != nil || statsSamples <= 0 {
+		statsSamples = 1000
+	}
+	statsWindow, err := time.ParseDuration(getEnv("STATS_WINDOW", "5m"))
+	if err != nil || statsWindow <= 0 {
+		statsWindow = 5 * time.Minute
+	}
+	stats = NewStatsTracker(statsSamples, statsWindow)
+	if v, err := strconv.ParseFloat(getEnv("ALERT_5XX_RATE", "0.5"), 64); err == nil && v > 0 && v <= 1 {
+		alerter.threshold = v
+	}
+	if n, err := strconv.Atoi(getEnv("ALERT_MIN_REQUESTS", "10")); err == nil && n > 0 {
+		alerter.minRequests = n
+	}
+	if d, err := time.ParseDuration(getEnv("ALERT_WINDOW", "1m")); err == nil && d > 0 {
+		alerter.window = d
+	}
+	if d, err := time.ParseDuration(getEnv("ALERT_COOLDOWN", "5m")); err == nil && d >= 0 {
+		alerter.cooldown = d
+	}
 
 	log.Printf("[observability-service] starting on :%s", port)
 	log.Printf("[observability-service] connecting to Redis at %s", redisAddr)
@@ -277,10 +962,16 @@ func main() {
 		}
 	}
 
+	loadServices()
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/event", eventHandler)
 	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/ready", readyHandler)
 	mux.HandleFunc("/rules", rulesHandler)
+	mux.HandleFunc("/rules/services", servicesRuleHandler)
+	mux.HandleFunc("/events/recent", recentEventsHandler)
+	mux.HandleFunc("/stats", statsHandler)
 
 	log.Printf("[observability-service] ready — publishing to Redis channel %q", redisChannel)
 	if err := http.ListenAndServe(":"+port, mux); err != nil {
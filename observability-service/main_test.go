@@ -0,0 +1,117 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplatePathNonUUIDTableIDs(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/tables/demo-table-1a2b3c", "/tables/{id}"},
+		{"/tables/demo-table-1a2b3c/stream", "/tables/{id}/stream"},
+		{"/tables/player-table-alice/action", "/tables/{id}/action"},
+		{"/tables/42/rules", "/tables/{id}/rules"},
+		{"/tables/7?since=3", "/tables/{id}?since=3"},
+		{"/shoe/demo-table-1a2b3c/deal", "/shoe/{id}/deal"},
+		{"/shoe/123456/status", "/shoe/{id}/status"},
+		{"/players/p1/balance", "/players/{id}/balance"},
+		// Prefix alone or unknown prefixes are left as they are
+		{"/tables/", "/tables/"},
+		{"/tables", "/tables"},
+		{"/rules", "/rules"},
+		{"/bet/demo-table-1", "/bet/demo-table-1"},
+	}
+	for _, c := range cases {
+		if got := templatePath(c.path); got != c.want {
+			t.Errorf("templatePath(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestSanitizePathCollapsesTableIDs(t *testing.T) {
+	// Templating runs first, so a non-UUID ID never reaches the regexes and
+	// every table aggregates under one path.
+	for _, path := range []string{
+		"/tables/demo-table-9f8e/action",
+		"/tables/3/action",
+		"/tables/550e8400-e29b-41d4-a716-446655440000/action",
+	} {
+		if got := sanitizePath(path); got != "/tables/{id}/action" {
+			t.Errorf("sanitizePath(%q) = %q, want /tables/{id}/action", path, got)
+		}
+	}
+}
+
+func TestLoadPathTemplates(t *testing.T) {
+	got := loadPathTemplates(" shoe, /tables/ ,,/hands")
+	want := []string{"/shoe/", "/tables/", "/hands/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadPathTemplates = %q, want %q", got, want)
+	}
+
+	saved := pathTemplates
+	defer func() { pathTemplates = saved }()
+	pathTemplates = got
+	if p := templatePath("/hands/1001/replay"); p != "/hands/{id}/replay" {
+		t.Errorf("custom template: got %q", p)
+	}
+}
+
+func TestSanitizePathSeveralTokens(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			"email, card, ip and uuid",
+			"/audit/550e8400-e29b-41d4-a716-446655440000/4111 1111 1111 1111/bob.smith@mail.example.co.uk/192.168.1.1",
+			"/audit/[id]/[number]/[email]/[ip]",
+		},
+		{
+			"templated prefix plus card, ip and uuid",
+			"/players/alice@example.com/cards/4111111111111111/from/10.0.0.12/hand/550e8400-e29b-41d4-a716-446655440000",
+			"/players/{id}/cards/[number]/from/[ip]/hand/[id]",
+		},
+		{
+			"encoded email and ip, card in the query",
+			"/login/alice%40example.com/ip/172.16.0.5?card=4111-1111-1111-1111&token=abc",
+			"/login/[email]/ip/[ip]?card=[redacted]&token=[redacted]",
+		},
+		{
+			"ipv6 and email",
+			"/peer/2001:db8::1/player/bob@example.org",
+			"/peer/[ip]/player/[email]",
+		},
+		{
+			"card and uuid in the path, email and ip in the query",
+			"/x/4111111111111111/550e8400-e29b-41d4-a716-446655440000?email=a@b.co&ip=1.2.3.4",
+			"/x/[number]/[id]?email=[redacted]&ip=[redacted]",
+		},
+		// The cases below only come out whole because of the replacement
+		// order; swapping two steps half-redacts them.
+		{
+			"token first: a digit run inside a JWT doesn't split it",
+			"/session/eyJhbGciOiJIUzI1NiJ9.4111111111111111.c2lnbmF0dXJl/alice@example.com",
+			"/session/[token]/[email]",
+		},
+		{
+			"email before ip: an ip-like domain stays inside the email",
+			"/u/alice@10.0.0.1.example.com",
+			"/u/[email]",
+		},
+		{
+			"uuid before number: an all-digit uuid is not a card number",
+			"/audit/12345678-1234-1234-1234-123456789012/x",
+			"/audit/[id]/x",
+		},
+	}
+	for _, c := range cases {
+		if got := sanitizePath(c.path); got != c.want {
+			t.Errorf("%s:\n sanitizePath(%q)\n  = %q\n want %q", c.name, c.path, got, c.want)
+		}
+	}
+}
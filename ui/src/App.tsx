@@ -101,7 +101,11 @@ function App() {
   useEffect(() => {
     const pid = isDemo ? DEMO_PLAYER_ID : session?.playerId;
     if (!pid) return;
-    const es = new EventSource(`${GATEWAY_URL}/api/bank/balance/stream`);
+    // Signed-in players get a stream scoped to their own account
+    const url = !isDemo && session?.accessToken
+      ? `${GATEWAY_URL}/balance/stream?playerId=${encodeURIComponent(pid)}&token=${encodeURIComponent(session.accessToken)}`
+      : `${GATEWAY_URL}/api/bank/balance/stream`;
+    const es = new EventSource(url);
     es.addEventListener('balance_update', (e: MessageEvent) => {
       try {
         const evt = JSON.parse(e.data);
@@ -48,6 +48,7 @@ export const AuthModal: React.FC<Props> = ({ onSuccess, onClose }) => {
   const [fieldErrors, setFieldErrors]         = useState<Record<string, string>>({});
   const [submitError, setSubmitError]         = useState<string | null>(null);
   const [loading, setLoading]                 = useState(false);
+  const [resendNote, setResendNote]           = useState<string | null>(null);
 
   useEffect(() => {
     if (modalState !== 'form') return;
@@ -154,15 +155,81 @@ export const AuthModal: React.FC<Props> = ({ onSuccess, onClose }) => {
     }
   };
 
+  // ── Password login (fallback for browsers without WebAuthn) ────────────────
+
+  const handlePasswordLogin = async () => {
+    setSubmitError(null);
+    setFieldErrors({});
+    setLoading(true);
+
+    try {
+      const res  = await fetch(`${AUTH_UI}/submit`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ action: 'login', fields: values }),
+      });
+      const data = await res.json();
+
+      if (res.status === 422 && data.fields) {
+        const errs: Record<string, string> = {};
+        (data.fields as { field: string; message: string }[]).forEach(e => { errs[e.field] = e.message; });
+        setFieldErrors(errs);
+        return;
+      }
+      if (!res.ok) { setSubmitError(data.error ?? 'Sign in failed'); return; }
+
+      onSuccess(data as AuthResult);
+    } catch {
+      setSubmitError('Network error — is the auth service running?');
+    } finally {
+      setLoading(false);
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     if (action === 'register') {
       await handleRegister();
+    } else if (values['password']) {
+      await handlePasswordLogin();
     } else {
       await handlePasskeyLogin();
     }
   };
 
+  // ── Email availability (register form, on blur) ───────────────────────────
+
+  const checkEmailAvailable = async () => {
+    const email = (values['email'] ?? '').trim();
+    if (!email) return;
+    try {
+      const res = await fetch(`${AUTH_UI}/available?email=${encodeURIComponent(email)}`);
+      if (!res.ok) return; // advisory only — submit still validates
+      const data = await res.json();
+      setFieldErrors(errs => {
+        const { email: _, ...rest } = errs;
+        return data.available ? rest : { ...rest, email: 'That email address is already registered' };
+      });
+    } catch { /* advisory only */ }
+  };
+
+  // ── Resend verification email ───────────────────────────────────────────────
+
+  const handleResend = async () => {
+    setResendNote(null);
+    try {
+      const res  = await fetch(`${AUTH_UI}/resend-verification`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ email: registeredEmail }),
+      });
+      const data = await res.json();
+      setResendNote(data.message ?? data.error ?? null);
+    } catch {
+      setResendNote('Network error — is the auth service running?');
+    }
+  };
+
   const switchAction = (a: Action) => {
     setAction(a);
     setModalState('form');
@@ -211,6 +278,12 @@ export const AuthModal: React.FC<Props> = ({ onSuccess, onClose }) => {
               Link expires in 24 hours.
             </p>
             <button onClick={onClose} style={btnPrimary}>Got it</button>
+            <button onClick={handleResend} style={btnGhost}>
+              Didn't get it? Send again
+            </button>
+            {resendNote && (
+              <p style={{ color: '#8b949e', margin: '8px 0 0', fontSize: '0.8rem' }}>{resendNote}</p>
+            )}
             <button onClick={() => switchAction('login')} style={btnGhost}>
               Already verified? Sign in
             </button>
@@ -261,6 +334,7 @@ export const AuthModal: React.FC<Props> = ({ onSuccess, onClose }) => {
                   type={field.type}
                   value={values[field.name] ?? ''}
                   onChange={e => setValues(v => ({ ...v, [field.name]: e.target.value }))}
+                  onBlur={field.name === 'email' && action === 'register' ? checkEmailAvailable : undefined}
                   placeholder={field.placeholder}
                   maxLength={field.maxLength}
                   required={field.required}
@@ -314,7 +388,7 @@ export const AuthModal: React.FC<Props> = ({ onSuccess, onClose }) => {
                 cursor: loading ? 'not-allowed' : 'pointer',
                 opacity: loading ? 0.7 : 1,
               }}>
-                {loading ? 'Working...' : fieldDefs.submit}
+                {loading ? 'Working...' : (action === 'login' && values['password'] ? 'Sign In' : fieldDefs.submit)}
               </button>
             </div>
           </form>
@@ -13,6 +13,7 @@ const SERVICE_COLORS: Record<string, string> = {
   'auth-service': '#0ea5e9',
   'chat-service': '#e879f9',
   'email-service': '#6b7280',
+  cobol: '#f97316',
 };
 
 const PROTOCOL_BADGES: Record<string, { label: string; color: string }> = {
@@ -20,6 +21,9 @@ const PROTOCOL_BADGES: Record<string, { label: string; color: string }> = {
   websocket: { label: 'WS', color: '#d69e2e' },
   http: { label: 'HTTP', color: '#4a9eff' },
   mtls: { label: 'mTLS', color: '#a855f7' },
+  exec: { label: 'EXEC', color: '#f97316' },
+  sql: { label: 'SQL', color: '#0ea5e9' },
+  postgres: { label: 'SQL', color: '#0ea5e9' },
 };
 
 interface EventRowProps {
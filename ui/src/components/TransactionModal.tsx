@@ -6,6 +6,8 @@ interface Transaction {
   amount: string;
   balanceBefore: string;
   balanceAfter: string;
+  refId?: string | null;
+  parentTransactionId?: string | null;  // set on a double-down's bet and payout
   createdAt: string;
 }
 
@@ -21,10 +23,15 @@ const GATEWAY_URL = import.meta.env.VITE_GATEWAY_URL || '';
 const TYPE_COLORS: Record<string, string> = {
   bet:          '#f59e0b',
   payout_win:   '#38a169',
+  payout_blackjack: '#38a169',
   payout_loss:  '#fc8181',
   payout_push:  '#8b949e',
+  bet_cancelled: '#8b949e',
   deposit:      '#38a169',
   withdrawal:   '#fc8181',
+  cashout:      '#fc8181',
+  transfer_in:  '#38a169',
+  transfer_out: '#fc8181',
 };
 
 function typeLabel(type: string): string {
@@ -34,7 +41,7 @@ function typeLabel(type: string): string {
 function formatAmount(type: string, amount: string): string {
   const n = parseFloat(amount);
   if (n === 0) return '—';
-  const wins = ['payout_win', 'deposit'];
+  const wins = ['payout_win', 'payout_blackjack', 'deposit', 'bet_cancelled', 'transfer_in'];
   const prefix = wins.some(t => type === t) ? '+' : type === 'payout_push' ? '±' : '-';
   return `${prefix}${n.toFixed(2)}`;
 }
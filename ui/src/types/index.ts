@@ -9,8 +9,8 @@ export interface Card {
 }
 
 export type PlayerStatus =
-  | 'waiting' | 'betting' | 'playing' | 'standing'
-  | 'bust' | 'blackjack' | 'won' | 'lost' | 'push';
+  | 'waiting' | 'ready' | 'sitting_out' | 'betting' | 'playing' | 'standing'
+  | 'bust' | 'blackjack' | 'surrendered' | 'won' | 'lost' | 'push';
 
 export interface PlayerState {
   id: string;
@@ -20,6 +20,7 @@ export interface PlayerState {
   hand: Card[];
   handValue: number;
   isSoftHand: boolean;
+  insuranceBet?: number;
   status: PlayerStatus;
 }
 
@@ -30,7 +31,7 @@ export interface DealerState {
 }
 
 export type GamePhase =
-  | 'waiting' | 'betting' | 'dealing'
+  | 'waiting' | 'betting' | 'dealing' | 'insurance'
   | 'player_turn' | 'dealer_turn' | 'payout' | 'complete';
 
 export interface GameState {
@@ -41,8 +42,29 @@ export interface GameState {
   activePlayerId: string | null;
   minBet: number;
   maxBet: number;
+  betWindowSeconds?: number;
+  betDeadline?: string;  // RFC3339 — betting window countdown target
+  turnExpiresAt?: string;  // RFC3339 — decision clock; auto-stand on expiry
+  dealerHitsSoft17: boolean;  // table rule: H17 vs S17
+  dealerStrategy: 'standard' | 'hit-soft-17' | 'spanish';  // dealer-ai policy
   handledBy: string;  // container hostname — shown in observability
   timestamp: string;
+  version: number;  // bumped per state change — a gap means missed updates
+  lastResult?: HandSummary;  // set at payout, cleared at the next deal
+}
+
+export interface SeatResult {
+  playerId: string;
+  outcome: 'win' | 'loss' | 'push' | 'blackjack' | 'surrender';
+  betAmount: number;
+  payout: number;     // returned by the bank, stake included
+  netChange: number;  // payout - betAmount
+  playerValue: number;
+}
+
+export interface HandSummary {
+  dealerValue: number;
+  results: SeatResult[];
 }
 
 export interface SSEGameEvent {
@@ -50,7 +72,7 @@ export interface SSEGameEvent {
   data: GameState;
 }
 
-export type PlayerAction = 'bet' | 'hit' | 'stand' | 'double' | 'split' | 'insurance';
+export type PlayerAction = 'bet' | 'hit' | 'stand' | 'double' | 'split' | 'surrender' | 'insurance' | 'no_insurance';
 
 // Captured at phase=complete for session history drawer
 export interface RoundSnapshot {
@@ -69,5 +91,8 @@ export interface ObservabilityEvent {
   path: string;
   statusCode: number;
   latencyMs: number;
-  protocol: 'http' | 'https' | 'sse' | 'websocket' | 'mtls';
+  protocol: 'http' | 'https' | 'sse' | 'websocket' | 'mtls' | 'exec' | 'sql' | 'postgres';
+  requestId?: string;  // X-Request-ID shared by every call one action caused
+  attempt?: number;  // upstream retry attempt (gateway GET/HEAD retries)
+  degraded?: boolean;  // caller fell back to a local answer (e.g. hand-evaluator down)
 }